@@ -7,6 +7,24 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Session store types
+const (
+	sessionStoreMemory = "memory"
+	sessionStoreFile   = "file"
+)
+
+// Idle reset modes
+const (
+	idleResetClear   = "clear"
+	idleResetDivider = "divider"
+)
+
+// defaultGreeting is used when the greeting is enabled without custom text
+const defaultGreeting = "Bonjour ! Comment puis-je vous aider ?"
+
+// defaultWelcomeBack is the divider added to idle sessions without custom text
+const defaultWelcomeBack = "Rebonjour ! Reprenons où nous en étions."
+
 // Config represents the application configuration
 type Config struct {
 	Server struct {
@@ -16,13 +34,49 @@ type Config struct {
 	Orchestrator struct {
 		URL            string `yaml:"url"`
 		TimeoutSeconds int    `yaml:"timeout_seconds"`
+		StreamUploads  bool   `yaml:"stream_uploads"` // Pipe voice uploads instead of buffering them
 	} `yaml:"orchestrator"`
 	Session struct {
-		MaxHistory int `yaml:"max_history"`
+		MaxHistory     int    `yaml:"max_history"`
+		PerUserHistory bool   `yaml:"per_user_history"` // Only send the speaker's own turns to the orchestrator
+		PinnedPrefix   int    `yaml:"pinned_prefix"`    // Leading messages kept when history is trimmed
+		SecureCookie   bool   `yaml:"secure_cookie"`    // Only send cookies over HTTPS; leave off for localhost
+		CookieDomain   string `yaml:"cookie_domain"`    // Empty scopes cookies to the serving host
+		CookiePath     string `yaml:"cookie_path"`      // Default "/"
+		Greeting       struct {
+			Enabled bool   `yaml:"enabled"`
+			Text    string `yaml:"text"`
+		} `yaml:"greeting"`
+		IdleReset struct {
+			AfterMinutes int    `yaml:"after_minutes"` // Idle time before a session starts over, 0 disables
+			Mode         string `yaml:"mode"`          // "clear" (default) or "divider"
+			Text         string `yaml:"text"`          // Divider message for the "divider" mode
+		} `yaml:"idle_reset"`
+		Store struct {
+			Type                    string `yaml:"type"`                      // "memory" (default) or "file"
+			Path                    string `yaml:"path"`                      // Snapshot file for the "file" store (default sessions.json)
+			SnapshotIntervalSeconds int    `yaml:"snapshot_interval_seconds"` // How often the "file" store saves (default 60)
+		} `yaml:"store"`
 	} `yaml:"session"`
+	Audio struct {
+		SampleRate               int  `yaml:"sample_rate"`                // Hz of the WAV sent to the orchestrator (default 16000, as Whisper expects)
+		Channels                 int  `yaml:"channels"`                   // Default 1 (mono)
+		NormalizeLoudness        bool `yaml:"normalize_loudness"`         // Apply ffmpeg loudnorm while converting (default off)
+		MaxConcurrentConversions int  `yaml:"max_concurrent_conversions"` // ffmpeg processes at once, 0 is unlimited
+		ConversionWaitMs         int  `yaml:"conversion_wait_ms"`         // Longest wait for a slot (default 10000)
+	} `yaml:"audio"`
+	Chat struct {
+		HeartbeatIntervalMs int `yaml:"heartbeat_interval_ms"` // SSE keep-alive period, 0 disables
+	} `yaml:"chat"`
+	Log struct {
+		Format string `yaml:"format"` // "json" (default) or "text"
+		Level  string `yaml:"level"`  // "debug", "info" (default), "warn" or "error"
+	} `yaml:"log"`
 	TTS struct {
 		Enabled         bool     `yaml:"enabled"`
 		VoicePreference []string `yaml:"voice_preference"`
+		MaxSentences    int      `yaml:"max_sentences"` // Replies are cut after this many sentences, 0 is unlimited
+		MaxChars        int      `yaml:"max_chars"`     // Replies are cut to fit this many characters, 0 is unlimited
 	} `yaml:"tts"`
 }
 
@@ -54,6 +108,56 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.Session.MaxHistory == 0 {
 		cfg.Session.MaxHistory = 20
 	}
+	if cfg.Session.Greeting.Enabled && cfg.Session.Greeting.Text == "" {
+		cfg.Session.Greeting.Text = defaultGreeting
+	}
+	if cfg.Session.IdleReset.Mode == "" {
+		cfg.Session.IdleReset.Mode = idleResetClear
+	}
+	if cfg.Session.IdleReset.Mode == idleResetDivider && cfg.Session.IdleReset.Text == "" {
+		cfg.Session.IdleReset.Text = defaultWelcomeBack
+	}
+	if cfg.Session.Store.Type == "" {
+		cfg.Session.Store.Type = sessionStoreMemory
+	}
+	if cfg.Session.Store.Path == "" {
+		cfg.Session.Store.Path = "sessions.json"
+	}
+	if cfg.Session.Store.SnapshotIntervalSeconds == 0 {
+		cfg.Session.Store.SnapshotIntervalSeconds = 60
+	}
+	if cfg.Audio.SampleRate == 0 {
+		cfg.Audio.SampleRate = defaultAudioFormat.sampleRate
+	}
+	if cfg.Audio.Channels == 0 {
+		cfg.Audio.Channels = defaultAudioFormat.channels
+	}
+
+	// Reject audio settings ffmpeg would choke on or the sidecar can't use
+	if cfg.Audio.SampleRate < 8000 || cfg.Audio.SampleRate > 192000 {
+		return nil, fmt.Errorf("invalid audio sample_rate %d: must be between 8000 and 192000", cfg.Audio.SampleRate)
+	}
+	if cfg.Audio.Channels < 1 || cfg.Audio.Channels > 8 {
+		return nil, fmt.Errorf("invalid audio channels %d: must be between 1 and 8", cfg.Audio.Channels)
+	}
+	if cfg.Audio.MaxConcurrentConversions < 0 || cfg.Audio.ConversionWaitMs < 0 {
+		return nil, fmt.Errorf("invalid audio conversion limit: values must not be negative")
+	}
+	if cfg.Session.Store.Type != sessionStoreMemory && cfg.Session.Store.Type != sessionStoreFile {
+		return nil, fmt.Errorf("invalid session store type %q: must be %q or %q", cfg.Session.Store.Type, sessionStoreMemory, sessionStoreFile)
+	}
+	if cfg.Session.Store.SnapshotIntervalSeconds < 0 {
+		return nil, fmt.Errorf("invalid session snapshot_interval_seconds %d: must not be negative", cfg.Session.Store.SnapshotIntervalSeconds)
+	}
+	if cfg.Session.IdleReset.Mode != idleResetClear && cfg.Session.IdleReset.Mode != idleResetDivider {
+		return nil, fmt.Errorf("invalid session idle_reset mode %q: must be %q or %q", cfg.Session.IdleReset.Mode, idleResetClear, idleResetDivider)
+	}
+	if cfg.Session.IdleReset.AfterMinutes < 0 {
+		return nil, fmt.Errorf("invalid session idle_reset after_minutes %d: must not be negative", cfg.Session.IdleReset.AfterMinutes)
+	}
+	if cfg.TTS.MaxSentences < 0 || cfg.TTS.MaxChars < 0 {
+		return nil, fmt.Errorf("invalid tts limits: max_sentences and max_chars must not be negative")
+	}
 
 	return &cfg, nil
 }
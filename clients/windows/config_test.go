@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeClientConfig writes a config file with the given contents
+func writeClientConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_AudioDefaults(t *testing.T) {
+	cfg, err := LoadConfig(writeClientConfig(t, "server:\n  port: 10090\n"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Audio.SampleRate != 16000 || cfg.Audio.Channels != 1 {
+		t.Errorf("expected 16000 Hz mono by default, got %d Hz, %d channels", cfg.Audio.SampleRate, cfg.Audio.Channels)
+	}
+}
+
+func TestLoadConfig_InvalidAudio(t *testing.T) {
+	tests := map[string]string{
+		"negative sample rate": "audio:\n  sample_rate: -1\n",
+		"tiny sample rate":     "audio:\n  sample_rate: 100\n",
+		"negative channels":    "audio:\n  channels: -2\n",
+		"too many channels":    "audio:\n  channels: 32\n",
+		"negative conversions": "audio:\n  max_concurrent_conversions: -1\n",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadConfig(writeClientConfig(t, data)); err == nil {
+				t.Error("expected LoadConfig to reject the audio settings")
+			}
+		})
+	}
+}
+
+func TestLoadConfig_SessionStore(t *testing.T) {
+	cfg, err := LoadConfig(writeClientConfig(t, "server:\n  port: 10090\n"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Session.Store.Type != "memory" || cfg.Session.Store.Path != "sessions.json" || cfg.Session.Store.SnapshotIntervalSeconds != 60 {
+		t.Errorf("expected memory store defaults, got %+v", cfg.Session.Store)
+	}
+
+	if _, err := LoadConfig(writeClientConfig(t, "session:\n  store:\n    type: redis\n")); err == nil {
+		t.Error("expected LoadConfig to reject an unknown session store")
+	}
+}
+
+func TestLoadConfig_IdleReset(t *testing.T) {
+	cfg, err := LoadConfig(writeClientConfig(t, "session:\n  idle_reset:\n    after_minutes: 30\n    mode: divider\n"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Session.IdleReset.Text != defaultWelcomeBack {
+		t.Errorf("expected the default divider text, got %q", cfg.Session.IdleReset.Text)
+	}
+
+	for name, data := range map[string]string{
+		"unknown mode":   "session:\n  idle_reset:\n    mode: archive\n",
+		"negative after": "session:\n  idle_reset:\n    after_minutes: -5\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadConfig(writeClientConfig(t, data)); err == nil {
+				t.Error("expected LoadConfig to reject the idle reset settings")
+			}
+		})
+	}
+}
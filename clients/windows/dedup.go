@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// voiceDedupWindow is how long a finished voice request keeps answering
+// identical uploads, long enough to absorb a double click
+const voiceDedupWindow = 2 * time.Second
+
+// voiceCall is one orchestrator voice request shared by identical uploads
+type voiceCall struct {
+	done chan struct{}
+	resp *VoiceResponse
+	err  error
+}
+
+// voiceDedup collapses identical voice uploads arriving together into a
+// single orchestrator call whose result they all share
+type voiceDedup struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*voiceCall
+}
+
+func newVoiceDedup(window time.Duration) *voiceDedup {
+	return &voiceDedup{window: window, calls: make(map[string]*voiceCall)}
+}
+
+// voiceDedupKey identifies an upload by its session and audio
+func voiceDedupKey(sessionID, mimeType string, audio []byte) string {
+	h := sha256.New()
+	h.Write([]byte(sessionID))
+	h.Write([]byte{0})
+	h.Write([]byte(mimeType))
+	h.Write([]byte{0})
+	h.Write(audio)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Do runs fn unless a call with the same key is in flight or finished within
+// the window, in which case it waits for and returns that call's result,
+// failures included. A failed call is forgotten once it returns, so a retry
+// after an error reaches the orchestrator. shared reports whether the result
+// came from another upload.
+func (d *voiceDedup) Do(key string, fn func() (*VoiceResponse, error)) (resp *VoiceResponse, shared bool, err error) {
+	d.mu.Lock()
+	if call, ok := d.calls[key]; ok {
+		d.mu.Unlock()
+		<-call.done
+		return call.resp, true, call.err
+	}
+	call := &voiceCall{done: make(chan struct{})}
+	d.calls[key] = call
+	d.mu.Unlock()
+
+	call.resp, call.err = fn()
+	close(call.done)
+
+	forget := func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.calls[key] == call {
+			delete(d.calls, key)
+		}
+	}
+	if call.err != nil {
+		forget()
+	} else {
+		time.AfterFunc(d.window, forget)
+	}
+	return call.resp, false, call.err
+}
@@ -2,4 +2,10 @@ module github.com/yourproject/windows-client
 
 go 1.22
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/assistant/orchestrator v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+// The error envelope is shared with the orchestrator in the same repository
+replace github.com/assistant/orchestrator => ../..
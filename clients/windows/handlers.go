@@ -1,39 +1,92 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"io"
-	"log"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/assistant/orchestrator/pkg/apierror"
 )
 
 //go:embed templates/*
 var templateFS embed.FS
 
+// voiceCookieName remembers the chosen TTS voice across sessions
+const voiceCookieName = "voice_preference"
+
 // Server represents the HTTP server
 type Server struct {
 	config         *Config
 	sessionManager *SessionManager
+	sessionFile    *FileSessionManager // nil unless sessions are persisted to disk
 	proxy          *OrchestratorProxy
 	templates      *template.Template
+	static         *StaticAssets
+	ffmpeg         *ffmpegChecker
+	voiceDedup     *voiceDedup
+	logger         *slog.Logger
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *Config) (*Server, error) {
+func NewServer(cfg *Config, logger *slog.Logger) (*Server, error) {
+	// Load static assets (templates reference them by versioned URL)
+	static, err := NewStaticAssets(staticFS, "static")
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse templates
-	tmpl, err := template.ParseFS(templateFS, "templates/*.html")
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"asset": static.URL,
+	}).ParseFS(templateFS, "templates/*.html")
 	if err != nil {
 		return nil, err
 	}
 
+	sessionManager := NewSessionManager(cfg.Session.MaxHistory)
+	var sessionFile *FileSessionManager
+	if cfg.Session.Store.Type == sessionStoreFile {
+		sessionFile = NewFileSessionManager(cfg.Session.MaxHistory, cfg.Session.Store.Path)
+		if err := sessionFile.Load(); err != nil {
+			logger.Warn("starting without saved sessions", "path", cfg.Session.Store.Path, "error", err)
+		}
+		sessionManager = sessionFile.SessionManager
+	}
+	if cfg.Session.Greeting.Enabled {
+		sessionManager.SetGreeting(cfg.Session.Greeting.Text)
+	}
+	sessionManager.SetPinnedPrefix(cfg.Session.PinnedPrefix)
+	sessionManager.SetIdleReset(time.Duration(cfg.Session.IdleReset.AfterMinutes)*time.Minute,
+		cfg.Session.IdleReset.Mode, cfg.Session.IdleReset.Text)
+
+	proxy := NewOrchestratorProxy(cfg.Orchestrator.URL, cfg.Orchestrator.TimeoutSeconds)
+	if cfg.Audio.SampleRate > 0 && cfg.Audio.Channels > 0 {
+		proxy.SetAudioFormat(cfg.Audio.SampleRate, cfg.Audio.Channels)
+	}
+	proxy.SetLoudnessNormalization(cfg.Audio.NormalizeLoudness)
+	proxy.SetConversionLimit(cfg.Audio.MaxConcurrentConversions,
+		time.Duration(cfg.Audio.ConversionWaitMs)*time.Millisecond)
+
 	return &Server{
 		config:         cfg,
-		sessionManager: NewSessionManager(cfg.Session.MaxHistory),
-		proxy:          NewOrchestratorProxy(cfg.Orchestrator.URL, cfg.Orchestrator.TimeoutSeconds),
+		sessionManager: sessionManager,
+		sessionFile:    sessionFile,
+		proxy:          proxy,
 		templates:      tmpl,
+		static:         static,
+		ffmpeg:         newFFmpegChecker(3*time.Second, time.Minute),
+		voiceDedup:     newVoiceDedup(voiceDedupWindow),
+		logger:         logger,
 	}, nil
 }
 
@@ -45,23 +98,32 @@ func (s *Server) IndexHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get or create session
-	sessionID := s.getSessionID(r)
+	sessionID := s.liveSessionID(w, r)
 	if sessionID == "" {
 		sessionID = s.createSession(w)
 	}
 
-	// Prepare template data
-	voicePrefJSON, _ := json.Marshal(s.config.TTS.VoicePreference)
-	
+	voice := s.negotiateVoice(w, r, sessionID)
+
+	// Prepare template data; the session's voice is tried before the configured ones
+	voicePrefs := s.config.TTS.VoicePreference
+	if voice != "" {
+		voicePrefs = append([]string{voice}, voicePrefs...)
+	}
+	voicePrefJSON, _ := json.Marshal(voicePrefs)
+	historyJSON, _ := json.Marshal(s.sessionManager.GetHistory(sessionID))
+
 	data := map[string]interface{}{
 		"TTSEnabled":           s.config.TTS.Enabled,
+		"VoicePreference":      voice,
 		"VoicePreferencesJSON": template.JS(voicePrefJSON),
+		"HistoryJSON":          template.JS(historyJSON),
 		"SessionID":            sessionID,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := s.templates.ExecuteTemplate(w, "index.html", data); err != nil {
-		log.Printf("Error rendering template: %v", err)
+		s.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -74,49 +136,101 @@ func (s *Server) VoiceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get session
-	sessionID := s.getSessionID(r)
+	sessionID := s.liveSessionID(w, r)
 	if sessionID == "" {
 		s.sendJSONError(w, "Session not found", http.StatusBadRequest, "")
 		return
 	}
-	session := s.sessionManager.GetOrCreateSession(sessionID)
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10MB max
-		s.sendJSONError(w, "Failed to parse form", http.StatusBadRequest, err.Error())
-		return
+	// Streamed uploads are read part by part so the audio never lands in
+	// memory or on disk; buffered ones are parsed whole
+	var (
+		file      io.Reader
+		formValue func(key string) string
+	)
+	if s.config.Orchestrator.StreamUploads {
+		part, fields, err := readVoiceFields(r)
+		if err != nil {
+			s.sendJSONError(w, "Failed to parse form", http.StatusBadRequest, err.Error())
+			return
+		}
+		defer part.Close()
+		file = part
+		formValue = func(key string) string { return fields[key] }
+	} else {
+		if err := r.ParseMultipartForm(10 << 20); err != nil { // 10MB max
+			s.sendJSONError(w, "Failed to parse form", http.StatusBadRequest, err.Error())
+			return
+		}
+
+		upload, _, err := r.FormFile("file")
+		if err != nil {
+			s.sendJSONError(w, "No audio file provided", http.StatusBadRequest, err.Error())
+			return
+		}
+		defer upload.Close()
+		file = upload
+		formValue = r.FormValue
 	}
 
-	// Get audio file
-	file, _, err := r.FormFile("file")
-	if err != nil {
-		s.sendJSONError(w, "No audio file provided", http.StatusBadRequest, err.Error())
+	// Get MIME type (optional, for format detection)
+	mimeType := formValue("mime_type")
+
+	// Get conversation history. The speaker is only known after identification,
+	// so per-user history goes by the optional user_id hint, or failing that
+	// by whoever spoke last in this session.
+	speaker := formValue("user_id")
+	if speaker == "" {
+		speaker = s.sessionManager.LastSpeaker(sessionID)
+	}
+	history := s.historyFor(sessionID, speaker)
+
+	// Forward to orchestrator. Buffered uploads identical to one already in
+	// flight (a double click) share its result, success or error, instead of
+	// calling again. Streamed uploads are never deduplicated: the audio is
+	// only known once it has been sent, too late to spot a duplicate.
+	var (
+		resp   *VoiceResponse
+		shared bool
+		err    error
+	)
+	if s.config.Orchestrator.StreamUploads {
+		resp, err = s.proxy.ForwardVoiceStream(file, mimeType, history)
+	} else {
+		// Read audio data
+		audioData, readErr := io.ReadAll(file)
+		if readErr != nil {
+			s.sendJSONError(w, "Failed to read audio", http.StatusInternalServerError, readErr.Error())
+			return
+		}
+		key := voiceDedupKey(sessionID, mimeType, audioData)
+		resp, shared, err = s.voiceDedup.Do(key, func() (*VoiceResponse, error) {
+			return s.proxy.ForwardVoice(audioData, mimeType, history)
+		})
+		if shared {
+			s.logger.Info("duplicate voice upload shared an in-flight request", "session_id", sessionID)
+		}
+	}
+	if errors.Is(err, ErrFFmpegMissing) {
+		s.logger.Error("voice conversion failed", "error", err)
+		s.sendJSONErrorCode(w, "ffmpeg is not installed. Install ffmpeg and add it to your PATH to use voice input.",
+			"ffmpeg_missing", http.StatusInternalServerError, err.Error())
 		return
 	}
-	defer file.Close()
-
-	// Read audio data
-	audioData, err := io.ReadAll(file)
-	if err != nil {
-		s.sendJSONError(w, "Failed to read audio", http.StatusInternalServerError, err.Error())
+	if errors.Is(err, ErrConversionBusy) {
+		s.logger.Warn("voice conversion gave up waiting for ffmpeg", "error", err)
+		s.sendJSONErrorCode(w, "Too many recordings are being converted right now. Please try again in a moment.",
+			"conversion_busy", http.StatusServiceUnavailable, err.Error())
 		return
 	}
-
-	// Get MIME type (optional, for format detection)
-	mimeType := r.FormValue("mime_type")
-
-	// Get conversation history
-	history := s.sessionManager.GetHistory(sessionID)
-
-	// Forward to orchestrator
-	resp, err := s.proxy.ForwardVoice(audioData, mimeType, history)
 	if err != nil {
 		s.sendJSONError(w, "Orchestrator unavailable", http.StatusServiceUnavailable, err.Error())
 		return
 	}
 
-	// Add to conversation history if successful
-	if resp.Status == "identified" || resp.Status == "fallback" {
+	// Add to conversation history if successful; a shared result was
+	// already recorded by the upload that made the call
+	if !shared && (resp.Status == "identified" || resp.Status == "fallback") {
 		// Add user message
 		s.sessionManager.AddMessage(sessionID, Message{
 			Role:    "user",
@@ -133,9 +247,51 @@ func (s *Server) VoiceHandler(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	// Shorten a copy for speech; a shared result is also read by the other
+	// upload, and the history keeps the full reply
+	spoken := *resp
+	spoken.Response, spoken.Truncated = s.shortenForSpeech(resp.Response)
+
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(&spoken)
+}
+
+// maxVoiceFieldBytes caps the text fields of a streamed voice upload
+const maxVoiceFieldBytes = 1 << 10
+
+// readVoiceFields reads a streamed voice upload up to its "file" part, which
+// is returned unread along with the text fields that came before it. Fields
+// sent after the file are not seen, so the UI sends them first.
+func readVoiceFields(r *http.Request) (*multipart.Part, map[string]string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := make(map[string]string)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, nil, errors.New("no audio file provided")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if part.FormName() == "file" {
+			return part, fields, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxVoiceFieldBytes+1))
+		part.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(value) > maxVoiceFieldBytes {
+			return nil, nil, fmt.Errorf("field %s is too long", part.FormName())
+		}
+		fields[part.FormName()] = string(value)
+	}
 }
 
 // ChatHandler handles text-based chat messages
@@ -146,7 +302,7 @@ func (s *Server) ChatHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get session
-	sessionID := s.getSessionID(r)
+	sessionID := s.liveSessionID(w, r)
 	if sessionID == "" {
 		s.sendJSONError(w, "Session not found", http.StatusBadRequest, "")
 		return
@@ -160,36 +316,107 @@ func (s *Server) ChatHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get conversation history
-	history := s.sessionManager.GetHistory(sessionID)
-	req.ConversationHistory = history
+	req.ConversationHistory = s.historyFor(sessionID, req.UserID)
+
+	// Stream heartbeats while waiting if the browser asked for SSE
+	if s.config.Chat.HeartbeatIntervalMs > 0 && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		if flusher, ok := w.(http.Flusher); ok {
+			s.streamChat(w, flusher, r, sessionID, req)
+			return
+		}
+	}
 
 	// Forward to orchestrator
-	resp, err := s.proxy.ForwardChat(req)
+	resp, err := s.forwardChat(r.Context(), sessionID, req)
 	if err != nil {
 		s.sendJSONError(w, "Orchestrator unavailable", http.StatusServiceUnavailable, err.Error())
 		return
 	}
 
-	// Add to conversation history
-	s.sessionManager.AddMessage(sessionID, Message{
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// streamChat forwards a chat message while emitting SSE "heartbeat" events at the
+// configured interval, then sends a single "response" or "error" event and ends
+func (s *Server) streamChat(w http.ResponseWriter, flusher http.Flusher, r *http.Request, sessionID string, req ChatRequest) {
+	type chatResult struct {
+		resp *ChatResponse
+		err  error
+	}
+	done := make(chan chatResult, 1)
+	go func() {
+		resp, err := s.forwardChat(r.Context(), sessionID, req)
+		done <- chatResult{resp: resp, err: err}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(time.Duration(s.config.Chat.HeartbeatIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			writeSSE(w, "heartbeat", map[string]string{"status": "thinking"})
+			flusher.Flush()
+
+		case result := <-done:
+			if result.err != nil {
+				writeSSE(w, "error", apierror.New(http.StatusServiceUnavailable, "", "Orchestrator unavailable", result.err.Error()))
+			} else {
+				writeSSE(w, "response", result.resp)
+			}
+			flusher.Flush()
+			return
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// forwardChat sends a chat message to the orchestrator. The user message is
+// added to the session history up front, so a refresh while the reply is on
+// its way still shows the question, and is rolled back if the call fails or
+// ctx is cancelled. The history keeps the full reply even when the returned
+// one is shortened for speech.
+func (s *Server) forwardChat(ctx context.Context, sessionID string, req ChatRequest) (*ChatResponse, error) {
+	pending := s.sessionManager.AddPendingMessage(sessionID, Message{
 		Role:    "user",
 		Content: req.Message,
 		UserID:  req.UserID,
 	})
 
-	s.sessionManager.AddMessage(sessionID, Message{
+	resp, err := s.proxy.ForwardChat(ctx, req)
+	if err != nil {
+		s.sessionManager.RemoveMessage(sessionID, pending)
+		return nil, err
+	}
+
+	s.sessionManager.AnswerMessage(sessionID, pending, Message{
 		Role:      "assistant",
 		Content:   resp.Response,
 		UserID:    resp.UserID,
 		ModelUsed: resp.ModelUsed,
 	})
+	resp.Response, resp.Truncated = s.shortenForSpeech(resp.Response)
+	return resp, nil
+}
 
-	// Send response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+// writeSSE writes a single server-sent event with a JSON payload
+func writeSSE(w io.Writer, event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
 }
 
-// HealthHandler checks the health of the orchestrator
+// HealthHandler checks the health of the orchestrator and reports whether
+// ffmpeg is available for voice conversion
 func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
@@ -197,7 +424,7 @@ func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err := s.proxy.CheckHealth()
-	
+
 	response := map[string]string{
 		"orchestrator": s.config.Orchestrator.URL,
 	}
@@ -209,6 +436,18 @@ func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 		response["status"] = "ok"
 	}
 
+	// Voice needs ffmpeg; report it without affecting the overall status,
+	// since WAV uploads and chat still work without it
+	if ffmpeg := s.ffmpeg.Status(); ffmpeg.Available {
+		response["ffmpeg"] = "available"
+		if ffmpeg.Version != "" {
+			response["ffmpeg_version"] = ffmpeg.Version
+		}
+	} else {
+		response["ffmpeg"] = "missing"
+		response["ffmpeg_detail"] = ffmpeg.Detail
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -229,47 +468,181 @@ func (s *Server) ClearHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// transcriptExport is the JSON form of an exported conversation
+type transcriptExport struct {
+	SessionID  string    `json:"session_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	Messages   []Message `json:"messages"`
+}
+
+// ExportHandler serves the current session's conversation as a download,
+// either a readable transcript (format=txt, the default) or JSON (format=json)
+func (s *Server) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "txt"
+	}
+	if format != "txt" && format != "json" {
+		s.sendJSONError(w, "Invalid format", http.StatusBadRequest, "format must be txt or json")
+		return
+	}
+
+	sessionID := s.getSessionID(r)
+	if sessionID == "" || !s.sessionManager.HasSession(sessionID) {
+		s.sendJSONError(w, "Session not found", http.StatusNotFound, "")
+		return
+	}
+
+	now := time.Now()
+	history := s.sessionManager.GetHistory(sessionID)
+	filename := fmt.Sprintf("conversation-%s.%s", now.Format("20060102-150405"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(transcriptExport{SessionID: sessionID, ExportedAt: now, Messages: history})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "Conversation exported %s\n\n", now.Format("2006-01-02 15:04:05"))
+	for _, msg := range history {
+		fmt.Fprintf(w, "[%s] %s: %s\n", msg.Timestamp.Format("2006-01-02 15:04:05"), speakerName(msg), msg.Content)
+	}
+}
+
+// speakerName labels a message in a text transcript
+func speakerName(msg Message) string {
+	if msg.Role == "assistant" {
+		return "Assistant"
+	}
+	if msg.UserID != "" {
+		return msg.UserID
+	}
+	return "User"
+}
+
 // Helper functions
 
-// getSessionID retrieves the session ID from the cookie
+// historyFor returns the history to send for a request from userID, scoped to
+// that user when per-user history is enabled
+func (s *Server) historyFor(sessionID, userID string) []Message {
+	if !s.config.Session.PerUserHistory {
+		return s.sessionManager.GetHistory(sessionID)
+	}
+	if userID == "" {
+		return []Message{}
+	}
+	return s.sessionManager.GetHistoryForUser(sessionID, userID)
+}
+
+// getSessionID retrieves the session ID from the cookie. A browser may send
+// several session_id cookies set under different paths, most specific first;
+// the first one naming a live session wins, otherwise the first one is used
 func (s *Server) getSessionID(r *http.Request) string {
-	cookie, err := r.Cookie("session_id")
+	var first string
+	for _, cookie := range r.Cookies() {
+		if cookie.Name != "session_id" || cookie.Value == "" {
+			continue
+		}
+		if s.sessionManager.HasSession(cookie.Value) {
+			return cookie.Value
+		}
+		if first == "" {
+			first = cookie.Value
+		}
+	}
+	return first
+}
+
+// liveSessionID is getSessionID for handlers that record history: the session
+// is touched, so one resumed after a long idle spell starts over, and when the
+// cookies only name sessions that no longer exist, a fresh session is created
+// and its cookie set. A request without any session cookie still gets ""
+func (s *Server) liveSessionID(w http.ResponseWriter, r *http.Request) string {
+	sessionID := s.getSessionID(r)
+	if sessionID == "" || s.sessionManager.Touch(sessionID) {
+		return sessionID
+	}
+	s.logger.Info("session cookie names an expired session, starting a new one")
+	return s.createSession(w)
+}
+
+// negotiateVoice picks the session's TTS voice. A ?voice= query param
+// replaces the choice (an empty one clears it); otherwise the session keeps
+// its own, and a fresh session picks the choice back up from the cookie.
+func (s *Server) negotiateVoice(w http.ResponseWriter, r *http.Request, sessionID string) string {
+	if values, ok := r.URL.Query()["voice"]; ok {
+		voice := strings.TrimSpace(values[0])
+		s.sessionManager.SetVoicePreference(sessionID, voice)
+		cookie := s.newCookie(voiceCookieName, url.QueryEscape(voice), 86400*365) // 1 year
+		if voice == "" {
+			cookie.MaxAge = -1
+		}
+		http.SetCookie(w, cookie)
+		return voice
+	}
+
+	if voice := s.sessionManager.GetVoicePreference(sessionID); voice != "" {
+		return voice
+	}
+
+	cookie, err := r.Cookie(voiceCookieName)
 	if err != nil {
 		return ""
 	}
-	return cookie.Value
+	voice, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return ""
+	}
+	voice = strings.TrimSpace(voice)
+	s.sessionManager.SetVoicePreference(sessionID, voice)
+	return voice
 }
 
-// createSession creates a new session and sets the cookie
-func (s *Server) createSession(w http.ResponseWriter) string {
-	session := s.sessionManager.GetOrCreateSession("")
-	
-	cookie := &http.Cookie{
-		Name:     "session_id",
-		Value:    session.ID,
-		Path:     "/",
+// newCookie builds a client cookie with the configured scope and Secure flag
+func (s *Server) newCookie(name, value string, maxAge int) *http.Cookie {
+	path := s.config.Session.CookiePath
+	if path == "" {
+		path = "/"
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   s.config.Session.CookieDomain,
+		Secure:   s.config.Session.SecureCookie,
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
-		MaxAge:   86400 * 30, // 30 days
+		MaxAge:   maxAge,
 	}
+}
+
+// createSession creates a new session and sets the cookie
+func (s *Server) createSession(w http.ResponseWriter) string {
+	session := s.sessionManager.GetOrCreateSession("")
+
+	cookie := s.newCookie("session_id", session.ID, 86400*30) // 30 days
 	http.SetCookie(w, cookie)
-	
+
 	return session.ID
 }
 
 // sendJSONError sends a JSON error response
 func (s *Server) sendJSONError(w http.ResponseWriter, message string, statusCode int, detail string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	
-	response := map[string]string{
-		"error": message,
-	}
-	if detail != "" {
-		response["detail"] = detail
-	}
-	
-	json.NewEncoder(w).Encode(response)
+	apierror.Write(w, statusCode, apierror.New(statusCode, "", message, detail))
+}
+
+// sendJSONErrorCode sends a JSON error response with a machine-readable code
+func (s *Server) sendJSONErrorCode(w http.ResponseWriter, message, code string, statusCode int, detail string) {
+	apierror.Write(w, statusCode, apierror.New(statusCode, code, message, detail))
 }
 
 // StartCleanupRoutine starts a goroutine to periodically clean up old sessions
@@ -281,3 +654,21 @@ func (s *Server) StartCleanupRoutine() {
 		}
 	}()
 }
+
+// StartSnapshotRoutine periodically saves sessions when they are persisted
+// to disk, until stop is closed
+func (s *Server) StartSnapshotRoutine(stop <-chan struct{}) {
+	if s.sessionFile == nil || s.config.Session.Store.SnapshotIntervalSeconds <= 0 {
+		return
+	}
+	interval := time.Duration(s.config.Session.Store.SnapshotIntervalSeconds) * time.Second
+	s.sessionFile.StartSnapshots(interval, s.logger, stop)
+}
+
+// SaveSessions writes the sessions to disk when they are persisted
+func (s *Server) SaveSessions() error {
+	if s.sessionFile == nil {
+		return nil
+	}
+	return s.sessionFile.Save()
+}
@@ -0,0 +1,774 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer creates a client server pointed at the given orchestrator URL
+func newTestServer(t *testing.T, orchestratorURL string) *Server {
+	t.Helper()
+
+	cfg := &Config{}
+	cfg.Orchestrator.URL = orchestratorURL
+	cfg.Orchestrator.TimeoutSeconds = 5
+	cfg.Session.MaxHistory = 20
+
+	server, err := NewServer(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	return server
+}
+
+// newSessionRequest builds a request carrying a cookie for a fresh session
+func newSessionRequest(server *Server, method, path string, body []byte) *http.Request {
+	session := server.sessionManager.GetOrCreateSession("")
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+	return req
+}
+
+func TestChatHandler_SSEHeartbeats(t *testing.T) {
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(110 * time.Millisecond)
+		json.NewEncoder(w).Encode(ChatResponse{Response: "done thinking", UserID: "dad"})
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	server.config.Chat.HeartbeatIntervalMs = 20
+
+	body, _ := json.Marshal(ChatRequest{UserID: "dad", Message: "think hard"})
+	req := newSessionRequest(server, "POST", "/api/chat", body)
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	server.ChatHandler(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %s", ct)
+	}
+
+	out := w.Body.String()
+	heartbeats := strings.Count(out, "event: heartbeat\n")
+	if heartbeats < 3 {
+		t.Errorf("expected at least 3 heartbeats during a 110ms call, got %d:\n%s", heartbeats, out)
+	}
+
+	if !strings.HasSuffix(out, "\n\n") || !strings.Contains(out, "event: response\n") {
+		t.Errorf("expected a final response event, got:\n%s", out)
+	}
+	if strings.Index(out, "event: response") < strings.LastIndex(out, "event: heartbeat") {
+		t.Error("expected the response event to be last")
+	}
+	if !strings.Contains(out, `"response":"done thinking"`) {
+		t.Errorf("expected response payload, got:\n%s", out)
+	}
+}
+
+func TestChatHandler_JSONWithoutEventStream(t *testing.T) {
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ChatResponse{Response: "plain", UserID: "dad"})
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	server.config.Chat.HeartbeatIntervalMs = 20
+
+	body, _ := json.Marshal(ChatRequest{UserID: "dad", Message: "hi"})
+	req := newSessionRequest(server, "POST", "/api/chat", body)
+	w := httptest.NewRecorder()
+
+	server.ChatHandler(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json, got %s", ct)
+	}
+
+	var resp ChatResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Response != "plain" {
+		t.Errorf("expected response 'plain', got %s", resp.Response)
+	}
+}
+
+func TestChatHandler_SendsUserScopedHistory(t *testing.T) {
+	var received ChatRequest
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(ChatResponse{Response: "ok", UserID: received.UserID})
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	server.config.Session.PerUserHistory = true
+
+	session := server.sessionManager.GetOrCreateSession("")
+	server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "mom secret", UserID: "mom"})
+	server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "teen question", UserID: "teen"})
+
+	body, _ := json.Marshal(ChatRequest{UserID: "teen", Message: "hi"})
+	req := httptest.NewRequest("POST", "/api/chat", bytes.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+	w := httptest.NewRecorder()
+
+	server.ChatHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if len(received.ConversationHistory) != 1 || received.ConversationHistory[0].Content != "teen question" {
+		t.Errorf("expected only teen's history, got %+v", received.ConversationHistory)
+	}
+}
+
+func TestVoiceHandler_HistoryOfLastSpeaker(t *testing.T) {
+	var received []Message
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.Unmarshal([]byte(r.FormValue("conversation_history")), &received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"identified","user_id":"teen","transcript":"and now?","response":"ok"}`))
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	server.config.Session.PerUserHistory = true
+
+	session := server.sessionManager.GetOrCreateSession("")
+	server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "mom secret", UserID: "mom"})
+	server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "teen question", UserID: "teen"})
+
+	// The upload carries no user_id hint
+	w := httptest.NewRecorder()
+	server.VoiceHandler(w, newVoiceUpload(session.ID, []byte("RIFF fake wav")))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(received) != 1 || received[0].Content != "teen question" {
+		t.Errorf("expected only the last speaker's history, got %+v", received)
+	}
+}
+
+func TestVoiceHandler_StreamedUpload(t *testing.T) {
+	var (
+		audio    []byte
+		received []Message
+	)
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, _ := r.FormFile("file")
+		if file != nil {
+			audio, _ = io.ReadAll(file)
+		}
+		json.Unmarshal([]byte(r.FormValue("conversation_history")), &received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"identified","user_id":"mom","transcript":"hi","response":"ok"}`))
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	server.config.Orchestrator.StreamUploads = true
+	server.config.Session.PerUserHistory = true
+
+	session := server.sessionManager.GetOrCreateSession("")
+	server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "mom question", UserID: "mom"})
+	server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "teen question", UserID: "teen"})
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("user_id", "mom")
+	writer.WriteField("mime_type", "audio/wav")
+	part, _ := writer.CreateFormFile("file", "recording.wav")
+	part.Write([]byte("RIFF streamed wav"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/voice", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+	w := httptest.NewRecorder()
+
+	server.VoiceHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if string(audio) != "RIFF streamed wav" {
+		t.Errorf("expected the audio to be forwarded, got %q", audio)
+	}
+	if len(received) != 1 || received[0].Content != "mom question" {
+		t.Errorf("expected the hinted speaker's history, got %+v", received)
+	}
+}
+
+func TestVoiceHandler_FFmpegMissing(t *testing.T) {
+	withoutFFmpeg(t)
+
+	for _, stream := range []bool{false, true} {
+		server := newTestServer(t, "http://127.0.0.1:1")
+		server.config.Orchestrator.StreamUploads = stream
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("mime_type", "audio/webm")
+		part, _ := writer.CreateFormFile("file", "recording.webm")
+		part.Write([]byte("webm data"))
+		writer.Close()
+
+		req := newSessionRequest(server, "POST", "/api/voice", body.Bytes())
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+
+		server.VoiceHandler(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("stream=%v: expected status 500, got %d: %s", stream, w.Code, w.Body.String())
+		}
+
+		var errResp map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+			t.Fatalf("failed to decode error response: %v", err)
+		}
+		if errResp["code"] != "ffmpeg_missing" {
+			t.Errorf("stream=%v: expected code 'ffmpeg_missing', got %q", stream, errResp["code"])
+		}
+	}
+}
+
+func TestHealthHandler_ReportsFFmpeg(t *testing.T) {
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer orchestrator.Close()
+
+	t.Run("available", func(t *testing.T) {
+		withFakeFFmpeg(t)
+		server := newTestServer(t, orchestrator.URL)
+
+		w := httptest.NewRecorder()
+		server.HealthHandler(w, httptest.NewRequest("GET", "/api/health", nil))
+
+		var resp map[string]string
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp["ffmpeg"] != "available" || resp["ffmpeg_version"] != "6.1.1-test" {
+			t.Errorf("expected ffmpeg 6.1.1-test available, got %v", resp)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		withoutFFmpeg(t)
+		server := newTestServer(t, orchestrator.URL)
+
+		w := httptest.NewRecorder()
+		server.HealthHandler(w, httptest.NewRequest("GET", "/api/health", nil))
+
+		var resp map[string]string
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp["ffmpeg"] != "missing" {
+			t.Errorf("expected ffmpeg missing, got %v", resp)
+		}
+		if resp["status"] != "ok" {
+			t.Errorf("expected a missing ffmpeg not to change the overall status, got %q", resp["status"])
+		}
+	})
+}
+
+// newVoiceUpload builds a WAV voice upload for the given session
+func newVoiceUpload(sessionID string, audio []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("mime_type", "audio/wav")
+	part, _ := writer.CreateFormFile("file", "recording.wav")
+	part.Write(audio)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/voice", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+	return req
+}
+
+func TestVoiceHandler_DeduplicatesIdenticalUploads(t *testing.T) {
+	var calls int32
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"identified","user_id":"dad","transcript":"hello","response":"hi"}`))
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	session := server.sessionManager.GetOrCreateSession("")
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			server.VoiceHandler(w, newVoiceUpload(session.ID, []byte("same audio")))
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected one orchestrator call for identical uploads, got %d", n)
+	}
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("upload %d: expected status 200, got %d", i, code)
+		}
+	}
+	if history := server.sessionManager.GetHistory(session.ID); len(history) != 2 {
+		t.Errorf("expected the exchange recorded once (2 messages), got %d", len(history))
+	}
+}
+
+func TestVoiceHandler_DifferentUploadsNotDeduplicated(t *testing.T) {
+	var calls int32
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"identified","user_id":"dad","transcript":"hello","response":"hi"}`))
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	session := server.sessionManager.GetOrCreateSession("")
+
+	server.VoiceHandler(httptest.NewRecorder(), newVoiceUpload(session.ID, []byte("first")))
+	server.VoiceHandler(httptest.NewRecorder(), newVoiceUpload(session.ID, []byte("second")))
+
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expected two orchestrator calls for different audio, got %d", n)
+	}
+}
+
+func TestVoiceHandler_RetryAfterFailedUpload(t *testing.T) {
+	var calls int32
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"identified","user_id":"dad","transcript":"hello","response":"hi"}`))
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	session := server.sessionManager.GetOrCreateSession("")
+
+	first := httptest.NewRecorder()
+	server.VoiceHandler(first, newVoiceUpload(session.ID, []byte("same audio")))
+	retry := httptest.NewRecorder()
+	server.VoiceHandler(retry, newVoiceUpload(session.ID, []byte("same audio")))
+
+	if first.Code == http.StatusOK {
+		t.Fatalf("expected the first upload to fail, got %d", first.Code)
+	}
+	if retry.Code != http.StatusOK {
+		t.Errorf("expected the retry to reach the orchestrator, got %d: %s", retry.Code, retry.Body.String())
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expected two orchestrator calls, got %d", n)
+	}
+}
+
+func TestChatHandler_QuestionVisibleWhileInFlight(t *testing.T) {
+	var server *Server
+	var sessionID string
+	var seen []Message
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = server.sessionManager.GetHistory(sessionID)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"response":"4","model_used":"m","user_id":"dad"}`))
+	}))
+	defer orchestrator.Close()
+
+	server = newTestServer(t, orchestrator.URL)
+	req := newSessionRequest(server, "POST", "/api/chat", []byte(`{"user_id":"dad","message":"2+2?"}`))
+	sessionID = server.getSessionID(req)
+	w := httptest.NewRecorder()
+	server.ChatHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(seen) != 1 || seen[0].Content != "2+2?" {
+		t.Errorf("expected the question in history during the call, got %+v", seen)
+	}
+	history := server.sessionManager.GetHistory(sessionID)
+	if len(history) != 2 || history[0].Content != "2+2?" || history[1].Content != "4" {
+		t.Errorf("expected question then answer, got %+v", history)
+	}
+}
+
+func TestChatHandler_QuestionRolledBackOnFailure(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+	req := newSessionRequest(server, "POST", "/api/chat", []byte(`{"user_id":"dad","message":"2+2?"}`))
+	sessionID := server.getSessionID(req)
+	w := httptest.NewRecorder()
+	server.ChatHandler(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if history := server.sessionManager.GetHistory(sessionID); len(history) != 0 {
+		t.Errorf("expected the question to be rolled back, got %+v", history)
+	}
+}
+
+func TestExportHandler_Formats(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+	session := server.sessionManager.GetOrCreateSession("")
+	server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "Quelle heure est-il ?", UserID: "mom"})
+	server.sessionManager.AddMessage(session.ID, Message{Role: "assistant", Content: "Il est midi.", UserID: "mom"})
+
+	t.Run("txt", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/export?format=txt", nil)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+		w := httptest.NewRecorder()
+		server.ExportHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") || !strings.Contains(cd, ".txt") {
+			t.Errorf("expected a .txt attachment, got %q", cd)
+		}
+		body := w.Body.String()
+		for _, want := range []string{"mom: Quelle heure est-il ?", "Assistant: Il est midi.", time.Now().Format("2006-01-02")} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expected transcript to contain %q, got:\n%s", want, body)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/export?format=json", nil)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+		w := httptest.NewRecorder()
+		server.ExportHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, ".json") {
+			t.Errorf("expected a .json attachment, got %q", cd)
+		}
+
+		var export transcriptExport
+		if err := json.NewDecoder(w.Body).Decode(&export); err != nil {
+			t.Fatalf("failed to decode export: %v", err)
+		}
+		if export.SessionID != session.ID || len(export.Messages) != 2 {
+			t.Fatalf("unexpected export: %+v", export)
+		}
+		if export.Messages[1].Content != "Il est midi." || export.Messages[1].Timestamp.IsZero() {
+			t.Errorf("expected timestamped messages, got %+v", export.Messages[1])
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/export?format=pdf", nil)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+		w := httptest.NewRecorder()
+		server.ExportHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestExportHandler_MissingSession(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+
+	for name, cookie := range map[string]*http.Cookie{
+		"no cookie":       nil,
+		"unknown session": {Name: "session_id", Value: "does-not-exist"},
+	} {
+		req := httptest.NewRequest("GET", "/api/export?format=json", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		server.ExportHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestGetSessionID_PrefersLiveCookie(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+	live := server.sessionManager.GetOrCreateSession("")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "stale-from-old-path"})
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: live.ID})
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "another-stale-one"})
+
+	if got := server.getSessionID(req); got != live.ID {
+		t.Errorf("expected live session %q, got %q", live.ID, got)
+	}
+}
+
+func TestChatHandler_DuplicateCookiesKeepHistory(t *testing.T) {
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ChatResponse{Response: "4", UserID: "dad"})
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	live := server.sessionManager.GetOrCreateSession("")
+
+	req := httptest.NewRequest("POST", "/api/chat", strings.NewReader(`{"user_id":"dad","message":"2+2?"}`))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "stale-from-old-path"})
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: live.ID})
+	w := httptest.NewRecorder()
+	server.ChatHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no new session cookie, got %+v", cookies)
+	}
+	if history := server.sessionManager.GetHistory(live.ID); len(history) != 2 {
+		t.Errorf("expected the exchange in the live session, got %+v", history)
+	}
+}
+
+func TestChatHandler_OnlyStaleCookiesStartFreshSession(t *testing.T) {
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ChatResponse{Response: "4", UserID: "dad"})
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+
+	req := httptest.NewRequest("POST", "/api/chat", strings.NewReader(`{"user_id":"dad","message":"2+2?"}`))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "stale-one"})
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "stale-two"})
+	w := httptest.NewRecorder()
+	server.ChatHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session_id" {
+		t.Fatalf("expected a fresh session cookie, got %+v", cookies)
+	}
+	fresh := cookies[0].Value
+	if fresh == "stale-one" || fresh == "stale-two" || !server.sessionManager.HasSession(fresh) {
+		t.Fatalf("expected a new live session, got %q", fresh)
+	}
+	if history := server.sessionManager.GetHistory(fresh); len(history) != 2 {
+		t.Errorf("expected the exchange in the fresh session, got %+v", history)
+	}
+}
+
+func TestIndexHandler_VoicePreferenceRoundTrips(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+	server.config.TTS.VoicePreference = []string{"Julie"}
+
+	req := newSessionRequest(server, "GET", "/?voice=Hortense", nil)
+	w := httptest.NewRecorder()
+	server.IndexHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var voiceCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == voiceCookieName {
+			voiceCookie = cookie
+		}
+	}
+	if voiceCookie == nil || voiceCookie.Value != "Hortense" {
+		t.Fatalf("expected a voice cookie for Hortense, got %+v", voiceCookie)
+	}
+
+	// The same session remembers the choice without the query param
+	next := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range req.Cookies() {
+		next.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	server.IndexHandler(w, next)
+
+	body := w.Body.String()
+	if !strings.Contains(body, `voicePreference: "Hortense"`) {
+		t.Errorf("expected the session's voice in the page, got:\n%s", body)
+	}
+	if !strings.Contains(body, `voicePreferences: ["Hortense","Julie"]`) {
+		t.Errorf("expected the session's voice tried first, got:\n%s", body)
+	}
+}
+
+func TestIndexHandler_VoicePreferenceFromCookie(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+
+	// A fresh session picks the earlier choice back up from the cookie
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: voiceCookieName, Value: "Microsoft+Hortense"})
+	w := httptest.NewRecorder()
+	server.IndexHandler(w, req)
+
+	var sessionID string
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session_id" {
+			sessionID = cookie.Value
+		}
+	}
+	if voice := server.sessionManager.GetVoicePreference(sessionID); voice != "Microsoft Hortense" {
+		t.Errorf("expected the cookie's voice on the new session, got %q", voice)
+	}
+}
+
+func TestIndexHandler_EmptyVoiceClearsPreference(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+
+	req := newSessionRequest(server, "GET", "/?voice=", nil)
+	sessionID := server.getSessionID(req)
+	server.sessionManager.SetVoicePreference(sessionID, "Hortense")
+	w := httptest.NewRecorder()
+	server.IndexHandler(w, req)
+
+	if voice := server.sessionManager.GetVoicePreference(sessionID); voice != "" {
+		t.Errorf("expected the preference cleared, got %q", voice)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == voiceCookieName && cookie.MaxAge >= 0 {
+			t.Errorf("expected the voice cookie deleted, got %+v", cookie)
+		}
+	}
+}
+
+func TestCreateSession_CookieFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		secure     bool
+		domain     string
+		path       string
+		wantPath   string
+		wantSecure bool
+	}{
+		{"localhost default", false, "", "", "/", false},
+		{"secure with scope", true, "assistant.home", "/app", "/app", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, "http://127.0.0.1:1")
+			server.config.Session.SecureCookie = tt.secure
+			server.config.Session.CookieDomain = tt.domain
+			server.config.Session.CookiePath = tt.path
+
+			w := httptest.NewRecorder()
+			server.IndexHandler(w, httptest.NewRequest("GET", "/", nil))
+
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != "session_id" {
+				t.Fatalf("expected a session cookie, got %+v", cookies)
+			}
+			cookie := cookies[0]
+			if cookie.Secure != tt.wantSecure {
+				t.Errorf("expected Secure=%v, got %v", tt.wantSecure, cookie.Secure)
+			}
+			if cookie.Domain != tt.domain || cookie.Path != tt.wantPath {
+				t.Errorf("expected domain %q path %q, got %q %q", tt.domain, tt.wantPath, cookie.Domain, cookie.Path)
+			}
+			if !cookie.HttpOnly {
+				t.Error("expected the session cookie to stay HttpOnly")
+			}
+		})
+	}
+}
+
+// sharedErrorBody is the error envelope both tiers must produce for the same
+// inputs; the orchestrator's tests assert the identical body
+const sharedErrorBody = `{"code":"quiet_hours","message":"quiet time","error":"quiet time","detail":"until 07:00"}`
+
+func TestSendJSONErrorCode_SharedEnvelope(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+
+	w := httptest.NewRecorder()
+	server.sendJSONErrorCode(w, "quiet time", "quiet_hours", http.StatusForbidden, "until 07:00")
+
+	if got := strings.TrimSpace(w.Body.String()); got != sharedErrorBody {
+		t.Errorf("expected %s, got %s", sharedErrorBody, got)
+	}
+
+	// Errors without a code get the generic one for their status
+	w = httptest.NewRecorder()
+	server.sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+	if got, want := strings.TrimSpace(w.Body.String()), `{"code":"method_not_allowed","message":"Method not allowed","error":"Method not allowed"}`; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestChatHandler_ResumedIdleSession(t *testing.T) {
+	tests := []struct {
+		mode string
+		want []string // History contents after the resumed exchange
+	}{
+		{idleResetClear, []string{"2+2?", "4"}},
+		{idleResetDivider, []string{"old question", "old answer", "Rebonjour !", "2+2?", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(ChatResponse{Response: "4", UserID: "dad"})
+			}))
+			defer orchestrator.Close()
+
+			server := newTestServer(t, orchestrator.URL)
+			clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+			server.sessionManager.SetClock(clock)
+			server.sessionManager.SetIdleReset(30*time.Minute, tt.mode, "Rebonjour !")
+
+			session := server.sessionManager.GetOrCreateSession("")
+			server.sessionManager.AddMessage(session.ID, Message{Role: "user", Content: "old question", UserID: "dad"})
+			server.sessionManager.AddMessage(session.ID, Message{Role: "assistant", Content: "old answer", UserID: "dad"})
+			clock.Advance(2 * time.Hour)
+
+			req := httptest.NewRequest("POST", "/api/chat", strings.NewReader(`{"user_id":"dad","message":"2+2?"}`))
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+			w := httptest.NewRecorder()
+			server.ChatHandler(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			history := server.sessionManager.GetHistory(session.ID)
+			var got []string
+			for _, msg := range history {
+				got = append(got, msg.Content)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("expected history %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggingMiddleware_LogsStatus(t *testing.T) {
+	var buf bytes.Buffer
+	handler, _ := newLogHandler("json", "info", &buf)
+	logger := slog.New(handler)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusTeapot)
+	})
+	w := httptest.NewRecorder()
+	loggingMiddleware(logger, next).ServeHTTP(w, httptest.NewRequest("GET", "/api/health", nil))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "request completed" {
+		t.Errorf("expected request completed, got %v", entry["msg"])
+	}
+	if entry["method"] != "GET" || entry["path"] != "/api/health" {
+		t.Errorf("expected GET /api/health, got %v %v", entry["method"], entry["path"])
+	}
+	if entry["status"] != float64(http.StatusTeapot) {
+		t.Errorf("expected status 418, got %v", entry["status"])
+	}
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Error("expected duration_ms in the log entry")
+	}
+}
+
+func TestLoggingMiddleware_KeepsFlusher(t *testing.T) {
+	var buf bytes.Buffer
+	handler, _ := newLogHandler("json", "info", &buf)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("expected the wrapped writer to support flushing for SSE")
+		}
+	})
+	loggingMiddleware(slog.New(handler), next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/chat", nil))
+}
+
+func TestNewLogHandler_UnknownValuesWarn(t *testing.T) {
+	var buf bytes.Buffer
+	handler, warnings := newLogHandler("xml", "loud", &buf)
+	if len(warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", warnings)
+	}
+
+	slog.New(handler).Info("hello")
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("expected JSON fallback, got %q", buf.String())
+	}
+}
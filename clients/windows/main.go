@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,9 +14,8 @@ import (
 func main() {
 	// Load configuration
 	cfg, err := LoadConfig("config.yaml")
+	configErr := err
 	if err != nil {
-		log.Printf("Warning: Failed to load config.yaml: %v", err)
-		log.Println("Using default configuration")
 		cfg = &Config{}
 		cfg.Server.Host = "127.0.0.1"
 		cfg.Server.Port = 10090
@@ -26,28 +25,43 @@ func main() {
 		cfg.TTS.Enabled = true
 	}
 
+	// Setup logger
+	handler, warnings := newLogHandler(cfg.Log.Format, cfg.Log.Level, os.Stdout)
+	logger := slog.New(handler)
+	for _, warning := range warnings {
+		logger.Warn("log configuration", "warning", warning)
+	}
+	if configErr != nil {
+		logger.Warn("failed to load config.yaml, using default configuration", "error", configErr)
+	}
+
 	// Create server
-	server, err := NewServer(cfg)
+	server, err := NewServer(cfg, logger)
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+		logger.Error("failed to create server", "error", err)
+		os.Exit(1)
 	}
 
-	// Start session cleanup routine
+	// Start session cleanup and snapshot routines
 	server.StartCleanupRoutine()
+	stopSnapshots := make(chan struct{})
+	server.StartSnapshotRoutine(stopSnapshots)
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", server.IndexHandler)
+	mux.Handle("/static/", server.static)
 	mux.HandleFunc("/api/voice", server.VoiceHandler)
 	mux.HandleFunc("/api/chat", server.ChatHandler)
 	mux.HandleFunc("/api/health", server.HealthHandler)
 	mux.HandleFunc("/api/clear-history", server.ClearHistoryHandler)
+	mux.HandleFunc("/api/export", server.ExportHandler)
 
 	// Create HTTP server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	httpServer := &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      loggingMiddleware(logger, mux),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 90 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -59,28 +73,32 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting Windows Go Client on %s", addr)
-		log.Printf("Orchestrator URL: %s", cfg.Orchestrator.URL)
-		log.Printf("Open http://%s in Microsoft Edge to use the assistant", addr)
-		
+		logger.Info("starting Windows Go Client",
+			"addr", addr,
+			"orchestrator_url", cfg.Orchestrator.URL,
+			"open", fmt.Sprintf("http://%s in Microsoft Edge to use the assistant", addr),
+		)
+
 		// Check orchestrator health on startup
 		err := server.proxy.CheckHealth()
 		if err != nil {
-			log.Printf("WARNING: Orchestrator is not reachable at %s", cfg.Orchestrator.URL)
-			log.Printf("         The client will start anyway, but voice/chat features won't work until the orchestrator is available")
-			log.Printf("         Error: %v", err)
+			logger.Warn("orchestrator is not reachable; voice/chat features won't work until it is available",
+				"orchestrator_url", cfg.Orchestrator.URL,
+				"error", err,
+			)
 		} else {
-			log.Printf("Orchestrator health check passed")
+			logger.Info("orchestrator health check passed")
 		}
-		
+
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			logger.Error("server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	// Wait for interrupt signal
 	<-stop
-	log.Println("\nShutting down gracefully...")
+	logger.Info("shutting down gracefully")
 
 	// Create shutdown context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -88,8 +106,14 @@ func main() {
 
 	// Shutdown server
 	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+		logger.Error("server shutdown error", "error", err)
+	}
+
+	// Save sessions once no request can change them anymore
+	close(stopSnapshots)
+	if err := server.SaveSessions(); err != nil {
+		logger.Error("failed to save sessions", "error", err)
 	}
 
-	log.Println("Server stopped")
+	logger.Info("server stopped")
 }
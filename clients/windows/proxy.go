@@ -2,21 +2,66 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ErrFFmpegMissing is returned when audio needs converting but ffmpeg is not installed
+var ErrFFmpegMissing = errors.New("ffmpeg not found")
+
+// lookPath locates executables, replaced in tests
+var lookPath = exec.LookPath
+
 // OrchestratorProxy handles communication with the WSL orchestrator
 type OrchestratorProxy struct {
-	baseURL string
-	timeout time.Duration
-	client  *http.Client
+	baseURL      string
+	timeout      time.Duration
+	client       *http.Client
+	maxRetries   int                // Extra attempts after a connection error
+	retryBackoff time.Duration      // Pause before each retry
+	audio        audioFormat        // WAV format produced by ffmpeg conversions
+	conversions  *conversionLimiter // nil runs conversions without a limit
+}
+
+// audioFormat is the WAV format non-WAV recordings are converted to
+type audioFormat struct {
+	sampleRate int
+	channels   int
+	loudnorm   bool // Normalize loudness so quiet recordings reach the sidecar at a usable level
+}
+
+// defaultAudioFormat is 16kHz mono, as required by Whisper
+var defaultAudioFormat = audioFormat{sampleRate: 16000, channels: 1}
+
+// ffmpegArgs returns the ffmpeg arguments converting input to WAV in this
+// format, written to output
+func (f audioFormat) ffmpegArgs(input, output string) []string {
+	// -ar: Sample rate
+	// -ac: Channel count
+	// -af loudnorm: EBU R128 loudness normalization, when enabled
+	// -f wav: Force WAV output format
+	args := []string{"-i", input}
+	if f.loudnorm {
+		args = append(args, "-af", "loudnorm")
+	}
+	return append(args,
+		"-ar", strconv.Itoa(f.sampleRate),
+		"-ac", strconv.Itoa(f.channels),
+		"-f", "wav",
+		"-y", // Overwrite output file
+		output,
+	)
 }
 
 // NewOrchestratorProxy creates a new orchestrator proxy
@@ -27,9 +72,32 @@ func NewOrchestratorProxy(baseURL string, timeoutSeconds int) *OrchestratorProxy
 		client: &http.Client{
 			Timeout: time.Duration(timeoutSeconds) * time.Second,
 		},
+		maxRetries:   1,
+		retryBackoff: 250 * time.Millisecond,
+		audio:        defaultAudioFormat,
 	}
 }
 
+// SetAudioFormat sets the sample rate and channel count recordings are
+// converted to before being forwarded
+func (p *OrchestratorProxy) SetAudioFormat(sampleRate, channels int) {
+	p.audio.sampleRate = sampleRate
+	p.audio.channels = channels
+}
+
+// SetConversionLimit caps concurrent ffmpeg conversions at max, 0 lifting
+// the cap. Conversions beyond it wait up to wait for a slot, then fail with
+// ErrConversionBusy.
+func (p *OrchestratorProxy) SetConversionLimit(max int, wait time.Duration) {
+	p.conversions = newConversionLimiter(max, wait)
+}
+
+// SetLoudnessNormalization enables ffmpeg's loudnorm filter on converted
+// recordings. WAV uploads are forwarded as-is and are not normalized.
+func (p *OrchestratorProxy) SetLoudnessNormalization(enabled bool) {
+	p.audio.loudnorm = enabled
+}
+
 // VoiceRequest represents the voice endpoint request
 type VoiceRequest struct {
 	AudioData           []byte    `json:"-"` // WAV file data
@@ -46,6 +114,7 @@ type VoiceResponse struct {
 	Response   string  `json:"response,omitempty"`
 	Fallback   bool    `json:"fallback,omitempty"`
 	ModelUsed  string  `json:"model_used,omitempty"`
+	Truncated  bool    `json:"truncated,omitempty"` // Set by the client when Response was shortened for speech
 }
 
 // ChatRequest represents the chat endpoint request
@@ -60,54 +129,77 @@ type ChatResponse struct {
 	Response  string `json:"response"`
 	ModelUsed string `json:"model_used,omitempty"`
 	UserID    string `json:"user_id,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"` // Set by the client when Response was shortened for speech
 }
 
 // ForwardVoice forwards a WAV file to the orchestrator's /voice endpoint
 func (p *OrchestratorProxy) ForwardVoice(audioData []byte, mimeType string, history []Message) (*VoiceResponse, error) {
 	// Convert WebM to WAV if necessary
 	if mimeType != "" && !isWAVFormat(mimeType) {
-		var err error
-		audioData, err = convertToWAV(audioData)
+		release, err := p.conversions.acquire()
+		if err != nil {
+			return nil, err
+		}
+		audioData, err = convertToWAV(audioData, p.audio)
+		release()
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert audio to WAV: %w", err)
 		}
 	}
 
-	// Create multipart form data
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	// Stream the multipart body rather than buffering a second copy of the
+	// audio; each attempt rebuilds it from audioData under the same boundary
+	form := multipart.NewWriter(io.Discard)
+	newBody := func() io.Reader {
+		body, _ := pipeVoiceForm(bytes.NewReader(audioData), history, form.Boundary())
+		return body
+	}
 
-	// Add the audio file
-	part, err := writer.CreateFormFile("file", "recording.wav")
+	// Send request
+	url := fmt.Sprintf("%s/voice", p.baseURL)
+	resp, err := p.doWithRetry(context.Background(), "POST", url, form.FormDataContentType(), newBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
-	}
-	if _, err := part.Write(audioData); err != nil {
-		return nil, fmt.Errorf("failed to write audio data: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Add conversation history as JSON field
-	if len(history) > 0 {
-		historyJSON, err := json.Marshal(history)
+	return parseVoiceResponse(resp)
+}
+
+// ForwardVoiceStream forwards audio to the orchestrator's /voice endpoint without
+// buffering it: the multipart body is produced through a pipe while it is sent,
+// and non-WAV input is converted by streaming through ffmpeg. A streamed body
+// cannot be replayed, so connection errors are not retried.
+func (p *OrchestratorProxy) ForwardVoiceStream(audio io.Reader, mimeType string, history []Message) (*VoiceResponse, error) {
+	// Convert WebM to WAV if necessary
+	if mimeType != "" && !isWAVFormat(mimeType) {
+		// ffmpeg runs until the upload is sent, so the slot is held until then
+		release, err := p.conversions.acquire()
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal history: %w", err)
+			return nil, err
 		}
-		if err := writer.WriteField("conversation_history", string(historyJSON)); err != nil {
-			return nil, fmt.Errorf("failed to write history field: %w", err)
+		defer release()
+		converted, err := convertToWAVStream(audio, p.audio)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert audio to WAV: %w", err)
 		}
+		defer converted.Close()
+		audio = converted
 	}
 
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
-	}
+	// Produce the multipart body on the fly
+	body, contentType := pipeVoiceForm(audio, history, "")
+	defer body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
 
-	// Create request
 	url := fmt.Sprintf("%s/voice", p.baseURL)
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Type", contentType)
 
 	// Send request
 	resp, err := p.client.Do(req)
@@ -116,6 +208,58 @@ func (p *OrchestratorProxy) ForwardVoice(audioData []byte, mimeType string, hist
 	}
 	defer resp.Body.Close()
 
+	return parseVoiceResponse(resp)
+}
+
+// pipeVoiceForm writes the voice form into a pipe from a goroutine and returns
+// the reading end with the form's content type. A write that fails partway,
+// such as an audio read error, closes the pipe with that error, so the
+// request carrying the body fails instead of sending a truncated form.
+// Closing the reader stops the goroutine. An empty boundary picks a random one.
+func pipeVoiceForm(audio io.Reader, history []Message, boundary string) (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	if boundary != "" {
+		writer.SetBoundary(boundary)
+	}
+	go func() {
+		pw.CloseWithError(writeVoiceForm(writer, audio, history))
+	}()
+	return pr, writer.FormDataContentType()
+}
+
+// writeVoiceForm writes the audio file and conversation history as multipart
+// fields and closes the writer
+func writeVoiceForm(writer *multipart.Writer, audio io.Reader, history []Message) error {
+	// Add the audio file
+	part, err := writer.CreateFormFile("file", "recording.wav")
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return fmt.Errorf("failed to write audio data: %w", err)
+	}
+
+	// Add conversation history as JSON field
+	if len(history) > 0 {
+		historyJSON, err := json.Marshal(history)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history: %w", err)
+		}
+		if err := writer.WriteField("conversation_history", string(historyJSON)); err != nil {
+			return fmt.Errorf("failed to write history field: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	return nil
+}
+
+// parseVoiceResponse reads and decodes the orchestrator's /voice response
+func parseVoiceResponse(resp *http.Response) (*VoiceResponse, error) {
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -135,26 +279,20 @@ func (p *OrchestratorProxy) ForwardVoice(audioData []byte, mimeType string, hist
 	return &voiceResp, nil
 }
 
-// ForwardChat forwards a text message to the orchestrator's /chat endpoint
-func (p *OrchestratorProxy) ForwardChat(req ChatRequest) (*ChatResponse, error) {
+// ForwardChat forwards a text message to the orchestrator's /chat endpoint.
+// Cancelling ctx (e.g. the browser navigating away) aborts the request.
+func (p *OrchestratorProxy) ForwardChat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	// Marshal request
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Create HTTP request
-	url := fmt.Sprintf("%s/chat", p.baseURL)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	// Send request
-	resp, err := p.client.Do(httpReq)
+	url := fmt.Sprintf("%s/chat", p.baseURL)
+	resp, err := p.doWithRetry(ctx, "POST", url, "application/json", func() io.Reader { return bytes.NewReader(reqBody) })
 	if err != nil {
-		return nil, fmt.Errorf("orchestrator unavailable: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -177,10 +315,72 @@ func (p *OrchestratorProxy) ForwardChat(req ChatRequest) (*ChatResponse, error)
 	return &chatResp, nil
 }
 
+// doWithRetry sends a request, retrying on connection errors while the overall
+// deadline (the configured timeout) leaves room for another attempt. newBody
+// is called for a fresh body on every attempt.
+func (p *OrchestratorProxy) doWithRetry(parent context.Context, method, url, contentType string, newBody func() io.Reader) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(parent, p.timeout)
+
+	var lastErr error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		if attempt > 0 {
+			// Give up if the backoff would run past the deadline
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= p.retryBackoff {
+				break
+			}
+			select {
+			case <-time.After(p.retryBackoff):
+			case <-ctx.Done():
+				cancel()
+				return nil, fmt.Errorf("orchestrator unavailable: %w", ctx.Err())
+			}
+		}
+
+		body := newBody()
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			if closer, ok := body.(io.Closer); ok {
+				closer.Close() // Stops a pipe's writer
+			}
+			cancel()
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := p.client.Do(req)
+		if err == nil {
+			// Release the deadline once the caller has read the body
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	cancel()
+	return nil, fmt.Errorf("orchestrator unavailable: %w", lastErr)
+}
+
+// cancelOnClose releases a request context when the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the context
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
 // CheckHealth checks if the orchestrator is reachable
 func (p *OrchestratorProxy) CheckHealth() error {
 	url := fmt.Sprintf("%s/health", p.baseURL)
-	
+
 	// Use a shorter timeout for health checks
 	client := &http.Client{
 		Timeout: 5 * time.Second,
@@ -204,8 +404,78 @@ func isWAVFormat(mimeType string) bool {
 	return mimeType == "audio/wav" || mimeType == "audio/wave" || mimeType == "audio/x-wav"
 }
 
-// convertToWAV converts audio data to WAV format using ffmpeg
-func convertToWAV(inputData []byte) ([]byte, error) {
+// ffmpegPath locates the ffmpeg binary, wrapping ErrFFmpegMissing if it is not on PATH
+func ffmpegPath() (string, error) {
+	path, err := lookPath("ffmpeg")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFFmpegMissing, err)
+	}
+	return path, nil
+}
+
+// ffmpegStatus reports whether ffmpeg can be run and which version it is
+type ffmpegStatus struct {
+	Available bool
+	Version   string // Empty when the version line could not be parsed
+	Detail    string // Why ffmpeg is unavailable
+}
+
+// ffmpegChecker runs "ffmpeg -version" and caches the result, so health
+// checks do not spawn a process on every poll
+type ffmpegChecker struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	status  ffmpegStatus
+	checked time.Time
+}
+
+func newFFmpegChecker(timeout, ttl time.Duration) *ffmpegChecker {
+	return &ffmpegChecker{timeout: timeout, ttl: ttl}
+}
+
+// Status returns the cached ffmpeg status, probing again once it is stale
+func (c *ffmpegChecker) Status() ffmpegStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checked.IsZero() && time.Since(c.checked) < c.ttl {
+		return c.status
+	}
+	c.status = c.probe()
+	c.checked = time.Now()
+	return c.status
+}
+
+func (c *ffmpegChecker) probe() ffmpegStatus {
+	path, err := ffmpegPath()
+	if err != nil {
+		return ffmpegStatus{Detail: err.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		return ffmpegStatus{Detail: fmt.Sprintf("ffmpeg -version failed: %v", err)}
+	}
+	return ffmpegStatus{Available: true, Version: parseFFmpegVersion(string(out))}
+}
+
+// parseFFmpegVersion extracts the version from the first line of
+// "ffmpeg -version", e.g. "ffmpeg version 6.1.1-full_build Copyright ..."
+func parseFFmpegVersion(output string) string {
+	fields := strings.Fields(strings.SplitN(output, "\n", 2)[0])
+	if len(fields) >= 3 && fields[0] == "ffmpeg" && fields[1] == "version" {
+		return fields[2]
+	}
+	return ""
+}
+
+// convertToWAV converts audio data to WAV in the given format using ffmpeg
+func convertToWAV(inputData []byte, format audioFormat) ([]byte, error) {
 	// Create temporary files for input and output
 	tmpInput, err := os.CreateTemp("", "input-*.webm")
 	if err != nil {
@@ -227,18 +497,13 @@ func convertToWAV(inputData []byte) ([]byte, error) {
 	}
 	tmpInput.Close()
 
+	ffmpeg, err := ffmpegPath()
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert using ffmpeg
-	// -ar 16000: Sample rate 16kHz (required by Whisper)
-	// -ac 1: Mono channel
-	// -f wav: Force WAV output format
-	cmd := exec.Command("ffmpeg",
-		"-i", tmpInput.Name(),
-		"-ar", "16000",
-		"-ac", "1",
-		"-f", "wav",
-		"-y", // Overwrite output file
-		tmpOutput.Name(),
-	)
+	cmd := exec.Command(ffmpeg, format.ffmpegArgs(tmpInput.Name(), tmpOutput.Name())...)
 
 	// Capture stderr for error messages
 	var stderr bytes.Buffer
@@ -256,3 +521,70 @@ func convertToWAV(inputData []byte) ([]byte, error) {
 
 	return wavData, nil
 }
+
+// convertToWAVStream converts audio to WAV by piping it through ffmpeg's
+// stdin/stdout, avoiding temp files. The returned reader reports ffmpeg
+// failures at EOF and must be closed.
+func convertToWAVStream(input io.Reader, format audioFormat) (io.ReadCloser, error) {
+	ffmpeg, err := ffmpegPath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Same parameters as convertToWAV, reading pipe:0 and writing pipe:1
+	cmd := exec.Command(ffmpeg, format.ffmpegArgs("pipe:0", "pipe:1")...)
+	cmd.Stdin = input
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ffmpeg stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	return &ffmpegStream{stdout: stdout, cmd: cmd, stderr: stderr}, nil
+}
+
+// ffmpegStream exposes a running ffmpeg conversion as an io.ReadCloser
+type ffmpegStream struct {
+	stdout io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+
+	waitOnce sync.Once // Read and Close may race, but ffmpeg is reaped once
+	waitErr  error
+}
+
+// Read reads converted WAV data, surfacing ffmpeg's exit status at EOF
+func (f *ffmpegStream) Read(p []byte) (int, error) {
+	n, err := f.stdout.Read(p)
+	if err == io.EOF {
+		if waitErr := f.wait(false); waitErr != nil {
+			return n, fmt.Errorf("ffmpeg conversion failed: %w, stderr: %s", waitErr, f.stderr.String())
+		}
+	}
+	return n, err
+}
+
+// Close stops ffmpeg if it is still running and releases its resources
+func (f *ffmpegStream) Close() error {
+	f.wait(true)
+	return nil
+}
+
+// wait reaps ffmpeg the first time it is called, killing it first when kill
+// is set, and returns its exit status
+func (f *ffmpegStream) wait(kill bool) error {
+	f.waitOnce.Do(func() {
+		if kill {
+			f.cmd.Process.Kill()
+		}
+		f.waitErr = f.cmd.Wait()
+	})
+	return f.waitErr
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyOrchestrator drops the connection for the first failures requests
+// and then answers with handler
+func newFlakyOrchestrator(t *testing.T, failures int32, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n <= failures {
+			hj, ok := w.(http.Hijacker)
+			if !ok {
+				t.Fatal("response writer does not support hijacking")
+			}
+			conn, _, err := hj.Hijack()
+			if err != nil {
+				t.Fatalf("hijack failed: %v", err)
+			}
+			conn.Close()
+			return
+		}
+		handler(w, r)
+	}))
+	return server, &calls
+}
+
+func TestForwardChat_RetriesConnectionError(t *testing.T) {
+	server, calls := newFlakyOrchestrator(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode retried body: %v", err)
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Response: "echo: " + req.Message, UserID: req.UserID})
+	})
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 5)
+	proxy.retryBackoff = 10 * time.Millisecond
+
+	resp, err := proxy.ForwardChat(context.Background(), ChatRequest{UserID: "dad", Message: "hello"})
+	if err != nil {
+		t.Fatalf("ForwardChat failed: %v", err)
+	}
+
+	if resp.Response != "echo: hello" {
+		t.Errorf("expected response 'echo: hello', got %s", resp.Response)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
+
+func TestForwardVoice_RetriesWithRebuiltBody(t *testing.T) {
+	server, calls := newFlakyOrchestrator(t, 1, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Errorf("failed to parse retried multipart body: %v", err)
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("expected file in retried request: %v", err)
+		} else {
+			file.Close()
+		}
+		json.NewEncoder(w).Encode(VoiceResponse{Status: "identified", UserID: "mom"})
+	})
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 5)
+	proxy.retryBackoff = 10 * time.Millisecond
+
+	resp, err := proxy.ForwardVoice([]byte("RIFF....WAVE"), "audio/wav", nil)
+	if err != nil {
+		t.Fatalf("ForwardVoice failed: %v", err)
+	}
+
+	if resp.Status != "identified" {
+		t.Errorf("expected status 'identified', got %s", resp.Status)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
+
+func TestForwardChat_GivesUpAfterRetryBudget(t *testing.T) {
+	server, calls := newFlakyOrchestrator(t, 10, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no successful call")
+	})
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 5)
+	proxy.retryBackoff = 10 * time.Millisecond
+
+	if _, err := proxy.ForwardChat(context.Background(), ChatRequest{UserID: "dad", Message: "hello"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
+
+func TestForwardChat_NoRetryOnStatusError(t *testing.T) {
+	server, calls := newFlakyOrchestrator(t, 0, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 5)
+	proxy.retryBackoff = 10 * time.Millisecond
+
+	if _, err := proxy.ForwardChat(context.Background(), ChatRequest{UserID: "dad", Message: "hello"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+}
+
+func TestForwardChat_RespectsDeadline(t *testing.T) {
+	server, calls := newFlakyOrchestrator(t, 10, nil)
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 1)
+	proxy.retryBackoff = 2 * time.Second
+
+	start := time.Now()
+	if _, err := proxy.ForwardChat(context.Background(), ChatRequest{UserID: "dad", Message: "hello"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to give up within the 1s timeout, took %v", elapsed)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+}
+
+// capturedVoiceUpload records what the orchestrator received on /voice
+type capturedVoiceUpload struct {
+	audio   []byte
+	history string
+}
+
+func newCapturingOrchestrator(t *testing.T, uploads *[]capturedVoiceUpload) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Errorf("failed to parse multipart body: %v", err)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("expected file in request: %v", err)
+			return
+		}
+		defer file.Close()
+
+		audio, _ := io.ReadAll(file)
+		*uploads = append(*uploads, capturedVoiceUpload{
+			audio:   audio,
+			history: r.FormValue("conversation_history"),
+		})
+		json.NewEncoder(w).Encode(VoiceResponse{Status: "identified", UserID: "teen"})
+	}))
+}
+
+func TestForwardVoiceStream_MatchesBuffered(t *testing.T) {
+	var uploads []capturedVoiceUpload
+	server := newCapturingOrchestrator(t, &uploads)
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 5)
+
+	// Large enough to span several pipe writes
+	audio := bytes.Repeat([]byte("RIFF-audio-sample-"), 64*1024)
+	history := []Message{
+		{Role: "user", Content: "hi", UserID: "teen"},
+		{Role: "assistant", Content: "hello", UserID: "teen"},
+	}
+
+	buffered, err := proxy.ForwardVoice(audio, "audio/wav", history)
+	if err != nil {
+		t.Fatalf("ForwardVoice failed: %v", err)
+	}
+
+	streamed, err := proxy.ForwardVoiceStream(bytes.NewReader(audio), "audio/wav", history)
+	if err != nil {
+		t.Fatalf("ForwardVoiceStream failed: %v", err)
+	}
+
+	if buffered.Status != streamed.Status || buffered.UserID != streamed.UserID {
+		t.Errorf("expected identical responses, got %+v and %+v", buffered, streamed)
+	}
+
+	if len(uploads) != 2 {
+		t.Fatalf("expected 2 uploads, got %d", len(uploads))
+	}
+	if !bytes.Equal(uploads[0].audio, uploads[1].audio) {
+		t.Errorf("streamed audio differs from buffered audio (%d vs %d bytes)", len(uploads[1].audio), len(uploads[0].audio))
+	}
+	if !bytes.Equal(uploads[1].audio, audio) {
+		t.Error("streamed audio differs from the original recording")
+	}
+	if uploads[0].history != uploads[1].history {
+		t.Errorf("expected identical history, got %q and %q", uploads[0].history, uploads[1].history)
+	}
+}
+
+// failingReader yields some data, then fails
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestPipeVoiceForm_PropagatesWriteError(t *testing.T) {
+	errDisk := errors.New("disk gone")
+	body, _ := pipeVoiceForm(&failingReader{data: []byte("RIFF....WAVE"), err: errDisk}, nil, "")
+	defer body.Close()
+
+	_, err := io.ReadAll(body)
+	if !errors.Is(err, errDisk) {
+		t.Fatalf("expected the audio read error from the body, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "failed to write audio data") {
+		t.Errorf("expected the failing step in the error, got %v", err)
+	}
+}
+
+func TestPipeVoiceForm_CloseStopsWriter(t *testing.T) {
+	body, _ := pipeVoiceForm(bytes.NewReader(bytes.Repeat([]byte("a"), 1<<20)), nil, "")
+
+	// Closing before the form is read must not leave the writer blocked
+	if err := body.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := body.Read(make([]byte, 1)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("expected a closed pipe, got %v", err)
+	}
+}
+
+func TestForwardVoiceStream_AudioErrorFailsRequest(t *testing.T) {
+	var completed int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(&completed, 1)
+		json.NewEncoder(w).Encode(VoiceResponse{Status: "identified", UserID: "dad"})
+	}))
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 5)
+	errTruncated := errors.New("recording truncated")
+	audio := &failingReader{data: bytes.Repeat([]byte("RIFF"), 1024), err: errTruncated}
+
+	if _, err := proxy.ForwardVoiceStream(audio, "audio/wav", nil); !errors.Is(err, errTruncated) {
+		t.Fatalf("expected the audio read error to propagate, got %v", err)
+	}
+	if got := atomic.LoadInt32(&completed); got != 0 {
+		t.Errorf("expected the orchestrator never to see a complete form, got %d", got)
+	}
+}
+
+// withoutFFmpeg makes ffmpeg lookups fail for the duration of a test
+func withoutFFmpeg(t *testing.T) {
+	t.Helper()
+	original := lookPath
+	lookPath = func(file string) (string, error) {
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+	t.Cleanup(func() { lookPath = original })
+}
+
+// withFakeFFmpeg points ffmpeg lookups at a shell script printing a version
+// banner and recording each run in the returned log file
+func withFakeFFmpeg(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := filepath.Join(dir, "ffmpeg")
+	body := "#!/bin/sh\necho run >> " + calls + "\necho 'ffmpeg version 6.1.1-test Copyright (c) 2000-2023'\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg: %v", err)
+	}
+
+	original := lookPath
+	lookPath = func(file string) (string, error) { return script, nil }
+	t.Cleanup(func() { lookPath = original })
+	return calls
+}
+
+func TestFFmpegChecker_ReportsVersionAndCaches(t *testing.T) {
+	calls := withFakeFFmpeg(t)
+
+	checker := newFFmpegChecker(time.Second, time.Minute)
+	for i := 0; i < 2; i++ {
+		status := checker.Status()
+		if !status.Available || status.Version != "6.1.1-test" {
+			t.Fatalf("expected ffmpeg 6.1.1-test available, got %+v", status)
+		}
+	}
+
+	runs, _ := os.ReadFile(calls)
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Errorf("expected one ffmpeg run thanks to caching, got %d", n)
+	}
+}
+
+func TestFFmpegChecker_Missing(t *testing.T) {
+	withoutFFmpeg(t)
+
+	status := newFFmpegChecker(time.Second, time.Minute).Status()
+	if status.Available {
+		t.Fatal("expected ffmpeg to be reported missing")
+	}
+	if !strings.Contains(status.Detail, "ffmpeg not found") {
+		t.Errorf("expected detail to mention missing ffmpeg, got %q", status.Detail)
+	}
+}
+
+func TestForwardVoice_FFmpegMissing(t *testing.T) {
+	withoutFFmpeg(t)
+
+	var calls int32
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer orchestrator.Close()
+
+	proxy := NewOrchestratorProxy(orchestrator.URL, 5)
+
+	if _, err := proxy.ForwardVoice([]byte("webm data"), "audio/webm", nil); !errors.Is(err, ErrFFmpegMissing) {
+		t.Errorf("ForwardVoice: expected ErrFFmpegMissing, got %v", err)
+	}
+	if _, err := proxy.ForwardVoiceStream(bytes.NewReader([]byte("webm data")), "audio/webm", nil); !errors.Is(err, ErrFFmpegMissing) {
+		t.Errorf("ForwardVoiceStream: expected ErrFFmpegMissing, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no orchestrator calls, got %d", calls)
+	}
+}
+
+func TestForwardVoice_WAVSkipsFFmpeg(t *testing.T) {
+	withoutFFmpeg(t)
+
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(VoiceResponse{Status: "no_speech"})
+	}))
+	defer orchestrator.Close()
+
+	proxy := NewOrchestratorProxy(orchestrator.URL, 5)
+
+	if _, err := proxy.ForwardVoice([]byte("wav data"), "audio/wav", nil); err != nil {
+		t.Errorf("expected WAV uploads to work without ffmpeg, got %v", err)
+	}
+}
+
+func TestForwardChat_CancelledContextAborts(t *testing.T) {
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a disconnect once the body has been read
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	proxy := NewOrchestratorProxy(server.URL, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := proxy.ForwardChat(ctx, ChatRequest{UserID: "dad", Message: "hello"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the forward to stop on cancel, took %v", elapsed)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("expected the orchestrator request to be cancelled")
+	}
+}
+
+func TestAudioFormat_FFmpegArgs(t *testing.T) {
+	args := audioFormat{sampleRate: 48000, channels: 2}.ffmpegArgs("in.webm", "out.wav")
+
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"-i in.webm", "-ar 48000", "-ac 2", "-f wav"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in ffmpeg args, got %q", want, joined)
+		}
+	}
+	if args[len(args)-1] != "out.wav" {
+		t.Errorf("expected output last, got %q", args[len(args)-1])
+	}
+}
+
+func TestAudioFormat_FFmpegArgsLoudnorm(t *testing.T) {
+	joined := strings.Join(defaultAudioFormat.ffmpegArgs("in.webm", "out.wav"), " ")
+	if strings.Contains(joined, "loudnorm") {
+		t.Errorf("expected no loudnorm filter by default, got %q", joined)
+	}
+
+	proxy := NewOrchestratorProxy("http://127.0.0.1:1", 5)
+	proxy.SetLoudnessNormalization(true)
+	proxy.SetAudioFormat(44100, 2)
+
+	joined = strings.Join(proxy.audio.ffmpegArgs("in.webm", "out.wav"), " ")
+	for _, want := range []string{"-i in.webm -af loudnorm", "-ar 44100", "-ac 2"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in ffmpeg args, got %q", want, joined)
+		}
+	}
+}
+
+func TestNewOrchestratorProxy_DefaultAudioFormat(t *testing.T) {
+	proxy := NewOrchestratorProxy("http://127.0.0.1:1", 5)
+	if proxy.audio != (audioFormat{sampleRate: 16000, channels: 1}) {
+		t.Errorf("expected 16kHz mono by default, got %+v", proxy.audio)
+	}
+
+	proxy.SetAudioFormat(44100, 2)
+	if proxy.audio != (audioFormat{sampleRate: 44100, channels: 2}) {
+		t.Errorf("expected 44.1kHz stereo after SetAudioFormat, got %+v", proxy.audio)
+	}
+}
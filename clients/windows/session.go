@@ -9,36 +9,92 @@ import (
 
 // Message represents a single conversation message
 type Message struct {
-	Role      string    `json:"role"`      // "user" or "assistant"
-	Content   string    `json:"content"`   // The message content
-	UserID    string    `json:"user_id"`   // Identified user (dad, mom, etc.)
+	Role      string    `json:"role"`                 // "user" or "assistant"
+	Content   string    `json:"content"`              // The message content
+	UserID    string    `json:"user_id"`              // Identified user (dad, mom, etc.)
 	ModelUsed string    `json:"model_used,omitempty"` // Model used for response
-	Timestamp time.Time `json:"timestamp"` // When the message was created
+	Timestamp time.Time `json:"timestamp"`            // When the message was created
+
+	pending uint64 // Non-zero while the message awaits its reply
 }
 
 // Session represents a user session with conversation history
 type Session struct {
-	ID      string
-	History []Message
-	Created time.Time
-	LastAccess time.Time
+	ID              string    `json:"id"`
+	History         []Message `json:"history"`
+	Created         time.Time `json:"created"`
+	LastAccess      time.Time `json:"last_access"`
+	VoicePreference string    `json:"voice_preference,omitempty"` // TTS voice picked in the browser, empty uses the configured list
+}
+
+// Clock tells the session manager the time, so tests can move it forward
+type Clock interface {
+	Now() time.Time
 }
 
+// realClock is the wall clock
+type realClock struct{}
+
+// Now returns the current time
+func (realClock) Now() time.Time { return time.Now() }
+
 // SessionManager manages user sessions and conversation history
 type SessionManager struct {
 	sessions   map[string]*Session
+	clock      Clock
 	mu         sync.RWMutex
 	maxHistory int
+	greeting   string        // Assistant message seeded into new sessions, empty disables
+	pinned     int           // Leading messages of each session never trimmed
+	idleAfter  time.Duration // Idle time before a session starts over, 0 disables
+	idleMode   string        // idleResetClear or idleResetDivider
+	idleText   string        // Divider message for idleResetDivider
+	nextID     uint64        // Last handle given out for a pending message
 }
 
 // NewSessionManager creates a new session manager
 func NewSessionManager(maxHistory int) *SessionManager {
 	return &SessionManager{
 		sessions:   make(map[string]*Session),
+		clock:      realClock{},
 		maxHistory: maxHistory,
 	}
 }
 
+// SetClock replaces the clock used for timestamps and session ages
+func (sm *SessionManager) SetClock(clock Clock) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.clock = clock
+}
+
+// SetGreeting sets the assistant message seeded into every new session
+func (sm *SessionManager) SetGreeting(text string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.greeting = text
+}
+
+// SetPinnedPrefix keeps the first n messages of every session (e.g. the
+// greeting) when history is trimmed; older unpinned messages go first instead
+func (sm *SessionManager) SetPinnedPrefix(n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.pinned = n
+}
+
+// SetIdleReset makes sessions left idle for longer than after start over when
+// next retrieved or touched: mode "clear" drops their history (re-seeding the greeting)
+// and "divider" keeps it, appending text as an assistant message. An after of
+// 0 disables the reset.
+func (sm *SessionManager) SetIdleReset(after time.Duration, mode, text string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.idleAfter = after
+	sm.idleMode = mode
+	sm.idleText = text
+}
+
 // GetOrCreateSession retrieves an existing session or creates a new one
 func (sm *SessionManager) GetOrCreateSession(sessionID string) *Session {
 	sm.mu.Lock()
@@ -48,22 +104,91 @@ func (sm *SessionManager) GetOrCreateSession(sessionID string) *Session {
 		sessionID = generateSessionID()
 	}
 
+	now := sm.clock.Now()
 	session, exists := sm.sessions[sessionID]
 	if !exists {
 		session = &Session{
 			ID:         sessionID,
 			History:    make([]Message, 0),
-			Created:    time.Now(),
-			LastAccess: time.Now(),
+			Created:    now,
+			LastAccess: now,
 		}
+		sm.greet(session, now)
 		sm.sessions[sessionID] = session
 	} else {
-		session.LastAccess = time.Now()
+		sm.touch(session, now)
 	}
 
 	return session
 }
 
+// Touch marks a session as used now, first starting it over if it sat idle
+// past the idle reset threshold. It reports whether the session exists.
+func (sm *SessionManager) Touch(sessionID string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if exists {
+		sm.touch(session, sm.clock.Now())
+	}
+	return exists
+}
+
+// touch applies the idle reset to a session resumed at now and records the access
+func (sm *SessionManager) touch(session *Session, now time.Time) {
+	if sm.idleAfter > 0 && now.Sub(session.LastAccess) > sm.idleAfter {
+		sm.resetIdle(session, now)
+	}
+	session.LastAccess = now
+}
+
+// greet seeds the greeting into an empty session. The greeting is an
+// ordinary history entry and ages out like any other, unless covered by the
+// pinned prefix.
+func (sm *SessionManager) greet(session *Session, now time.Time) {
+	if sm.greeting != "" {
+		session.History = append(session.History, Message{
+			Role:      "assistant",
+			Content:   sm.greeting,
+			Timestamp: now,
+		})
+	}
+}
+
+// resetIdle starts an idle session over according to the idle reset mode.
+// Messages still awaiting their reply are kept so the reply has a place to land.
+func (sm *SessionManager) resetIdle(session *Session, now time.Time) {
+	if sm.idleMode == idleResetDivider {
+		session.History = append(session.History, Message{
+			Role:      "assistant",
+			Content:   sm.idleText,
+			Timestamp: now,
+		})
+		sm.trim(session)
+		return
+	}
+
+	var pending []Message
+	for _, msg := range session.History {
+		if msg.pending != 0 {
+			pending = append(pending, msg)
+		}
+	}
+	session.History = make([]Message, 0, len(pending)+1)
+	sm.greet(session, now)
+	session.History = append(session.History, pending...)
+}
+
+// HasSession reports whether a session with this ID is live
+func (sm *SessionManager) HasSession(sessionID string) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	_, exists := sm.sessions[sessionID]
+	return exists
+}
+
 // AddMessage adds a message to the session history
 func (sm *SessionManager) AddMessage(sessionID string, msg Message) {
 	sm.mu.Lock()
@@ -74,15 +199,108 @@ func (sm *SessionManager) AddMessage(sessionID string, msg Message) {
 		return
 	}
 
-	msg.Timestamp = time.Now()
+	msg.Timestamp = sm.clock.Now()
 	session.History = append(session.History, msg)
+	sm.trim(session)
+	session.LastAccess = sm.clock.Now()
+}
+
+// AddPendingMessage adds a message whose reply is still on its way, so it is
+// visible in the history meanwhile. The returned handle is passed to
+// AnswerMessage once the reply arrives, or RemoveMessage if it never does.
+// It is 0 if the session does not exist.
+func (sm *SessionManager) AddPendingMessage(sessionID string, msg Message) uint64 {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return 0
+	}
+
+	sm.nextID++
+	msg.pending = sm.nextID
+	msg.Timestamp = sm.clock.Now()
+	session.History = append(session.History, msg)
+	sm.trim(session)
+	session.LastAccess = sm.clock.Now()
+	return msg.pending
+}
+
+// AnswerMessage inserts reply right after the pending message, keeping each
+// question next to its answer when several are in flight
+func (sm *SessionManager) AnswerMessage(sessionID string, pending uint64, reply Message) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return
+	}
+
+	reply.Timestamp = sm.clock.Now()
+	at := len(session.History)
+	if i := findPending(session.History, pending); i >= 0 {
+		session.History[i].pending = 0
+		at = i + 1
+	}
+	session.History = append(session.History, Message{})
+	copy(session.History[at+1:], session.History[at:])
+	session.History[at] = reply
+
+	sm.trim(session)
+	session.LastAccess = sm.clock.Now()
+}
 
-	// Maintain max history size (FIFO)
+// RemoveMessage drops a pending message whose reply failed
+func (sm *SessionManager) RemoveMessage(sessionID string, pending uint64) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return
+	}
+	if i := findPending(session.History, pending); i >= 0 {
+		session.History = append(session.History[:i], session.History[i+1:]...)
+	}
+}
+
+// findPending returns the index of the pending message with the given handle, or -1
+func findPending(history []Message, pending uint64) int {
+	if pending == 0 {
+		return -1
+	}
+	for i, msg := range history {
+		if msg.pending == pending {
+			return i
+		}
+	}
+	return -1
+}
+
+// trim enforces the max history size. The caller must hold sm.mu.
+func (sm *SessionManager) trim(session *Session) {
+	// Maintain max history size (FIFO after the pinned prefix)
 	if len(session.History) > sm.maxHistory {
-		session.History = session.History[len(session.History)-sm.maxHistory:]
+		session.History = trimHistory(session.History, sm.maxHistory, sm.pinned)
 	}
+}
 
-	session.LastAccess = time.Now()
+// trimHistory drops the oldest messages after the first pinned ones until
+// history fits in max. The newest message is always kept, so at most max-1
+// messages are pinned.
+func trimHistory(history []Message, max, pinned int) []Message {
+	if pinned > max-1 {
+		pinned = max - 1
+	}
+	if pinned <= 0 {
+		return history[len(history)-max:]
+	}
+
+	trimmed := make([]Message, 0, max)
+	trimmed = append(trimmed, history[:pinned]...)
+	return append(trimmed, history[len(history)-(max-pinned):]...)
 }
 
 // GetHistory returns the conversation history for a session
@@ -101,6 +319,66 @@ func (sm *SessionManager) GetHistory(sessionID string) []Message {
 	return history
 }
 
+// SetVoicePreference records the TTS voice chosen for a session
+func (sm *SessionManager) SetVoicePreference(sessionID, voice string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if session, exists := sm.sessions[sessionID]; exists {
+		session.VoicePreference = voice
+	}
+}
+
+// GetVoicePreference returns the TTS voice chosen for a session, if any
+func (sm *SessionManager) GetVoicePreference(sessionID string) string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if session, exists := sm.sessions[sessionID]; exists {
+		return session.VoicePreference
+	}
+	return ""
+}
+
+// GetHistoryForUser returns only the turns of a session that belong to userID,
+// so family members sharing a browser don't see each other's context
+func (sm *SessionManager) GetHistoryForUser(sessionID, userID string) []Message {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return []Message{}
+	}
+
+	history := make([]Message, 0, len(session.History))
+	for _, msg := range session.History {
+		if msg.UserID == userID {
+			history = append(history, msg)
+		}
+	}
+	return history
+}
+
+// LastSpeaker returns the user behind the most recent user turn of a session
+// that has one, or "" when none does
+func (sm *SessionManager) LastSpeaker(sessionID string) string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return ""
+	}
+
+	for i := len(session.History) - 1; i >= 0; i-- {
+		if msg := session.History[i]; msg.Role == "user" && msg.UserID != "" {
+			return msg.UserID
+		}
+	}
+	return ""
+}
+
 // ClearHistory clears the conversation history for a session
 func (sm *SessionManager) ClearHistory(sessionID string) {
 	sm.mu.Lock()
@@ -109,7 +387,7 @@ func (sm *SessionManager) ClearHistory(sessionID string) {
 	session, exists := sm.sessions[sessionID]
 	if exists {
 		session.History = make([]Message, 0)
-		session.LastAccess = time.Now()
+		session.LastAccess = sm.clock.Now()
 	}
 }
 
@@ -118,7 +396,7 @@ func (sm *SessionManager) CleanupOldSessions(maxAge time.Duration) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	now := time.Now()
+	now := sm.clock.Now()
 	for id, session := range sm.sessions {
 		if now.Sub(session.LastAccess) > maxAge {
 			delete(sm.sessions, id)
@@ -126,6 +404,44 @@ func (sm *SessionManager) CleanupOldSessions(maxAge time.Duration) {
 	}
 }
 
+// snapshot returns a deep copy of every session. Messages still awaiting
+// their reply are left out.
+func (sm *SessionManager) snapshot() []*Session {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, session := range sm.sessions {
+		copied := *session
+		copied.History = make([]Message, 0, len(session.History))
+		for _, msg := range session.History {
+			if msg.pending == 0 {
+				copied.History = append(copied.History, msg)
+			}
+		}
+		sessions = append(sessions, &copied)
+	}
+	return sessions
+}
+
+// restore adds sessions, replacing any with the same ID, trimmed to the
+// current max history
+func (sm *SessionManager) restore(sessions []*Session) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for _, session := range sessions {
+		if session.ID == "" {
+			continue
+		}
+		if session.History == nil {
+			session.History = make([]Message, 0)
+		}
+		sm.trim(session)
+		sm.sessions[session.ID] = session
+	}
+}
+
 // generateSessionID creates a random session ID
 func generateSessionID() string {
 	bytes := make([]byte, 16)
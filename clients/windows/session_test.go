@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSessionManager_NewSessionGetsGreeting(t *testing.T) {
+	sm := NewSessionManager(4)
+	sm.SetGreeting("Bonjour !")
+
+	session := sm.GetOrCreateSession("")
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(history))
+	}
+	if history[0].Role != "assistant" || history[0].Content != "Bonjour !" {
+		t.Errorf("expected assistant greeting, got %+v", history[0])
+	}
+}
+
+func TestSessionManager_ExistingSessionNotGreetedAgain(t *testing.T) {
+	sm := NewSessionManager(4)
+	sm.SetGreeting("Bonjour !")
+
+	session := sm.GetOrCreateSession("")
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "hi", UserID: "dad"})
+
+	sm.GetOrCreateSession(session.ID)
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(history))
+	}
+	if history[1].Content != "hi" {
+		t.Errorf("expected user message last, got %+v", history[1])
+	}
+}
+
+func TestSessionManager_NoGreetingWhenDisabled(t *testing.T) {
+	sm := NewSessionManager(4)
+
+	session := sm.GetOrCreateSession("")
+
+	if history := sm.GetHistory(session.ID); len(history) != 0 {
+		t.Errorf("expected empty history, got %d messages", len(history))
+	}
+}
+
+func TestSessionManager_GreetingAgesOutWithinMaxHistory(t *testing.T) {
+	sm := NewSessionManager(4)
+	sm.SetGreeting("Bonjour !")
+
+	session := sm.GetOrCreateSession("")
+	for i := 0; i < 4; i++ {
+		sm.AddMessage(session.ID, Message{Role: "user", Content: fmt.Sprintf("msg %d", i)})
+	}
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 4 {
+		t.Fatalf("expected history capped at 4, got %d", len(history))
+	}
+	if history[0].Content != "msg 0" {
+		t.Errorf("expected greeting to be evicted first, got %+v", history[0])
+	}
+}
+
+func TestSessionManager_PinnedPrefixSurvivesTrimming(t *testing.T) {
+	sm := NewSessionManager(4)
+	sm.SetGreeting("Bonjour !")
+	sm.SetPinnedPrefix(1)
+
+	session := sm.GetOrCreateSession("")
+	for i := 0; i < 6; i++ {
+		sm.AddMessage(session.ID, Message{Role: "user", Content: fmt.Sprintf("msg %d", i)})
+	}
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 4 {
+		t.Fatalf("expected history capped at 4, got %d", len(history))
+	}
+	want := []string{"Bonjour !", "msg 3", "msg 4", "msg 5"}
+	for i, content := range want {
+		if history[i].Content != content {
+			t.Errorf("history[%d]: expected %q, got %q", i, content, history[i].Content)
+		}
+	}
+}
+
+func TestSessionManager_PinnedPrefixKeepsNewestMessage(t *testing.T) {
+	sm := NewSessionManager(3)
+	sm.SetPinnedPrefix(5)
+
+	session := sm.GetOrCreateSession("")
+	for i := 0; i < 5; i++ {
+		sm.AddMessage(session.ID, Message{Role: "user", Content: fmt.Sprintf("msg %d", i)})
+	}
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 3 {
+		t.Fatalf("expected history capped at 3, got %d", len(history))
+	}
+	if history[0].Content != "msg 0" || history[1].Content != "msg 1" {
+		t.Errorf("expected the first messages to stay pinned, got %q, %q", history[0].Content, history[1].Content)
+	}
+	if history[2].Content != "msg 4" {
+		t.Errorf("expected the newest message to be kept, got %q", history[2].Content)
+	}
+}
+
+func TestSessionManager_GetHistoryForUser(t *testing.T) {
+	sm := NewSessionManager(20)
+	session := sm.GetOrCreateSession("")
+
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "mom question", UserID: "mom"})
+	sm.AddMessage(session.ID, Message{Role: "assistant", Content: "mom answer", UserID: "mom"})
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "teen question", UserID: "teen"})
+	sm.AddMessage(session.ID, Message{Role: "assistant", Content: "teen answer", UserID: "teen"})
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "mom follow-up", UserID: "mom"})
+
+	mom := sm.GetHistoryForUser(session.ID, "mom")
+	if len(mom) != 3 {
+		t.Fatalf("expected 3 messages for mom, got %d", len(mom))
+	}
+	for _, msg := range mom {
+		if msg.UserID != "mom" {
+			t.Errorf("expected only mom's turns, got %+v", msg)
+		}
+	}
+	if mom[2].Content != "mom follow-up" {
+		t.Errorf("expected chronological order, got %+v", mom)
+	}
+
+	teen := sm.GetHistoryForUser(session.ID, "teen")
+	if len(teen) != 2 {
+		t.Errorf("expected 2 messages for teen, got %d", len(teen))
+	}
+
+	if all := sm.GetHistory(session.ID); len(all) != 5 {
+		t.Errorf("expected full history to keep 5 messages, got %d", len(all))
+	}
+}
+
+func TestSessionManager_PendingMessagesKeepOrder(t *testing.T) {
+	sm := NewSessionManager(20)
+	session := sm.GetOrCreateSession("")
+
+	// Two questions in flight at once, answered in reverse order
+	first := sm.AddPendingMessage(session.ID, Message{Role: "user", Content: "first question"})
+	second := sm.AddPendingMessage(session.ID, Message{Role: "user", Content: "second question"})
+	sm.AnswerMessage(session.ID, second, Message{Role: "assistant", Content: "second answer"})
+	sm.AnswerMessage(session.ID, first, Message{Role: "assistant", Content: "first answer"})
+
+	history := sm.GetHistory(session.ID)
+	want := []string{"first question", "first answer", "second question", "second answer"}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d messages, got %d", len(want), len(history))
+	}
+	for i, content := range want {
+		if history[i].Content != content {
+			t.Errorf("history[%d]: expected %q, got %q", i, content, history[i].Content)
+		}
+	}
+}
+
+func TestSessionManager_RemovePendingMessage(t *testing.T) {
+	sm := NewSessionManager(20)
+	session := sm.GetOrCreateSession("")
+
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "earlier"})
+	pending := sm.AddPendingMessage(session.ID, Message{Role: "user", Content: "lost question"})
+	if n := len(sm.GetHistory(session.ID)); n != 2 {
+		t.Fatalf("expected the pending message to be visible, got %d messages", n)
+	}
+
+	sm.RemoveMessage(session.ID, pending)
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 1 || history[0].Content != "earlier" {
+		t.Errorf("expected only the earlier message to remain, got %+v", history)
+	}
+}
+
+func TestSessionManager_VoicePreference(t *testing.T) {
+	sm := NewSessionManager(20)
+	session := sm.GetOrCreateSession("")
+
+	if voice := sm.GetVoicePreference(session.ID); voice != "" {
+		t.Errorf("expected no voice preference on a new session, got %q", voice)
+	}
+
+	sm.SetVoicePreference(session.ID, "Hortense")
+	if voice := sm.GetVoicePreference(session.ID); voice != "Hortense" {
+		t.Errorf("expected Hortense, got %q", voice)
+	}
+
+	sm.SetVoicePreference("missing", "Hortense")
+	if voice := sm.GetVoicePreference("missing"); voice != "" {
+		t.Errorf("expected no preference for a missing session, got %q", voice)
+	}
+}
+
+// fakeClock is a Clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newFakeClockManager(maxHistory int) (*SessionManager, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	sm := NewSessionManager(maxHistory)
+	sm.SetClock(clock)
+	return sm, clock
+}
+
+func TestSessionManager_CleanupOldSessionsWithClock(t *testing.T) {
+	sm, clock := newFakeClockManager(20)
+	stale := sm.GetOrCreateSession("")
+	active := sm.GetOrCreateSession("")
+
+	clock.Advance(23 * time.Hour)
+	sm.AddMessage(active.ID, Message{Role: "user", Content: "still here"})
+	clock.Advance(2 * time.Hour)
+
+	sm.CleanupOldSessions(24 * time.Hour)
+
+	if sm.HasSession(stale.ID) {
+		t.Error("expected the idle session to be cleaned up")
+	}
+	if !sm.HasSession(active.ID) {
+		t.Error("expected the recently used session to survive")
+	}
+}
+
+func TestSessionManager_CleanupKeepsSessionAtMaxAge(t *testing.T) {
+	sm, clock := newFakeClockManager(20)
+	session := sm.GetOrCreateSession("")
+
+	clock.Advance(24 * time.Hour)
+	sm.CleanupOldSessions(24 * time.Hour)
+	if !sm.HasSession(session.ID) {
+		t.Fatal("expected a session exactly at max age to survive")
+	}
+
+	clock.Advance(time.Second)
+	sm.CleanupOldSessions(24 * time.Hour)
+	if sm.HasSession(session.ID) {
+		t.Error("expected the session to be cleaned up past max age")
+	}
+}
+
+func TestSessionManager_TimestampsFromClock(t *testing.T) {
+	sm, clock := newFakeClockManager(2)
+	session := sm.GetOrCreateSession("")
+	created := clock.Now()
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Minute)
+		sm.AddMessage(session.ID, Message{Role: "user", Content: fmt.Sprintf("message %d", i)})
+	}
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 2 {
+		t.Fatalf("expected history trimmed to 2, got %d", len(history))
+	}
+	if want := created.Add(2 * time.Minute); !history[0].Timestamp.Equal(want) {
+		t.Errorf("expected the oldest kept message at %v, got %v", want, history[0].Timestamp)
+	}
+	if want := created.Add(3 * time.Minute); !history[1].Timestamp.Equal(want) {
+		t.Errorf("expected the newest message at %v, got %v", want, history[1].Timestamp)
+	}
+}
+
+func TestSessionManager_IdleResetClearsHistory(t *testing.T) {
+	sm, clock := newFakeClockManager(20)
+	sm.SetGreeting("Bonjour !")
+	sm.SetIdleReset(30*time.Minute, idleResetClear, "")
+	session := sm.GetOrCreateSession("")
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "old question"})
+	sm.AddMessage(session.ID, Message{Role: "assistant", Content: "old answer"})
+
+	clock.Advance(31 * time.Minute)
+	sm.GetOrCreateSession(session.ID)
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 1 || history[0].Content != "Bonjour !" {
+		t.Fatalf("expected only a fresh greeting after the idle reset, got %+v", history)
+	}
+	if !history[0].Timestamp.Equal(clock.Now()) {
+		t.Errorf("expected the greeting stamped at the reset, got %v", history[0].Timestamp)
+	}
+}
+
+func TestSessionManager_IdleResetKeepsPendingMessages(t *testing.T) {
+	sm, clock := newFakeClockManager(20)
+	sm.SetIdleReset(30*time.Minute, idleResetClear, "")
+	session := sm.GetOrCreateSession("")
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "old question"})
+	handle := sm.AddPendingMessage(session.ID, Message{Role: "user", Content: "slow question"})
+
+	clock.Advance(time.Hour)
+	sm.GetOrCreateSession(session.ID)
+	sm.AnswerMessage(session.ID, handle, Message{Role: "assistant", Content: "slow answer"})
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 2 || history[0].Content != "slow question" || history[1].Content != "slow answer" {
+		t.Errorf("expected only the pending exchange to survive the reset, got %+v", history)
+	}
+}
+
+func TestSessionManager_IdleResetDivider(t *testing.T) {
+	sm, clock := newFakeClockManager(20)
+	sm.SetIdleReset(30*time.Minute, idleResetDivider, "Rebonjour !")
+	session := sm.GetOrCreateSession("")
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "old question"})
+
+	clock.Advance(time.Hour)
+	sm.GetOrCreateSession(session.ID)
+
+	history := sm.GetHistory(session.ID)
+	if len(history) != 2 || history[0].Content != "old question" {
+		t.Fatalf("expected the old history kept, got %+v", history)
+	}
+	if divider := history[1]; divider.Role != "assistant" || divider.Content != "Rebonjour !" {
+		t.Errorf("expected a welcome back divider, got %+v", divider)
+	}
+}
+
+func TestSessionManager_ActiveSessionContinues(t *testing.T) {
+	sm, clock := newFakeClockManager(20)
+	sm.SetIdleReset(30*time.Minute, idleResetClear, "")
+	session := sm.GetOrCreateSession("")
+
+	// Each visit within the threshold restarts the idle timer
+	for i := 0; i < 3; i++ {
+		clock.Advance(20 * time.Minute)
+		sm.GetOrCreateSession(session.ID)
+		sm.AddMessage(session.ID, Message{Role: "user", Content: fmt.Sprintf("message %d", i)})
+	}
+	clock.Advance(30 * time.Minute)
+	sm.GetOrCreateSession(session.ID)
+
+	if history := sm.GetHistory(session.ID); len(history) != 3 {
+		t.Errorf("expected an active session to keep its 3 messages, got %+v", history)
+	}
+}
+
+func TestSessionManager_IdleResetDisabled(t *testing.T) {
+	sm, clock := newFakeClockManager(20)
+	session := sm.GetOrCreateSession("")
+	sm.AddMessage(session.ID, Message{Role: "user", Content: "old question"})
+
+	clock.Advance(72 * time.Hour)
+	sm.GetOrCreateSession(session.ID)
+
+	if history := sm.GetHistory(session.ID); len(history) != 1 {
+		t.Errorf("expected history kept with the idle reset disabled, got %+v", history)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sessionFileVersion is the format of the session snapshot file
+const sessionFileVersion = 1
+
+// sessionFile is the JSON snapshot written to disk
+type sessionFile struct {
+	Version  int        `json:"version"`
+	SavedAt  time.Time  `json:"saved_at"`
+	Sessions []*Session `json:"sessions"`
+}
+
+// FileSessionManager is a SessionManager whose sessions survive restarts:
+// they are loaded from a JSON snapshot on startup and written back
+// periodically and on shutdown
+type FileSessionManager struct {
+	*SessionManager
+	path string
+}
+
+// NewFileSessionManager creates a session manager persisted to path
+func NewFileSessionManager(maxHistory int, path string) *FileSessionManager {
+	return &FileSessionManager{
+		SessionManager: NewSessionManager(maxHistory),
+		path:           path,
+	}
+}
+
+// Load restores the sessions saved in the snapshot. A missing file is not an
+// error; it only means nothing was saved yet.
+func (fm *FileSessionManager) Load() error {
+	data, err := os.ReadFile(fm.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	var snapshot sessionFile
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse session file: %w", err)
+	}
+	if snapshot.Version != sessionFileVersion {
+		return fmt.Errorf("unsupported session file version %d", snapshot.Version)
+	}
+
+	fm.restore(snapshot.Sessions)
+	return nil
+}
+
+// Save writes every session to the snapshot. The file is replaced
+// atomically, so a crash mid-write leaves the previous snapshot intact.
+func (fm *FileSessionManager) Save() error {
+	data, err := json.Marshal(sessionFile{
+		Version:  sessionFileVersion,
+		SavedAt:  fm.clock.Now(),
+		Sessions: fm.snapshot(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode sessions: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fm.path), filepath.Base(fm.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fm.path); err != nil {
+		return fmt.Errorf("failed to replace session file: %w", err)
+	}
+	return nil
+}
+
+// StartSnapshots saves the sessions every interval until stop is closed
+func (fm *FileSessionManager) StartSnapshots(interval time.Duration, logger *slog.Logger, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := fm.Save(); err != nil {
+					logger.Error("failed to save sessions", "path", fm.path, "error", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
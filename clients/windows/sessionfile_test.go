@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSessionManager_SaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+
+	saved := NewFileSessionManager(20, path)
+	session := saved.GetOrCreateSession("")
+	saved.AddMessage(session.ID, Message{Role: "user", Content: "hello", UserID: "dad"})
+	saved.AddMessage(session.ID, Message{Role: "assistant", Content: "hi dad", UserID: "dad", ModelUsed: "llama3.1:8b"})
+	saved.SetVoicePreference(session.ID, "Microsoft Aria")
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded := NewFileSessionManager(20, path)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if !loaded.HasSession(session.ID) {
+		t.Fatal("expected the saved session to be restored")
+	}
+	history := loaded.GetHistory(session.ID)
+	if len(history) != 2 || history[0].Content != "hello" || history[1].ModelUsed != "llama3.1:8b" {
+		t.Errorf("expected the history restored, got %+v", history)
+	}
+	if got := loaded.GetVoicePreference(session.ID); got != "Microsoft Aria" {
+		t.Errorf("expected the voice preference restored, got %q", got)
+	}
+
+	// The restored session keeps working
+	loaded.AddMessage(session.ID, Message{Role: "user", Content: "again", UserID: "dad"})
+	if n := len(loaded.GetHistory(session.ID)); n != 3 {
+		t.Errorf("expected 3 messages after adding one, got %d", n)
+	}
+}
+
+func TestFileSessionManager_LoadRespectsMaxHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+
+	saved := NewFileSessionManager(10, path)
+	session := saved.GetOrCreateSession("")
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		saved.AddMessage(session.ID, Message{Role: "user", Content: content})
+	}
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Restarted with a smaller history limit
+	loaded := NewFileSessionManager(3, path)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	history := loaded.GetHistory(session.ID)
+	if len(history) != 3 || history[0].Content != "3" || history[2].Content != "5" {
+		t.Errorf("expected the 3 newest messages, got %+v", history)
+	}
+}
+
+func TestFileSessionManager_SkipsPendingMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+
+	saved := NewFileSessionManager(20, path)
+	session := saved.GetOrCreateSession("")
+	saved.AddMessage(session.ID, Message{Role: "user", Content: "answered"})
+	saved.AddPendingMessage(session.ID, Message{Role: "user", Content: "in flight"})
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded := NewFileSessionManager(20, path)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	history := loaded.GetHistory(session.ID)
+	if len(history) != 1 || history[0].Content != "answered" {
+		t.Errorf("expected only the answered message, got %+v", history)
+	}
+}
+
+func TestFileSessionManager_MissingFileLoadsNothing(t *testing.T) {
+	fm := NewFileSessionManager(20, filepath.Join(t.TempDir(), "missing.json"))
+	if err := fm.Load(); err != nil {
+		t.Errorf("expected a missing snapshot to be fine, got %v", err)
+	}
+}
+
+func TestFileSessionManager_CorruptFileFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	os.WriteFile(path, []byte("{not json"), 0o644)
+
+	fm := NewFileSessionManager(20, path)
+	if err := fm.Load(); err == nil {
+		t.Error("expected an error for a corrupt snapshot")
+	}
+}
+
+func TestFileSessionManager_SaveLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	fm := NewFileSessionManager(20, filepath.Join(dir, "sessions.json"))
+	fm.GetOrCreateSession("")
+
+	for i := 0; i < 2; i++ {
+		if err := fm.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "sessions.json" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("expected only sessions.json, got %v", names)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+//go:embed static
+var staticFS embed.FS
+
+// staticFile is an embedded asset with its precomputed validator
+type staticFile struct {
+	content     []byte
+	contentType string
+	etag        string // Quoted, e.g. "\"3f2a...\""
+	version     string // Short content hash used in versioned URLs
+}
+
+// StaticAssets serves embedded files under /static/ with ETag validation
+type StaticAssets struct {
+	files map[string]*staticFile // Keyed by path relative to the static root
+}
+
+// NewStaticAssets loads every file below root in fsys and hashes it
+func NewStaticAssets(fsys fs.FS, root string) (*StaticAssets, error) {
+	assets := &StaticAssets{files: make(map[string]*staticFile)}
+
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read static file %s: %w", name, err)
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(content)
+		}
+
+		assets.files[strings.TrimPrefix(name, root+"/")] = &staticFile{
+			content:     content,
+			contentType: contentType,
+			etag:        `"` + hash + `"`,
+			version:     hash[:12],
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+// URL returns the versioned URL of an asset for use in templates
+func (a *StaticAssets) URL(name string) string {
+	file, ok := a.files[name]
+	if !ok {
+		return "/static/" + name
+	}
+	return "/static/" + name + "?v=" + file.version
+}
+
+// ServeHTTP serves an asset. Versioned URLs are cacheable for a year since any
+// change produces a new URL; unversioned ones must be revalidated via ETag.
+func (a *StaticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+	file, ok := a.files[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.URL.Query().Get("v") == file.version {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("Content-Type", file.contentType)
+	w.Header().Set("ETag", file.etag)
+
+	// ServeContent answers If-None-Match with 304 using the ETag header
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(file.content))
+}
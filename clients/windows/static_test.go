@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStaticAssets_ServesWithETag(t *testing.T) {
+	assets, err := NewStaticAssets(staticFS, "static")
+	if err != nil {
+		t.Fatalf("failed to load static assets: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", assets.URL("css/app.css"), nil)
+	w := httptest.NewRecorder()
+
+	assets.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("expected text/css, got %s", ct)
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("expected an ETag header")
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=31536000") {
+		t.Errorf("expected long-lived Cache-Control for versioned URL, got %s", cc)
+	}
+	if w.Body.Len() == 0 {
+		t.Error("expected file content")
+	}
+}
+
+func TestStaticAssets_NotModified(t *testing.T) {
+	assets, err := NewStaticAssets(staticFS, "static")
+	if err != nil {
+		t.Fatalf("failed to load static assets: %v", err)
+	}
+
+	first := httptest.NewRecorder()
+	assets.ServeHTTP(first, httptest.NewRequest("GET", "/static/css/app.css", nil))
+	etag := first.Header().Get("ETag")
+
+	if cc := first.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected unversioned URL to require revalidation, got %s", cc)
+	}
+
+	req := httptest.NewRequest("GET", "/static/css/app.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+
+	assets.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Error("expected empty body on 304")
+	}
+}
+
+func TestStaticAssets_NotFound(t *testing.T) {
+	assets, err := NewStaticAssets(staticFS, "static")
+	if err != nil {
+		t.Fatalf("failed to load static assets: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	assets.ServeHTTP(w, httptest.NewRequest("GET", "/static/missing.js", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestIndexHandler_LinksVersionedStylesheet(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:1")
+
+	w := httptest.NewRecorder()
+	server.IndexHandler(w, httptest.NewRequest("GET", "/", nil))
+
+	if !strings.Contains(w.Body.String(), server.static.URL("css/app.css")) {
+		t.Error("expected index to link the versioned stylesheet")
+	}
+}
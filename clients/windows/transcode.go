@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// defaultConversionWait is how long a conversion waits for an ffmpeg slot
+// when the limit is set without a wait
+const defaultConversionWait = 10 * time.Second
+
+// ErrConversionBusy is returned when a conversion waited too long for a free ffmpeg slot
+var ErrConversionBusy = errors.New("too many audio conversions in progress")
+
+// conversionLimiter caps how many ffmpeg conversions run at once; further
+// conversions queue for a slot, giving up after wait. A nil limiter lets
+// every conversion through.
+type conversionLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// newConversionLimiter returns a limiter allowing max concurrent conversions,
+// or nil when max is not positive
+func newConversionLimiter(max int, wait time.Duration) *conversionLimiter {
+	if max <= 0 {
+		return nil
+	}
+	if wait <= 0 {
+		wait = defaultConversionWait
+	}
+	return &conversionLimiter{slots: make(chan struct{}, max), wait: wait}
+}
+
+// acquire waits for a slot and returns the function releasing it
+func (l *conversionLimiter) acquire() (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-timer.C:
+		return nil, ErrConversionBusy
+	}
+}
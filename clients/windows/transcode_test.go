@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConversionLimiter_ExtraConversionWaitsForSlot(t *testing.T) {
+	limiter := newConversionLimiter(2, time.Second)
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := limiter.acquire()
+		if err != nil {
+			t.Fatalf("conversion %d: expected a free slot, got %v", i+1, err)
+		}
+		releases = append(releases, release)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := limiter.acquire()
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("expected the third conversion to wait while both slots are taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	releases[0]()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Errorf("expected the third conversion to get the freed slot, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the third conversion to proceed once a slot freed")
+	}
+	releases[1]()
+}
+
+func TestConversionLimiter_GivesUpAfterWait(t *testing.T) {
+	limiter := newConversionLimiter(1, 20*time.Millisecond)
+	release, err := limiter.acquire()
+	if err != nil {
+		t.Fatalf("expected a free slot, got %v", err)
+	}
+	defer release()
+
+	if _, err := limiter.acquire(); !errors.Is(err, ErrConversionBusy) {
+		t.Errorf("expected ErrConversionBusy, got %v", err)
+	}
+}
+
+func TestConversionLimiter_NilIsUnlimited(t *testing.T) {
+	limiter := newConversionLimiter(0, 0)
+	if limiter != nil {
+		t.Fatalf("expected no limiter for max 0, got %+v", limiter)
+	}
+	for i := 0; i < 10; i++ {
+		release, err := limiter.acquire()
+		if err != nil {
+			t.Fatalf("expected unlimited conversions, got %v", err)
+		}
+		release()
+	}
+}
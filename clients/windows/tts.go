@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ellipsis marks a reply shortened for speech
+const ellipsis = "…"
+
+// truncateForSpeech shortens text to at most maxSentences sentences and
+// maxChars characters (0 disables either limit), appending an ellipsis when
+// anything was cut. It prefers to stop at the end of a sentence and falls
+// back to a word boundary when even the first sentence is too long.
+func truncateForSpeech(text string, maxSentences, maxChars int) (string, bool) {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	ends := sentenceEnds(runes)
+
+	cut := len(runes)
+	if maxSentences > 0 && len(ends) > maxSentences {
+		cut = ends[maxSentences-1]
+	}
+	if maxChars > 0 && cut > maxChars {
+		// Keep the most sentences that fit
+		fit := 0
+		for _, end := range ends {
+			if end > maxChars {
+				break
+			}
+			fit = end
+		}
+		if fit == 0 {
+			return cutAtWord(runes, maxChars) + ellipsis, true
+		}
+		cut = fit
+	}
+
+	if cut == len(runes) {
+		return text, false
+	}
+	return strings.TrimSpace(string(runes[:cut])) + " " + ellipsis, true
+}
+
+// sentenceEnds returns the rune offsets just past each sentence: after a run
+// of '.', '!', '?' or '…' followed by whitespace or the end of the text
+func sentenceEnds(runes []rune) []int {
+	var ends []int
+	for i := 0; i < len(runes); i++ {
+		if !isSentenceTerminator(runes[i]) {
+			continue
+		}
+		for i+1 < len(runes) && isSentenceTerminator(runes[i+1]) {
+			i++
+		}
+		if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+			ends = append(ends, i+1)
+		}
+	}
+	if len(ends) == 0 || ends[len(ends)-1] != len(runes) {
+		ends = append(ends, len(runes)) // An unterminated last sentence
+	}
+	return ends
+}
+
+func isSentenceTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+// cutAtWord returns at most max runes of text, ending at the last word
+// boundary when there is one
+func cutAtWord(runes []rune, max int) string {
+	cut := runes[:max]
+	if i := strings.LastIndexFunc(string(cut), unicode.IsSpace); i > 0 {
+		return strings.TrimRightFunc(string(cut)[:i], unicode.IsPunct)
+	}
+	return string(cut)
+}
+
+// shortenForSpeech applies the configured TTS limits to a reply; replies
+// are left alone when TTS is off
+func (s *Server) shortenForSpeech(text string) (string, bool) {
+	if !s.config.TTS.Enabled {
+		return text, false
+	}
+	return truncateForSpeech(text, s.config.TTS.MaxSentences, s.config.TTS.MaxChars)
+}
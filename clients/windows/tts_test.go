@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTruncateForSpeech(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		maxSentences  int
+		maxChars      int
+		want          string
+		wantTruncated bool
+	}{
+		{"no limits", "One. Two. Three.", 0, 0, "One. Two. Three.", false},
+		{"within limits", "One. Two.", 2, 100, "One. Two.", false},
+		{"sentence limit", "One. Two! Three? Four.", 2, 0, "One. Two! …", true},
+		{"char limit keeps whole sentences", "First sentence. Second sentence. Third.", 0, 35, "First sentence. Second sentence. …", true},
+		{"char limit on sentence end", "Short one. Another one.", 0, 10, "Short one. …", true},
+		{"unterminated last sentence", "One. Two and more", 1, 0, "One. …", true},
+		{"ellipses and repeated marks", "Wait... Really?! Yes.", 2, 0, "Wait... Really?! …", true},
+		{"decimal is not a boundary", "Pi is 3.14 roughly. Next.", 1, 0, "Pi is 3.14 roughly. …", true},
+		{"long first sentence cut at word", "This opening sentence, without any break, is long.", 0, 25, "This opening sentence…", true},
+		{"no word boundary", "Supercalifragilistic", 0, 5, "Super…", true},
+		{"counts characters not bytes", "Très été. Ça va.", 0, 9, "Très été. …", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncateForSpeech(tt.text, tt.maxSentences, tt.maxChars)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("truncateForSpeech(%q, %d, %d) = %q, %v; want %q, %v",
+					tt.text, tt.maxSentences, tt.maxChars, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestChatHandler_TruncatesForSpeechKeepsFullHistory(t *testing.T) {
+	full := "First point. Second point. Third point."
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ChatResponse{Response: full, UserID: "dad"})
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	server.config.TTS.Enabled = true
+	server.config.TTS.MaxSentences = 1
+
+	session := server.sessionManager.GetOrCreateSession("")
+	body, _ := json.Marshal(ChatRequest{UserID: "dad", Message: "hi"})
+	req := httptest.NewRequest("POST", "/api/chat", bytes.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+	w := httptest.NewRecorder()
+
+	server.ChatHandler(w, req)
+
+	var resp ChatResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Response != "First point. …" || !resp.Truncated {
+		t.Errorf("expected the spoken reply shortened and flagged, got %q (truncated %v)", resp.Response, resp.Truncated)
+	}
+
+	history := server.sessionManager.GetHistory(session.ID)
+	if len(history) != 2 || history[1].Content != full {
+		t.Errorf("expected the full reply in history, got %+v", history)
+	}
+}
+
+func TestChatHandler_NoTruncationWithoutTTS(t *testing.T) {
+	full := "First point. Second point."
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ChatResponse{Response: full, UserID: "dad"})
+	}))
+	defer orchestrator.Close()
+
+	server := newTestServer(t, orchestrator.URL)
+	server.config.TTS.MaxSentences = 1
+
+	body, _ := json.Marshal(ChatRequest{UserID: "dad", Message: "hi"})
+	w := httptest.NewRecorder()
+	server.ChatHandler(w, newSessionRequest(server, "POST", "/api/chat", body))
+
+	var resp ChatResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Response != full || resp.Truncated {
+		t.Errorf("expected the full reply with TTS off, got %q", resp.Response)
+	}
+}
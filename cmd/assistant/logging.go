@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// newLogHandler builds the slog handler described by the log config.
+// Unknown values fall back to JSON/info and are reported as warnings.
+func newLogHandler(cfg config.LogConfig, w io.Writer) (slog.Handler, []string) {
+	var warnings []string
+
+	level := slog.LevelInfo
+	switch strings.ToLower(cfg.Level) {
+	case "", "info":
+	case "debug":
+		level = slog.LevelDebug
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		warnings = append(warnings, fmt.Sprintf("unknown log level %q, using info", cfg.Level))
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		return slog.NewJSONHandler(w, opts), warnings
+	case "text":
+		return slog.NewTextHandler(w, opts), warnings
+	default:
+		warnings = append(warnings, fmt.Sprintf("unknown log format %q, using json", cfg.Format))
+		return slog.NewJSONHandler(w, opts), warnings
+	}
+}
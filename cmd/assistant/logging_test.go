@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+func TestNewLogHandler_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	handler, warnings := newLogHandler(config.LogConfig{Format: "json", Level: "info"}, &buf)
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+
+	slog.New(handler).Info("hello")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "hello" {
+		t.Errorf("expected msg 'hello', got %v", entry["msg"])
+	}
+}
+
+func TestNewLogHandler_Text(t *testing.T) {
+	var buf bytes.Buffer
+	handler, warnings := newLogHandler(config.LogConfig{Format: "text", Level: "debug"}, &buf)
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+
+	if !handler.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected debug level to be enabled")
+	}
+
+	slog.New(handler).Info("hello")
+
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Errorf("expected text output, got %q", buf.String())
+	}
+}
+
+func TestNewLogHandler_InvalidFallsBack(t *testing.T) {
+	var buf bytes.Buffer
+	handler, warnings := newLogHandler(config.LogConfig{Format: "xml", Level: "loud"}, &buf)
+	if len(warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", warnings)
+	}
+
+	if handler.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected debug level to be disabled")
+	}
+
+	slog.New(handler).Info("hello")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected JSON fallback, got %q: %v", buf.String(), err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
@@ -15,7 +16,10 @@ import (
 )
 
 func main() {
-	// Setup structured logging
+	selftest := flag.Bool("selftest", false, "validate the config, ping every sidecar once and exit non-zero if a required one is down")
+	flag.Parse()
+
+	// Setup structured logging (JSON until the config says otherwise)
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
@@ -28,16 +32,34 @@ func main() {
 		os.Exit(1)
 	}
 
-	logger.Info("configuration loaded", 
+	// Rebuild the logger with the configured format and level
+	handler, warnings := newLogHandler(cfg.Log, os.Stdout)
+	logger = slog.New(handler)
+	slog.SetDefault(logger)
+	for _, warning := range warnings {
+		logger.Warn("invalid log configuration", "detail", warning)
+	}
+
+	logger.Info("configuration loaded",
 		"port", cfg.Server.Port,
+		"mode", cfg.Mode,
 		"voice_url", cfg.Sidecars.VoiceURL,
-		"llm_url", cfg.Sidecars.LLMURL,
+		"llm_url", cfg.Sidecars.LLMURL.String(),
 		"learning_url", cfg.Sidecars.LearningURL,
 	)
 
 	// Create and start server
 	srv := server.New(cfg, logger)
 
+	if *selftest {
+		if !srv.SelfTest(context.Background(), os.Stdout) {
+			logger.Error("self-test failed: a required sidecar is unreachable")
+			os.Exit(1)
+		}
+		logger.Info("self-test passed")
+		return
+	}
+
 	// Channel to listen for errors from the server
 	serverErrors := make(chan error, 1)
 
@@ -69,7 +91,7 @@ func main() {
 		// Gracefully shutdown the server
 		if err := srv.Shutdown(ctx); err != nil {
 			logger.Error("graceful shutdown failed", "error", err)
-			
+
 			// Force shutdown if graceful fails
 			if err := srv.Shutdown(context.Background()); err != nil {
 				logger.Error("forced shutdown failed", "error", err)
@@ -0,0 +1,100 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidWAV is returned when the payload is not a parseable RIFF/WAVE file
+var ErrInvalidWAV = errors.New("invalid wav")
+
+// WAVInfo holds the fields of a WAV header needed for validation
+type WAVInfo struct {
+	AudioFormat   uint16 // 1 = PCM
+	Channels      uint16
+	SampleRate    uint32
+	BitsPerSample uint16
+	DataOffset    int // Offset of the first sample byte
+	DataSize      int // Number of sample bytes actually present
+}
+
+// ParseWAVHeader walks the RIFF chunks of a WAV file and returns its format info
+func ParseWAVHeader(data []byte) (*WAVInfo, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, ErrInvalidWAV
+	}
+
+	info := &WAVInfo{}
+	haveFmt := false
+	offset := 12
+
+	for offset+8 <= len(data) {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 || body+16 > len(data) {
+				return nil, ErrInvalidWAV
+			}
+			info.AudioFormat = binary.LittleEndian.Uint16(data[body : body+2])
+			info.Channels = binary.LittleEndian.Uint16(data[body+2 : body+4])
+			info.SampleRate = binary.LittleEndian.Uint32(data[body+4 : body+8])
+			info.BitsPerSample = binary.LittleEndian.Uint16(data[body+14 : body+16])
+			haveFmt = true
+
+		case "data":
+			if !haveFmt {
+				return nil, ErrInvalidWAV
+			}
+			// Streaming writers may leave the size unset, so trust the bytes we have
+			available := len(data) - body
+			if chunkSize < 0 || chunkSize > available {
+				chunkSize = available
+			}
+			info.DataOffset = body
+			info.DataSize = chunkSize
+			return info, nil
+		}
+
+		// Chunks are padded to an even number of bytes
+		next := body + chunkSize + chunkSize%2
+		if chunkSize < 0 || next <= offset {
+			return nil, ErrInvalidWAV
+		}
+		offset = next
+	}
+
+	return nil, ErrInvalidWAV
+}
+
+// Duration returns the playback length of the sample data
+func (w *WAVInfo) Duration() time.Duration {
+	bytesPerSecond := int64(w.SampleRate) * int64(w.Channels) * int64(w.BitsPerSample/8)
+	if bytesPerSecond == 0 {
+		return 0
+	}
+	return time.Duration(int64(w.DataSize) * int64(time.Second) / bytesPerSecond)
+}
+
+// PeakLevel returns the peak absolute amplitude of 16-bit PCM samples as a
+// fraction of full scale (0.0 - 1.0). ok is false for other encodings.
+func PeakLevel(data []byte, info *WAVInfo) (level float64, ok bool) {
+	if info.AudioFormat != 1 || info.BitsPerSample != 16 {
+		return 0, false
+	}
+
+	samples := data[info.DataOffset : info.DataOffset+info.DataSize]
+	var peak float64
+	for i := 0; i+1 < len(samples); i += 2 {
+		v := math.Abs(float64(int16(binary.LittleEndian.Uint16(samples[i : i+2]))))
+		if v > peak {
+			peak = v
+		}
+	}
+
+	return peak / 32768, true
+}
@@ -0,0 +1,115 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// buildWAV creates a mono 16-bit PCM WAV file with every sample set to amplitude
+func buildWAV(sampleRate uint32, numSamples int, amplitude int16) []byte {
+	dataSize := numSamples * 2
+	buf := make([]byte, 44+dataSize)
+
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(36+dataSize))
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(buf[22:24], 1) // Mono
+	binary.LittleEndian.PutUint32(buf[24:28], sampleRate)
+	binary.LittleEndian.PutUint32(buf[28:32], sampleRate*2)
+	binary.LittleEndian.PutUint16(buf[32:34], 2)
+	binary.LittleEndian.PutUint16(buf[34:36], 16)
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(dataSize))
+
+	for i := 0; i < numSamples; i++ {
+		binary.LittleEndian.PutUint16(buf[44+i*2:], uint16(amplitude))
+	}
+
+	return buf
+}
+
+func TestParseWAVHeader_Valid(t *testing.T) {
+	data := buildWAV(16000, 16000, 1000)
+
+	info, err := ParseWAVHeader(data)
+	if err != nil {
+		t.Fatalf("ParseWAVHeader failed: %v", err)
+	}
+
+	if info.SampleRate != 16000 {
+		t.Errorf("expected sample rate 16000, got %d", info.SampleRate)
+	}
+	if info.Channels != 1 {
+		t.Errorf("expected 1 channel, got %d", info.Channels)
+	}
+	if info.DataSize != 32000 {
+		t.Errorf("expected data size 32000, got %d", info.DataSize)
+	}
+	if info.Duration() != time.Second {
+		t.Errorf("expected duration 1s, got %v", info.Duration())
+	}
+}
+
+func TestParseWAVHeader_ShortRecording(t *testing.T) {
+	// 100ms at 16kHz
+	info, err := ParseWAVHeader(buildWAV(16000, 1600, 1000))
+	if err != nil {
+		t.Fatalf("ParseWAVHeader failed: %v", err)
+	}
+
+	if info.Duration() != 100*time.Millisecond {
+		t.Errorf("expected duration 100ms, got %v", info.Duration())
+	}
+}
+
+func TestParseWAVHeader_TruncatedData(t *testing.T) {
+	// Header claims one second but only half the samples arrived
+	data := buildWAV(16000, 16000, 1000)
+	data = data[:44+16000]
+
+	info, err := ParseWAVHeader(data)
+	if err != nil {
+		t.Fatalf("ParseWAVHeader failed: %v", err)
+	}
+
+	if info.Duration() != 500*time.Millisecond {
+		t.Errorf("expected duration 500ms, got %v", info.Duration())
+	}
+}
+
+func TestParseWAVHeader_Invalid(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":       {},
+		"not riff":    []byte("fake wav data that is long enough"),
+		"header only": buildWAV(16000, 0, 0)[:20],
+	}
+
+	for name, data := range tests {
+		if _, err := ParseWAVHeader(data); !errors.Is(err, ErrInvalidWAV) {
+			t.Errorf("%s: expected ErrInvalidWAV, got %v", name, err)
+		}
+	}
+}
+
+func TestPeakLevel(t *testing.T) {
+	loud := buildWAV(16000, 1600, 16384)
+	info, _ := ParseWAVHeader(loud)
+	level, ok := PeakLevel(loud, info)
+	if !ok {
+		t.Fatal("expected PCM16 to be supported")
+	}
+	if level != 0.5 {
+		t.Errorf("expected peak 0.5, got %f", level)
+	}
+
+	silent := buildWAV(16000, 1600, 0)
+	info, _ = ParseWAVHeader(silent)
+	if level, _ := PeakLevel(silent, info); level != 0 {
+		t.Errorf("expected peak 0, got %f", level)
+	}
+}
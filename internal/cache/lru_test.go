@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_GetSet(t *testing.T) {
+	c := NewLRU[string](2, time.Minute)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected miss on empty cache")
+	}
+
+	c.Set("a", "1")
+	if v, ok := c.Get("a"); !ok || v != "1" {
+		t.Errorf("expected hit with '1', got %q (%v)", v, ok)
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string](2, time.Minute)
+
+	c.Set("a", "1")
+	c.Set("b", "2")
+	c.Get("a") // "b" is now least recently used
+	c.Set("c", "3")
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected 'b' to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected 'a' to be kept")
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLRU_Clear(t *testing.T) {
+	c := NewLRU[string](4, time.Minute)
+	c.Set("a", "1")
+	c.Set("b", "2")
+
+	if n := c.Clear(); n != 2 {
+		t.Errorf("expected 2 entries cleared, got %d", n)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected 'a' to be gone")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected an empty cache, got %d entries", c.Len())
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	c := NewLRU[string](2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", "1")
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected expired entry to miss")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected expired entry to be removed, got %d entries", c.Len())
+	}
+}
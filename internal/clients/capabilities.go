@@ -0,0 +1,78 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrCapabilitiesUnsupported is returned when a sidecar has no /capabilities endpoint
+var ErrCapabilitiesUnsupported = errors.New("sidecar does not report capabilities")
+
+// Capabilities is what a sidecar reports it supports
+type Capabilities struct {
+	Models    []string        `json:"models,omitempty"`    // Models it can answer with
+	Languages []string        `json:"languages,omitempty"` // Languages it understands
+	Features  map[string]bool `json:"features,omitempty"`  // Optional features, e.g. "enrollment"
+}
+
+// Capabilities asks the Voice sidecar what it supports
+func (c *VoiceClient) Capabilities(ctx context.Context) (*Capabilities, error) {
+	return fetchCapabilities(ctx, c.client, c.baseURL)
+}
+
+// Capabilities asks the first available LLM instance what it supports
+func (c *LLMClient) Capabilities(ctx context.Context) (*Capabilities, error) {
+	return fetchCapabilities(ctx, c.client, c.hostOrder()[0])
+}
+
+// Capabilities asks the Learning sidecar what it supports
+func (c *LearningClient) Capabilities(ctx context.Context) (*Capabilities, error) {
+	return fetchCapabilities(ctx, c.client, c.baseURL)
+}
+
+// Capabilities is not limited, like Health
+func (c *LimitedLLMClient) Capabilities(ctx context.Context) (*Capabilities, error) {
+	provider, ok := c.next.(CapabilitiesProvider)
+	if !ok {
+		return nil, ErrCapabilitiesUnsupported
+	}
+	return provider.Capabilities(ctx)
+}
+
+// fetchCapabilities reads GET /capabilities from a sidecar. Sidecars that
+// predate the endpoint answer 404 or 405, reported as ErrCapabilitiesUnsupported.
+func fetchCapabilities(ctx context.Context, client *http.Client, baseURL string) (*Capabilities, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/capabilities", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, ErrCapabilitiesUnsupported
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("capabilities returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var caps Capabilities
+	if err := json.Unmarshal(body, &caps); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &caps, nil
+}
@@ -0,0 +1,41 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLLMClient_Capabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/capabilities" {
+			t.Errorf("expected /capabilities, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"models":["llama3.1:8b"],"languages":["en"],"features":{"memory":true}}`))
+	}))
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, 5*time.Second)
+	caps, err := client.Capabilities(context.Background())
+	if err != nil {
+		t.Fatalf("Capabilities failed: %v", err)
+	}
+	if len(caps.Models) != 1 || caps.Models[0] != "llama3.1:8b" || !caps.Features["memory"] {
+		t.Errorf("unexpected capabilities: %+v", caps)
+	}
+}
+
+func TestVoiceClient_Capabilities_Unsupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := NewVoiceClient(server.URL, 5*time.Second)
+	_, err := client.Capabilities(context.Background())
+	if !errors.Is(err, ErrCapabilitiesUnsupported) {
+		t.Errorf("expected ErrCapabilitiesUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// gzipJSONServer answers every route with gzip-compressed JSON, whether or
+// not the request asked for compression
+func gzipJSONServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(body))
+	zw.Close()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+}
+
+func TestClients_DecompressGzipResponses(t *testing.T) {
+	server := gzipJSONServer(t, `{"status":"identified","user_id":"mom","transcript":"hi","response":"hello","id":"abc"}`)
+	defer server.Close()
+
+	transports := map[string][]ClientOption{
+		"default transport":    nil,
+		"compression disabled": {WithTransport(&http.Transport{DisableCompression: true})},
+	}
+
+	for name, opts := range transports {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			llm := NewLLMClient(server.URL, 5*time.Second, opts...)
+			chat, err := llm.Chat(ctx, &ChatRequest{UserID: "mom", Message: "hi"})
+			if err != nil || chat.Response != "hello" {
+				t.Errorf("llm: expected decompressed reply, got %+v, %v", chat, err)
+			}
+
+			voice := NewVoiceClient(server.URL, 5*time.Second, opts...)
+			voice.skipWAVValidation = true
+			processed, err := voice.ProcessVoice(ctx, []byte("audio"), ProcessVoiceOptions{})
+			if err != nil || processed.Transcript != "hi" {
+				t.Errorf("voice: expected decompressed response, got %+v, %v", processed, err)
+			}
+
+			learning := NewLearningClient(server.URL, 5*time.Second, opts...)
+			submitted, err := learning.Submit(ctx, &LearningRequest{UserID: "mom", Content: "c", Source: "s"})
+			if err != nil || submitted.ID != "abc" {
+				t.Errorf("learning: expected decompressed response, got %+v, %v", submitted, err)
+			}
+		})
+	}
+}
+
+func TestGzipTransport_EmptyBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewLearningClient(server.URL, 5*time.Second, WithTransport(&http.Transport{DisableCompression: true}))
+	if _, err := client.Health(context.Background()); err != nil {
+		t.Errorf("expected an empty gzip-labelled body to be fine, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package clients
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -13,7 +14,8 @@ type LLMClientInterface interface {
 
 // VoiceClientInterface defines the interface for Voice sidecar operations
 type VoiceClientInterface interface {
-	ProcessVoice(ctx context.Context, wavData []byte) (*VoiceResponse, error)
+	ProcessVoice(ctx context.Context, wavData []byte, opts ProcessVoiceOptions) (*VoiceResponse, error)
+	Enroll(ctx context.Context, userID string, wavData []byte) (*EnrollResponse, error)
 	Health(ctx context.Context) (time.Duration, error)
 }
 
@@ -22,3 +24,20 @@ type LearningClientInterface interface {
 	Submit(ctx context.Context, req *LearningRequest) (*LearningResponse, error)
 	Health(ctx context.Context) (time.Duration, error)
 }
+
+// CapabilitiesProvider is implemented by sidecar clients that can ask their
+// sidecar what it supports
+type CapabilitiesProvider interface {
+	Capabilities(ctx context.Context) (*Capabilities, error)
+}
+
+// LearningStreamer is implemented by Learning clients that can stream large
+// content to their sidecar instead of sending it in one JSON body
+type LearningStreamer interface {
+	SubmitStream(ctx context.Context, req *LearningStreamRequest, content io.Reader) (*LearningResponse, error)
+}
+
+// Summarizer is implemented by LLM clients that can condense a conversation
+type Summarizer interface {
+	Summarize(ctx context.Context, req *SummarizeRequest) (*SummarizeResponse, error)
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -18,16 +20,19 @@ type LearningClient struct {
 }
 
 // NewLearningClient creates a new Learning sidecar client
-func NewLearningClient(baseURL string, timeout time.Duration) *LearningClient {
+func NewLearningClient(baseURL string, timeout time.Duration, opts ...ClientOption) *LearningClient {
 	return &LearningClient{
 		baseURL: baseURL,
 		timeout: timeout,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		client:  newHTTPClient(timeout, opts),
 	}
 }
 
+// SetUserAgent changes the User-Agent header sent to the Learning sidecar
+func (c *LearningClient) SetUserAgent(userAgent string) {
+	c.client.Transport = withUserAgent(c.client.Transport, userAgent)
+}
+
 // LearningRequest represents a request to submit learning content
 type LearningRequest struct {
 	UserID  string `json:"user_id"`
@@ -37,8 +42,27 @@ type LearningRequest struct {
 
 // LearningResponse represents a response from the Learning sidecar
 type LearningResponse struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
+	ID     string   `json:"id"`
+	Status string   `json:"status"`
+	Reason string   `json:"reason,omitempty"` // Why a rejected submission was refused
+	Errors []string `json:"errors,omitempty"` // Individual validation failures, e.g. "content too long"
+}
+
+// Rejected reports whether the sidecar turned the submission down, as
+// "rejected" or a gate-specific "rejected_<gate>" status
+func (r *LearningResponse) Rejected() bool {
+	return r.Status == "rejected" || strings.HasPrefix(r.Status, "rejected_")
+}
+
+// RejectedError is returned when the Learning sidecar refuses a submission with
+// a 4xx status; unlike an outage, resending the same submission will not help
+type RejectedError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("Learning sidecar returned status %d: %s", e.StatusCode, e.Body)
 }
 
 // Submit sends a learning submission to the Learning sidecar
@@ -64,12 +88,85 @@ func (c *LearningClient) Submit(ctx context.Context, req *LearningRequest) (*Lea
 	}
 	defer resp.Body.Close()
 
+	return parseLearningResponse(resp)
+}
+
+// LearningStreamRequest describes content streamed to the Learning sidecar
+type LearningStreamRequest struct {
+	UserID   string
+	Source   string
+	Filename string // Name of the uploaded file, passed along for the sidecar's records
+}
+
+// SubmitStream sends learning content to the Learning sidecar as a multipart
+// upload (user_id and source fields, then a "file" part) to
+// /learning/submit/stream. The content is copied through a pipe while the
+// request is sent, so it is never held in memory as a whole; an error reading
+// it fails the request.
+func (c *LearningClient) SubmitStream(ctx context.Context, req *LearningStreamRequest, content io.Reader) (*LearningResponse, error) {
+	body, contentType := pipeLearningForm(req, content)
+	defer body.Close()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/learning/submit/stream", body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseLearningResponse(resp)
+}
+
+// pipeLearningForm returns a reader producing the multipart form for
+// SubmitStream as content is read. Closing the reader stops the writer.
+func pipeLearningForm(req *LearningStreamRequest, content io.Reader) (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		pw.CloseWithError(func() error {
+			if err := writer.WriteField("user_id", req.UserID); err != nil {
+				return err
+			}
+			if err := writer.WriteField("source", req.Source); err != nil {
+				return err
+			}
+			filename := req.Filename
+			if filename == "" {
+				filename = "content.txt"
+			}
+			part, err := writer.CreateFormFile("file", filename)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, content); err != nil {
+				return err
+			}
+			return writer.Close()
+		}())
+	}()
+
+	return pr, writer.FormDataContentType()
+}
+
+// parseLearningResponse reads the Learning sidecar's answer to a submission
+func parseLearningResponse(resp *http.Response) (*LearningResponse, error) {
 	// Read response body
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	// Client errors mean the submission itself was refused
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return nil, &RejectedError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
 	// Check for non-2xx status codes
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("Learning sidecar returned status %d: %s", resp.StatusCode, string(respBody))
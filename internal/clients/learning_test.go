@@ -1,8 +1,11 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -95,6 +98,143 @@ func TestLearningClient_Submit_ServerError(t *testing.T) {
 	}
 }
 
+func TestLearningClient_Submit_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte("unknown source"))
+	}))
+	defer server.Close()
+
+	client := NewLearningClient(server.URL, 5*time.Second)
+	_, err := client.Submit(context.Background(), &LearningRequest{UserID: "child", Content: "test", Source: "test"})
+
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("expected RejectedError, got %v", err)
+	}
+	if rejected.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", rejected.StatusCode)
+	}
+}
+
+func TestLearningClient_Submit_RejectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "corr_42", "status": "rejected_gate1", "reason": "failed sanity check", "errors": ["content too long", "unsafe content"]}`))
+	}))
+	defer server.Close()
+
+	client := NewLearningClient(server.URL, 5*time.Second)
+	resp, err := client.Submit(context.Background(), &LearningRequest{UserID: "teen", Content: "test", Source: "user_correction"})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	if !resp.Rejected() {
+		t.Errorf("expected status %q to count as rejected", resp.Status)
+	}
+	if resp.Reason != "failed sanity check" {
+		t.Errorf("expected the reason, got %q", resp.Reason)
+	}
+	if len(resp.Errors) != 2 || resp.Errors[0] != "content too long" {
+		t.Errorf("expected the validation errors, got %v", resp.Errors)
+	}
+}
+
+func TestLearningClient_SubmitStream(t *testing.T) {
+	// A transcript-sized upload, larger than the pipe or any copy buffer
+	content := bytes.Repeat([]byte("Le cours de maths est vendredi à 14h. "), 50000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/learning/submit/stream" {
+			t.Errorf("expected /learning/submit/stream, got %s", r.URL.Path)
+		}
+		if r.ContentLength != -1 {
+			t.Errorf("expected a streamed body of unknown length, got %d", r.ContentLength)
+		}
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			t.Fatalf("expected a multipart body: %v", err)
+		}
+		fields := map[string]string{}
+		var received []byte
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("failed to read part: %v", err)
+			}
+			data, _ := io.ReadAll(part)
+			if part.FormName() == "file" {
+				fields["filename"] = part.FileName()
+				received = data
+			} else {
+				fields[part.FormName()] = string(data)
+			}
+		}
+
+		if fields["user_id"] != "teen" || fields["source"] != "transcript" || fields["filename"] != "lecture.txt" {
+			t.Errorf("unexpected fields %v", fields)
+		}
+		if !bytes.Equal(received, content) {
+			t.Errorf("expected %d bytes of content, got %d", len(content), len(received))
+		}
+		json.NewEncoder(w).Encode(LearningResponse{ID: "learn_big", Status: "processing"})
+	}))
+	defer server.Close()
+
+	client := NewLearningClient(server.URL, 5*time.Second)
+	resp, err := client.SubmitStream(context.Background(),
+		&LearningStreamRequest{UserID: "teen", Source: "transcript", Filename: "lecture.txt"}, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("SubmitStream failed: %v", err)
+	}
+	if resp.ID != "learn_big" || resp.Status != "processing" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestLearningClient_SubmitStream_ReadErrorFailsRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		json.NewEncoder(w).Encode(LearningResponse{ID: "learn_1", Status: "processing"})
+	}))
+	defer server.Close()
+
+	readErr := errors.New("disk unplugged")
+	content := io.MultiReader(bytes.NewReader([]byte("partial transcript")), &erroringReader{err: readErr})
+
+	client := NewLearningClient(server.URL, 5*time.Second)
+	_, err := client.SubmitStream(context.Background(), &LearningStreamRequest{UserID: "teen", Source: "transcript"}, content)
+	if !errors.Is(err, readErr) {
+		t.Errorf("expected the content read error, got %v", err)
+	}
+}
+
+// erroringReader fails every read with err
+type erroringReader struct {
+	err error
+}
+
+func (e *erroringReader) Read(p []byte) (int, error) { return 0, e.err }
+
+func TestLearningResponse_Rejected(t *testing.T) {
+	for status, want := range map[string]bool{
+		"rejected":        true,
+		"rejected_gate2a": true,
+		"pending":         false,
+		"approved":        false,
+		"":                false,
+	} {
+		if got := (&LearningResponse{Status: status}).Rejected(); got != want {
+			t.Errorf("Rejected() for %q = %v, want %v", status, got, want)
+		}
+	}
+}
+
 func TestLearningClient_Health_Success(t *testing.T) {
 	// Create mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,105 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OverloadedError is returned when a concurrency-limited sidecar has no free
+// slot for a call, either because its wait queue is full or the wait timed out
+type OverloadedError struct {
+	Sidecar string
+	Reason  string // "queue_full" or "queue_timeout"
+}
+
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("%s sidecar overloaded: %s", e.Sidecar, e.Reason)
+}
+
+// limiter is a semaphore capping in-flight calls, with a bounded number of
+// callers allowed to wait for a slot
+type limiter struct {
+	sidecar string
+	slots   chan struct{} // One token per in-flight call
+	queue   chan struct{} // One token per waiting call
+	maxWait time.Duration
+}
+
+func newLimiter(sidecar string, maxInFlight, maxQueued int, maxWait time.Duration) *limiter {
+	return &limiter{
+		sidecar: sidecar,
+		slots:   make(chan struct{}, maxInFlight),
+		queue:   make(chan struct{}, maxQueued),
+		maxWait: maxWait,
+	}
+}
+
+// acquire takes a slot, waiting in the queue if there is room. Callers must
+// release the slot once acquire returns nil.
+func (l *limiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+		defer func() { <-l.queue }()
+	default:
+		return &OverloadedError{Sidecar: l.sidecar, Reason: "queue_full"}
+	}
+
+	var timeout <-chan time.Time
+	if l.maxWait > 0 {
+		timer := time.NewTimer(l.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return &OverloadedError{Sidecar: l.sidecar, Reason: "queue_timeout"}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire
+func (l *limiter) release() {
+	<-l.slots
+}
+
+// LimitedLLMClient caps the number of simultaneous Chat calls to an LLM client
+type LimitedLLMClient struct {
+	next    LLMClientInterface
+	limiter *limiter
+}
+
+// NewLimitedLLMClient wraps next so at most maxInFlight Chat calls run at once.
+// Up to maxQueued further calls wait as long as maxWait (0 waits for the
+// context); beyond that, Chat returns an *OverloadedError.
+func NewLimitedLLMClient(next LLMClientInterface, maxInFlight, maxQueued int, maxWait time.Duration) *LimitedLLMClient {
+	return &LimitedLLMClient{
+		next:    next,
+		limiter: newLimiter("LLM", maxInFlight, maxQueued, maxWait),
+	}
+}
+
+// Chat forwards the request once a slot is free
+func (c *LimitedLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if err := c.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.limiter.release()
+
+	return c.next.Chat(ctx, req)
+}
+
+// Health is not limited, so probes still answer while the sidecar is saturated
+func (c *LimitedLLMClient) Health(ctx context.Context) (time.Duration, error) {
+	return c.next.Health(ctx)
+}
@@ -0,0 +1,159 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingLLMClient holds every Chat call until release is closed
+type blockingLLMClient struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingLLMClient() *blockingLLMClient {
+	return &blockingLLMClient{
+		started: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+}
+
+func (c *blockingLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	c.started <- struct{}{}
+	<-c.release
+	return &ChatResponse{Response: "ok", UserID: req.UserID}, nil
+}
+
+func (c *blockingLLMClient) Health(ctx context.Context) (time.Duration, error) {
+	return time.Millisecond, nil
+}
+
+// fillSlots starts n Chat calls and waits until all of them reach the sidecar
+func fillSlots(t *testing.T, client *LimitedLLMClient, backend *blockingLLMClient, n int) chan error {
+	t.Helper()
+	done := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "hi"})
+			done <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case <-backend.started:
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of %d calls reached the sidecar", i, n)
+		}
+	}
+	return done
+}
+
+func TestLimitedLLMClient_FailsFastWithoutQueue(t *testing.T) {
+	backend := newBlockingLLMClient()
+	client := NewLimitedLLMClient(backend, 2, 0, 0)
+
+	done := fillSlots(t, client, backend, 2)
+	defer func() {
+		close(backend.release)
+		<-done
+		<-done
+	}()
+
+	_, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "one too many"})
+
+	var overloadedErr *OverloadedError
+	if !errors.As(err, &overloadedErr) {
+		t.Fatalf("expected OverloadedError, got %v", err)
+	}
+	if overloadedErr.Reason != "queue_full" {
+		t.Errorf("expected reason 'queue_full', got %s", overloadedErr.Reason)
+	}
+}
+
+func TestLimitedLLMClient_QueuedCallWaitsForSlot(t *testing.T) {
+	backend := newBlockingLLMClient()
+	client := NewLimitedLLMClient(backend, 1, 1, time.Second)
+
+	done := fillSlots(t, client, backend, 1)
+
+	queued := make(chan error, 1)
+	go func() {
+		_, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "queued"})
+		queued <- err
+	}()
+
+	// The queued call must not reach the sidecar while the slot is taken
+	select {
+	case <-backend.started:
+		t.Fatal("queued call ran while the only slot was busy")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(backend.release)
+	if err := <-done; err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if err := <-queued; err != nil {
+		t.Fatalf("queued call failed: %v", err)
+	}
+}
+
+func TestLimitedLLMClient_QueueTimeout(t *testing.T) {
+	backend := newBlockingLLMClient()
+	client := NewLimitedLLMClient(backend, 1, 1, 20*time.Millisecond)
+
+	done := fillSlots(t, client, backend, 1)
+	defer func() {
+		close(backend.release)
+		<-done
+	}()
+
+	_, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "waits too long"})
+
+	var overloadedErr *OverloadedError
+	if !errors.As(err, &overloadedErr) {
+		t.Fatalf("expected OverloadedError, got %v", err)
+	}
+	if overloadedErr.Reason != "queue_timeout" {
+		t.Errorf("expected reason 'queue_timeout', got %s", overloadedErr.Reason)
+	}
+}
+
+func TestLimitedLLMClient_QueueFull(t *testing.T) {
+	backend := newBlockingLLMClient()
+	client := NewLimitedLLMClient(backend, 1, 1, 0)
+
+	done := fillSlots(t, client, backend, 1)
+
+	// Occupy the single queue position until the test ends
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan error, 1)
+	go func() {
+		_, err := client.Chat(ctx, &ChatRequest{UserID: "dad", Message: "queued"})
+		queued <- err
+	}()
+	defer func() {
+		cancel()
+		<-queued
+		close(backend.release)
+		<-done
+	}()
+
+	// Wait for the queued call to take its position
+	deadline := time.Now().Add(time.Second)
+	for len(client.limiter.queue) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("queued call never entered the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "no room"})
+
+	var overloadedErr *OverloadedError
+	if !errors.As(err, &overloadedErr) || overloadedErr.Reason != "queue_full" {
+		t.Fatalf("expected queue_full OverloadedError, got %v", err)
+	}
+}
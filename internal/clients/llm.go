@@ -4,30 +4,86 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
-// LLMClient handles communication with the LLM sidecar
+// llmHostCooldown is how long a failed LLM instance is skipped
+const llmHostCooldown = 30 * time.Second
+
+// LLMClient handles communication with one or more LLM sidecar instances,
+// round-robining requests across those not recently seen failing
 type LLMClient struct {
-	baseURL string
-	timeout time.Duration
-	client  *http.Client
+	baseURLs []string
+	timeout  time.Duration
+	client   *http.Client
+
+	mu        sync.Mutex
+	next      int
+	downUntil map[string]time.Time
 }
 
 // NewLLMClient creates a new LLM sidecar client
-func NewLLMClient(baseURL string, timeout time.Duration) *LLMClient {
+func NewLLMClient(baseURL string, timeout time.Duration, opts ...ClientOption) *LLMClient {
+	return NewBalancedLLMClient([]string{baseURL}, timeout, opts...)
+}
+
+// NewBalancedLLMClient creates an LLM client load-balancing across several instances
+func NewBalancedLLMClient(baseURLs []string, timeout time.Duration, opts ...ClientOption) *LLMClient {
 	return &LLMClient{
-		baseURL: baseURL,
-		timeout: timeout,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		baseURLs:  baseURLs,
+		timeout:   timeout,
+		client:    newHTTPClient(timeout, opts),
+		downUntil: make(map[string]time.Time),
 	}
 }
 
+// SetUserAgent changes the User-Agent header sent to the LLM sidecar
+func (c *LLMClient) SetUserAgent(userAgent string) {
+	c.client.Transport = withUserAgent(c.client.Transport, userAgent)
+}
+
+// hostOrder returns every instance, starting with the next healthy one in
+// round-robin order. Instances in cooldown are moved to the end.
+func (c *LLMClient) hostOrder() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	start := c.next
+	c.next = (c.next + 1) % len(c.baseURLs)
+
+	var healthy, down []string
+	for i := range c.baseURLs {
+		url := c.baseURLs[(start+i)%len(c.baseURLs)]
+		if now.Before(c.downUntil[url]) {
+			down = append(down, url)
+		} else {
+			healthy = append(healthy, url)
+		}
+	}
+
+	return append(healthy, down...)
+}
+
+// markDown puts an instance in cooldown
+func (c *LLMClient) markDown(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.downUntil[url] = time.Now().Add(llmHostCooldown)
+}
+
+// markUp clears an instance's cooldown
+func (c *LLMClient) markUp(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.downUntil, url)
+}
+
 // ConversationTurn represents a single turn in conversation history
 type ConversationTurn struct {
 	Role    string `json:"role"`    // "user" or "assistant"
@@ -39,17 +95,39 @@ type ChatRequest struct {
 	UserID              string             `json:"user_id"`
 	Message             string             `json:"message"`
 	ConversationHistory []ConversationTurn `json:"conversation_history,omitempty"`
+	AssistantName       string             `json:"assistant_name,omitempty"` // Persona the sidecar should answer as
+	N                   int                `json:"n,omitempty"`              // Candidate replies wanted, omitted for one
+	Context             []string           `json:"context,omitempty"`        // Documents to ground the reply in
+	Model               string             `json:"model,omitempty"`          // Overrides the sidecar's model choice
+	Language            string             `json:"language,omitempty"`       // Language to answer in, e.g. "fr"
 }
 
 // ChatResponse represents a response from the LLM sidecar
 type ChatResponse struct {
-	Response     string   `json:"response"`
-	ModelUsed    string   `json:"model_used"`
-	MemoriesUsed []string `json:"memories_used,omitempty"`
-	UserID       string   `json:"user_id"`
+	Response      string      `json:"response"`
+	ModelUsed     string      `json:"model_used"`
+	MemoriesUsed  []string    `json:"memories_used,omitempty"`
+	UserID        string      `json:"user_id"`
+	Cached        bool        `json:"cached,omitempty"`         // Set by the orchestrator when served from its cache
+	Usage         *TokenUsage `json:"usage,omitempty"`          // nil when the sidecar does not report usage
+	MessageID     string      `json:"message_id,omitempty"`     // Set by the orchestrator to identify the reply
+	RequestID     string      `json:"request_id,omitempty"`     // Echo of the client's request_id
+	Candidates    []string    `json:"candidates,omitempty"`     // Alternative replies when more than one was requested
+	Suggestions   []string    `json:"suggestions,omitempty"`    // Follow-up quick replies, when the sidecar proposes some
+	EmptyResponse bool        `json:"empty_response,omitempty"` // Set by the orchestrator when the LLM's blank reply was replaced
+	FallbackModel string      `json:"fallback_model,omitempty"` // Set by the orchestrator when a fallback model served the reply
+
+	Raw json.RawMessage `json:"-"` // The sidecar's response body as received, for debugging
+}
+
+// TokenUsage reports the tokens consumed by one LLM call
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
-// Chat sends a chat request to the LLM sidecar
+// Chat sends a chat request to the LLM sidecar, failing over to the next
+// instance when one cannot be reached
 func (c *LLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
 	// Marshal request body
 	body, err := json.Marshal(req)
@@ -57,8 +135,23 @@ func (c *LLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse,
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	var lastErr error
+	for _, baseURL := range c.hostOrder() {
+		resp, err := c.chat(ctx, baseURL, body)
+		if err == nil || ctx.Err() != nil || !isTransportError(err) {
+			return resp, err
+		}
+		c.markDown(baseURL)
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// chat sends an encoded chat request to a single instance
+func (c *LLMClient) chat(ctx context.Context, baseURL string, body []byte) (*ChatResponse, error) {
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -68,7 +161,7 @@ func (c *LLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse,
 	// Execute request
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, &transportError{err: err}
 	}
 	defer resp.Body.Close()
 
@@ -88,15 +181,45 @@ func (c *LLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse,
 	if err := json.Unmarshal(respBody, &chatResp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	chatResp.Raw = respBody
 
 	return &chatResp, nil
 }
 
-// Health checks the health of the LLM sidecar
+// Health checks every LLM instance, updating their cooldowns. It reports the
+// fastest healthy instance and only fails when none are healthy.
 func (c *LLMClient) Health(ctx context.Context) (time.Duration, error) {
+	var (
+		best    time.Duration
+		healthy bool
+		lastErr error
+	)
+
+	for _, baseURL := range c.baseURLs {
+		latency, err := c.health(ctx, baseURL)
+		if err != nil {
+			c.markDown(baseURL)
+			lastErr = err
+			continue
+		}
+		c.markUp(baseURL)
+		if !healthy || latency < best {
+			best = latency
+		}
+		healthy = true
+	}
+
+	if !healthy {
+		return 0, lastErr
+	}
+	return best, nil
+}
+
+// health checks a single LLM instance
+func (c *LLMClient) health(ctx context.Context, baseURL string) (time.Duration, error) {
 	start := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/health", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/health", nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -115,3 +238,23 @@ func (c *LLMClient) Health(ctx context.Context) (time.Duration, error) {
 
 	return latency, nil
 }
+
+// transportError marks failures to reach an instance at all, as opposed to
+// error responses from it
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return fmt.Sprintf("failed to execute request: %v", e.err)
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// isTransportError reports whether err means the instance could not be reached
+func isTransportError(err error) bool {
+	var te *transportError
+	return errors.As(err, &te)
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -40,6 +42,7 @@ func TestLLMClient_Chat_Success(t *testing.T) {
 			ModelUsed:    "llama3.1:8b",
 			MemoriesUsed: []string{"memory1"},
 			UserID:       "dad",
+			Usage:        &TokenUsage{PromptTokens: 42, CompletionTokens: 17},
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
@@ -53,7 +56,7 @@ func TestLLMClient_Chat_Success(t *testing.T) {
 	req := &ChatRequest{
 		UserID:              "dad",
 		Message:             "test message",
-		ConversationHistory: []string{},
+		ConversationHistory: []ConversationTurn{},
 	}
 
 	resp, err := client.Chat(context.Background(), req)
@@ -71,6 +74,52 @@ func TestLLMClient_Chat_Success(t *testing.T) {
 	if resp.UserID != "dad" {
 		t.Errorf("expected user_id 'dad', got %s", resp.UserID)
 	}
+	if resp.Usage == nil {
+		t.Fatal("expected usage to be decoded")
+	}
+	if resp.Usage.PromptTokens != 42 || resp.Usage.CompletionTokens != 17 {
+		t.Errorf("expected usage 42/17, got %d/%d", resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	}
+}
+
+func TestLLMClient_Chat_WithoutUsage(t *testing.T) {
+	// Create mock server that reports no usage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"response": "hi", "model_used": "llama3.1:8b", "user_id": "dad"}`))
+	}))
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, 5*time.Second)
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "hi"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Usage != nil {
+		t.Errorf("expected nil usage, got %+v", resp.Usage)
+	}
+	if string(resp.Raw) != `{"response": "hi", "model_used": "llama3.1:8b", "user_id": "dad"}` {
+		t.Errorf("expected the raw body kept for debugging, got %s", resp.Raw)
+	}
+}
+
+func TestLLMClient_Chat_Suggestions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"response": "It's sunny.", "user_id": "dad", "suggestions": ["And tomorrow?", "Set a reminder"]}`))
+	}))
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, 5*time.Second)
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "weather?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if want := []string{"And tomorrow?", "Set a reminder"}; !reflect.DeepEqual(resp.Suggestions, want) {
+		t.Errorf("expected suggestions %v, got %v", want, resp.Suggestions)
+	}
 }
 
 func TestLLMClient_Chat_ServerError(t *testing.T) {
@@ -136,3 +185,88 @@ func TestLLMClient_Health_Failure(t *testing.T) {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+// newCountingLLMServer returns a mock LLM sidecar that counts chat requests
+func newCountingLLMServer(t *testing.T, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chat" {
+			atomic.AddInt32(calls, 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ChatResponse{Response: "ok", UserID: "dad"})
+	}))
+}
+
+func TestLLMClient_Chat_RoundRobin(t *testing.T) {
+	var callsA, callsB int32
+	serverA := newCountingLLMServer(t, &callsA)
+	defer serverA.Close()
+	serverB := newCountingLLMServer(t, &callsB)
+	defer serverB.Close()
+
+	// Create client
+	client := NewBalancedLLMClient([]string{serverA.URL, serverB.URL}, 5*time.Second)
+
+	for i := 0; i < 4; i++ {
+		if _, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "hi"}); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	if callsA != 2 || callsB != 2 {
+		t.Errorf("expected 2 calls per instance, got %d and %d", callsA, callsB)
+	}
+}
+
+func TestLLMClient_Chat_SkipsDownHost(t *testing.T) {
+	var callsUp int32
+	serverUp := newCountingLLMServer(t, &callsUp)
+	defer serverUp.Close()
+
+	// Closed server: connection refused
+	serverDown := httptest.NewServer(http.NotFoundHandler())
+	downURL := serverDown.URL
+	serverDown.Close()
+
+	// Create client with the down host first
+	client := NewBalancedLLMClient([]string{downURL, serverUp.URL}, 5*time.Second)
+
+	// Health marks the down host, which is then skipped
+	if _, err := client.Health(context.Background()); err != nil {
+		t.Fatalf("expected healthy overall with one instance up: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "hi"}); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	if callsUp != 4 {
+		t.Errorf("expected all 4 calls on the healthy instance, got %d", callsUp)
+	}
+}
+
+func TestLLMClient_Chat_FailsOverOnConnectionError(t *testing.T) {
+	var callsUp int32
+	serverUp := newCountingLLMServer(t, &callsUp)
+	defer serverUp.Close()
+
+	serverDown := httptest.NewServer(http.NotFoundHandler())
+	downURL := serverDown.URL
+	serverDown.Close()
+
+	// Create client without a prior health check
+	client := NewBalancedLLMClient([]string{downURL, serverUp.URL}, 5*time.Second)
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{UserID: "dad", Message: "hi"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Response != "ok" {
+		t.Errorf("expected response 'ok', got %s", resp.Response)
+	}
+	if callsUp != 1 {
+		t.Errorf("expected 1 call on the healthy instance, got %d", callsUp)
+	}
+}
@@ -0,0 +1,64 @@
+package clients
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOption customizes how a sidecar client talks HTTP
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+}
+
+// WithHTTPClient makes the sidecar client send its requests through a copy of
+// hc, keeping hc's timeout, redirect policy and transport. The User-Agent
+// header is still set on top of hc's transport.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = hc
+	}
+}
+
+// WithTransport sends the sidecar client's requests through rt instead of
+// http.DefaultTransport. It takes precedence over the transport of a client
+// given with WithHTTPClient.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = rt
+	}
+}
+
+// NewProxyTransport returns a copy of http.DefaultTransport sending every
+// request through the proxy at proxyURL. Sidecar clients built with it via
+// WithTransport share its connection pool.
+func NewProxyTransport(proxyURL *url.URL) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return transport
+}
+
+// newHTTPClient builds the *http.Client for a sidecar client. Without options
+// it is a plain client with the given timeout. Responses are decompressed
+// when gzip-encoded, whatever the transport.
+func newHTTPClient(timeout time.Duration, opts []ClientOption) *http.Client {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	if o.httpClient != nil {
+		// Copy so SetUserAgent never touches the caller's client
+		copied := *o.httpClient
+		client = &copied
+	}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+	client.Transport = withUserAgent(&gzipTransport{next: client.Transport}, DefaultUserAgent())
+	return client
+}
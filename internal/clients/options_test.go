@@ -0,0 +1,105 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport answers every request itself with an empty JSON object,
+// recording the path and User-Agent of each request
+type fakeTransport struct {
+	mu         sync.Mutex
+	paths      []string
+	userAgents []string
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.paths = append(f.paths, req.URL.Path)
+	f.userAgents = append(f.userAgents, req.Header.Get("User-Agent"))
+	f.mu.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestClients_WithTransport(t *testing.T) {
+	fake := &fakeTransport{}
+	ctx := context.Background()
+
+	// Nothing listens on this address; only the fake transport can answer
+	const baseURL = "http://sidecar.invalid"
+
+	llm := NewLLMClient(baseURL, 5*time.Second, WithTransport(fake))
+	if _, err := llm.Chat(ctx, &ChatRequest{UserID: "dad", Message: "hi"}); err != nil {
+		t.Fatalf("llm chat: %v", err)
+	}
+
+	voice := NewVoiceClient(baseURL, 5*time.Second, WithTransport(fake))
+	voice.skipWAVValidation = true
+	if _, err := voice.ProcessVoice(ctx, []byte("audio"), ProcessVoiceOptions{}); err != nil {
+		t.Fatalf("voice process: %v", err)
+	}
+
+	learning := NewLearningClient(baseURL, 5*time.Second, WithTransport(fake))
+	if _, err := learning.Submit(ctx, &LearningRequest{UserID: "dad", Content: "c", Source: "s"}); err != nil {
+		t.Fatalf("learning submit: %v", err)
+	}
+
+	want := []string{"/chat", "/voice/process", "/learning/submit"}
+	if strings.Join(fake.paths, " ") != strings.Join(want, " ") {
+		t.Errorf("expected requests %v through the transport, got %v", want, fake.paths)
+	}
+	for i, ua := range fake.userAgents {
+		if ua != DefaultUserAgent() {
+			t.Errorf("request %d: expected User-Agent %q, got %q", i, DefaultUserAgent(), ua)
+		}
+	}
+}
+
+func TestClients_WithHTTPClient(t *testing.T) {
+	fake := &fakeTransport{}
+	hc := &http.Client{Transport: fake, Timeout: time.Second}
+
+	learning := NewLearningClient("http://sidecar.invalid", 5*time.Second, WithHTTPClient(hc))
+	learning.SetUserAgent("orchestrator/test")
+	if _, err := learning.Health(context.Background()); err != nil {
+		t.Fatalf("health: %v", err)
+	}
+
+	if len(fake.paths) != 1 || fake.paths[0] != "/health" {
+		t.Fatalf("expected the health check through the given client, got %v", fake.paths)
+	}
+	if fake.userAgents[0] != "orchestrator/test" {
+		t.Errorf("expected configured User-Agent, got %q", fake.userAgents[0])
+	}
+	if learning.client.Timeout != time.Second {
+		t.Errorf("expected the given client's timeout, got %s", learning.client.Timeout)
+	}
+	if hc.Transport != fake {
+		t.Error("expected the caller's client to be left untouched")
+	}
+}
+
+func TestNewProxyTransport(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.internal:3128")
+	transport := NewProxyTransport(proxyURL)
+
+	req, _ := http.NewRequest("GET", "http://localhost:10002/health", nil)
+	got, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("Proxy failed: %v", err)
+	}
+	if got == nil || got.String() != "http://proxy.internal:3128" {
+		t.Errorf("expected requests to go through the proxy, got %v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// StubVoiceClient returns canned Voice sidecar responses for dry-run mode
+type StubVoiceClient struct{}
+
+// NewStubVoiceClient creates a stub Voice client
+func NewStubVoiceClient() *StubVoiceClient {
+	return &StubVoiceClient{}
+}
+
+// ProcessVoice always identifies the speaker as "dad"
+func (c *StubVoiceClient) ProcessVoice(ctx context.Context, wavData []byte, opts ProcessVoiceOptions) (*VoiceResponse, error) {
+	return &VoiceResponse{
+		Status:     "identified",
+		UserID:     "dad",
+		Confidence: 0.99,
+		Transcript: "dry run transcript",
+	}, nil
+}
+
+// Enroll always accepts the sample
+func (c *StubVoiceClient) Enroll(ctx context.Context, userID string, wavData []byte) (*EnrollResponse, error) {
+	return &EnrollResponse{
+		Status:  "enrolled",
+		UserID:  userID,
+		Samples: 1,
+	}, nil
+}
+
+// Capabilities reports enrollment and a single language
+func (c *StubVoiceClient) Capabilities(ctx context.Context) (*Capabilities, error) {
+	return &Capabilities{
+		Languages: []string{"en"},
+		Features:  map[string]bool{"enrollment": true},
+	}, nil
+}
+
+// Health always reports the stub as healthy
+func (c *StubVoiceClient) Health(ctx context.Context) (time.Duration, error) {
+	return time.Millisecond, nil
+}
+
+// StubLLMClient echoes chat messages back for dry-run mode
+type StubLLMClient struct{}
+
+// NewStubLLMClient creates a stub LLM client
+func NewStubLLMClient() *StubLLMClient {
+	return &StubLLMClient{}
+}
+
+// Chat replies with the user's message prefixed by "echo: ", numbering the
+// candidates when several are requested
+func (c *StubLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	resp := &ChatResponse{
+		Response:  "echo: " + req.Message,
+		ModelUsed: "dry-run",
+		UserID:    req.UserID,
+	}
+	if req.N > 1 {
+		for i := 1; i <= req.N; i++ {
+			resp.Candidates = append(resp.Candidates, fmt.Sprintf("echo %d: %s", i, req.Message))
+		}
+	}
+	return resp, nil
+}
+
+// Capabilities reports the single dry-run model
+func (c *StubLLMClient) Capabilities(ctx context.Context) (*Capabilities, error) {
+	return &Capabilities{Models: []string{"dry-run"}}, nil
+}
+
+// Summarize reports how many turns it was asked to condense
+func (c *StubLLMClient) Summarize(ctx context.Context, req *SummarizeRequest) (*SummarizeResponse, error) {
+	return &SummarizeResponse{Summary: fmt.Sprintf("summary of %d turns", len(req.ConversationHistory))}, nil
+}
+
+// Health always reports the stub as healthy
+func (c *StubLLMClient) Health(ctx context.Context) (time.Duration, error) {
+	return time.Millisecond, nil
+}
+
+// StubLearningClient accepts every submission for dry-run mode
+type StubLearningClient struct{}
+
+// NewStubLearningClient creates a stub Learning client
+func NewStubLearningClient() *StubLearningClient {
+	return &StubLearningClient{}
+}
+
+// Submit reports every submission as processing
+func (c *StubLearningClient) Submit(ctx context.Context, req *LearningRequest) (*LearningResponse, error) {
+	return &LearningResponse{
+		ID:     "dry-run",
+		Status: "processing",
+	}, nil
+}
+
+// SubmitStream reads the content through and reports it as processing
+func (c *StubLearningClient) SubmitStream(ctx context.Context, req *LearningStreamRequest, content io.Reader) (*LearningResponse, error) {
+	if _, err := io.Copy(io.Discard, content); err != nil {
+		return nil, err
+	}
+	return &LearningResponse{
+		ID:     "dry-run",
+		Status: "processing",
+	}, nil
+}
+
+// Health always reports the stub as healthy
+func (c *StubLearningClient) Health(ctx context.Context) (time.Duration, error) {
+	return time.Millisecond, nil
+}
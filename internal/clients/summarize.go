@@ -0,0 +1,97 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrSummarizeUnsupported is returned when an LLM sidecar has no /summarize endpoint
+var ErrSummarizeUnsupported = errors.New("sidecar does not summarize conversations")
+
+// SummarizeRequest asks the LLM sidecar to condense conversation turns
+type SummarizeRequest struct {
+	UserID              string             `json:"user_id"`
+	ConversationHistory []ConversationTurn `json:"conversation_history"`
+	Model               string             `json:"model,omitempty"` // Overrides the sidecar's model choice
+}
+
+// SummarizeResponse is the LLM sidecar's summary of the turns it was sent
+type SummarizeResponse struct {
+	Summary string `json:"summary"`
+}
+
+// Summarize condenses conversation turns into a short summary, failing over
+// to the next instance when one cannot be reached
+func (c *LLMClient) Summarize(ctx context.Context, req *SummarizeRequest) (*SummarizeResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var lastErr error
+	for _, baseURL := range c.hostOrder() {
+		resp, err := c.summarize(ctx, baseURL, body)
+		if err == nil || ctx.Err() != nil || !isTransportError(err) {
+			return resp, err
+		}
+		c.markDown(baseURL)
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// summarize sends an encoded summarize request to a single instance. Sidecars
+// that predate the endpoint answer 404 or 405, reported as ErrSummarizeUnsupported.
+func (c *LLMClient) summarize(ctx context.Context, baseURL string, body []byte) (*SummarizeResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/summarize", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, ErrSummarizeUnsupported
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("LLM sidecar returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var summaryResp SummarizeResponse
+	if err := json.Unmarshal(respBody, &summaryResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &summaryResp, nil
+}
+
+// Summarize takes a slot like Chat, since it runs the model too
+func (c *LimitedLLMClient) Summarize(ctx context.Context, req *SummarizeRequest) (*SummarizeResponse, error) {
+	summarizer, ok := c.next.(Summarizer)
+	if !ok {
+		return nil, ErrSummarizeUnsupported
+	}
+
+	if err := c.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.limiter.release()
+
+	return summarizer.Summarize(ctx, req)
+}
@@ -0,0 +1,64 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLLMClient_Summarize_Success(t *testing.T) {
+	var got SummarizeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/summarize" {
+			t.Errorf("expected path /summarize, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"summary": "Dad asked about the weather."}`))
+	}))
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, 5*time.Second)
+
+	resp, err := client.Summarize(context.Background(), &SummarizeRequest{
+		UserID: "dad",
+		ConversationHistory: []ConversationTurn{
+			{Role: "user", Content: "weather?"},
+			{Role: "assistant", Content: "Sunny."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if resp.Summary != "Dad asked about the weather." {
+		t.Errorf("unexpected summary %q", resp.Summary)
+	}
+	if got.UserID != "dad" || len(got.ConversationHistory) != 2 {
+		t.Errorf("expected the user and both turns to be sent, got %+v", got)
+	}
+}
+
+func TestLLMClient_Summarize_Unsupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, 5*time.Second)
+
+	_, err := client.Summarize(context.Background(), &SummarizeRequest{UserID: "dad"})
+	if !errors.Is(err, ErrSummarizeUnsupported) {
+		t.Errorf("expected ErrSummarizeUnsupported, got %v", err)
+	}
+}
+
+func TestLimitedLLMClient_SummarizeUnsupported(t *testing.T) {
+	client := NewLimitedLLMClient(newBlockingLLMClient(), 1, 0, 0)
+
+	_, err := client.Summarize(context.Background(), &SummarizeRequest{UserID: "dad"})
+	if !errors.Is(err, ErrSummarizeUnsupported) {
+		t.Errorf("expected ErrSummarizeUnsupported for a client without Summarize, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package clients
+
+import "net/http"
+
+// Version is reported to the sidecars in the User-Agent header. Release
+// builds set it with -ldflags "-X github.com/assistant/orchestrator/internal/clients.Version=1.2.3".
+var Version = "dev"
+
+// DefaultUserAgent returns the User-Agent sent when none is configured
+func DefaultUserAgent() string {
+	return "orchestrator/" + Version
+}
+
+// userAgentTransport sets the User-Agent header on every outgoing request
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+// withUserAgent wraps rt so its requests carry userAgent, replacing the
+// User-Agent of an rt that is already wrapped. A nil rt means http.DefaultTransport.
+func withUserAgent(rt http.RoundTripper, userAgent string) http.RoundTripper {
+	if wrapped, ok := rt.(*userAgentTransport); ok {
+		rt = wrapped.next
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &userAgentTransport{userAgent: userAgent, next: rt}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// userAgentRecorder is a fake sidecar answering every route with an empty
+// JSON object and remembering the User-Agent of each request by path
+type userAgentRecorder struct {
+	mu     sync.Mutex
+	byPath map[string]string
+}
+
+func (u *userAgentRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	u.byPath[r.URL.Path] = r.Header.Get("User-Agent")
+	u.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+func TestClients_SendUserAgent(t *testing.T) {
+	rec := &userAgentRecorder{byPath: make(map[string]string)}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	ctx := context.Background()
+
+	llm := NewLLMClient(server.URL, 5*time.Second)
+	llm.Chat(ctx, &ChatRequest{UserID: "dad", Message: "hi"})
+
+	voice := NewVoiceClient(server.URL, 5*time.Second)
+	voice.skipWAVValidation = true
+	voice.ProcessVoice(ctx, []byte("audio"), ProcessVoiceOptions{})
+
+	learning := NewLearningClient(server.URL, 5*time.Second)
+	learning.Submit(ctx, &LearningRequest{UserID: "dad", Content: "c", Source: "s"})
+	learning.Health(ctx)
+
+	want := DefaultUserAgent()
+	for _, path := range []string{"/chat", "/voice/process", "/learning/submit", "/health"} {
+		if got := rec.byPath[path]; got != want {
+			t.Errorf("%s: expected User-Agent %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestClients_SetUserAgent(t *testing.T) {
+	rec := &userAgentRecorder{byPath: make(map[string]string)}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	llm := NewLLMClient(server.URL, 5*time.Second)
+	llm.SetUserAgent("orchestrator/test")
+	llm.Health(context.Background())
+
+	if got := rec.byPath["/health"]; got != "orchestrator/test" {
+		t.Errorf("expected configured User-Agent, got %q", got)
+	}
+}
@@ -4,41 +4,162 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/assistant/orchestrator/internal/audio"
 )
 
+// ErrInvalidWAV is returned by ProcessVoice when the payload is not a RIFF/WAVE file
+var ErrInvalidWAV = errors.New("invalid_wav")
+
+// defaultBusyRetryAfter is suggested when a busy sidecar gives no Retry-After
+const defaultBusyRetryAfter = time.Second
+
+// BusyError is returned when the Voice sidecar answers 429 Too Many Requests
+type BusyError struct {
+	RetryAfter time.Duration // How long the sidecar asked callers to wait
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("voice sidecar busy, retry after %s", e.RetryAfter)
+}
+
+// TimeoutError is returned when the Voice sidecar answers 504 Gateway Timeout.
+// A sidecar that gave up mid-transcription may still report what it heard.
+type TimeoutError struct {
+	PartialTranscript string // Transcribed before the sidecar gave up, may be empty
+	UserID            string // Speaker, if identified before the timeout
+}
+
+func (e *TimeoutError) Error() string {
+	if e.PartialTranscript != "" {
+		return "voice sidecar timed out with a partial transcript"
+	}
+	return "voice sidecar timed out"
+}
+
 // VoiceClient handles communication with the Voice sidecar
 type VoiceClient struct {
 	baseURL string
 	timeout time.Duration
 	client  *http.Client
+
+	skipWAVValidation bool // Upload payloads as-is, for testing against fake sidecars
 }
 
 // NewVoiceClient creates a new Voice sidecar client
-func NewVoiceClient(baseURL string, timeout time.Duration) *VoiceClient {
+func NewVoiceClient(baseURL string, timeout time.Duration, opts ...ClientOption) *VoiceClient {
 	return &VoiceClient{
 		baseURL: baseURL,
 		timeout: timeout,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		client:  newHTTPClient(timeout, opts),
 	}
 }
 
+// SetUserAgent changes the User-Agent header sent to the Voice sidecar
+func (c *VoiceClient) SetUserAgent(userAgent string) {
+	c.client.Transport = withUserAgent(c.client.Transport, userAgent)
+}
+
 // VoiceResponse represents a response from the Voice sidecar
 type VoiceResponse struct {
-	Status     string  `json:"status"`      // "identified", "fallback", "no_speech", "rejected"
-	UserID     string  `json:"user_id,omitempty"`
-	Confidence float64 `json:"confidence,omitempty"`
-	Transcript string  `json:"transcript,omitempty"`
+	Status     string             `json:"status"` // "identified", "fallback", "no_speech", "rejected"
+	UserID     string             `json:"user_id,omitempty"`
+	Confidence float64            `json:"confidence,omitempty"`
+	Transcript string             `json:"transcript,omitempty"`
+	Candidates []SpeakerCandidate `json:"candidates,omitempty"` // Ranked best first when identification is ambiguous
+	Language   string             `json:"language,omitempty"`   // Spoken language, when the sidecar detects it
+
+	Raw json.RawMessage `json:"-"` // The sidecar's response body as received, for debugging
+}
+
+// SpeakerCandidate is one speaker the voice sidecar considered for a sample
+type SpeakerCandidate struct {
+	UserID     string  `json:"user_id"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ProcessVoiceOptions tunes speaker identification. The zero value sends no
+// extra fields, leaving the sidecar's defaults in place.
+type ProcessVoiceOptions struct {
+	MinConfidence float64 // Overrides the sidecar's identification threshold when > 0
+	Enroll        bool    // Asks the sidecar to add the sample to the speaker's profile
+}
+
+// writeFields adds the options that are set to a multipart form
+func (o ProcessVoiceOptions) writeFields(writer *multipart.Writer) error {
+	if o.MinConfidence > 0 {
+		if err := writer.WriteField("min_confidence", strconv.FormatFloat(o.MinConfidence, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	if o.Enroll {
+		if err := writer.WriteField("enroll", "true"); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ProcessVoice sends a WAV file to the Voice sidecar for processing
-func (c *VoiceClient) ProcessVoice(ctx context.Context, wavData []byte) (*VoiceResponse, error) {
+func (c *VoiceClient) ProcessVoice(ctx context.Context, wavData []byte, opts ProcessVoiceOptions) (*VoiceResponse, error) {
+	respBody, err := c.postAudio(ctx, "/voice/process", wavData, opts.writeFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse response
+	var voiceResp VoiceResponse
+	if err := json.Unmarshal(respBody, &voiceResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	voiceResp.Raw = respBody
+
+	return &voiceResp, nil
+}
+
+// EnrollResponse represents the Voice sidecar's answer to an enrollment sample
+type EnrollResponse struct {
+	Status  string `json:"status"` // e.g. "enrolled", "needs_more_samples"
+	UserID  string `json:"user_id"`
+	Samples int    `json:"samples,omitempty"` // Samples now stored for the speaker
+	Message string `json:"message,omitempty"`
+}
+
+// Enroll sends a voice sample to the Voice sidecar to register it for userID
+func (c *VoiceClient) Enroll(ctx context.Context, userID string, wavData []byte) (*EnrollResponse, error) {
+	respBody, err := c.postAudio(ctx, "/voice/enroll", wavData, func(writer *multipart.Writer) error {
+		return writer.WriteField("user_id", userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse response
+	var enrollResp EnrollResponse
+	if err := json.Unmarshal(respBody, &enrollResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &enrollResp, nil
+}
+
+// postAudio uploads a WAV file plus any extra form fields to path and returns
+// the body of a 2xx response
+func (c *VoiceClient) postAudio(ctx context.Context, path string, wavData []byte, writeFields func(*multipart.Writer) error) ([]byte, error) {
+	// Reject garbage before paying for the upload
+	if !c.skipWAVValidation {
+		if _, err := audio.ParseWAVHeader(wavData); err != nil {
+			return nil, fmt.Errorf("%w: missing or truncated RIFF/WAVE header", ErrInvalidWAV)
+		}
+	}
+
 	// Create multipart form data
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
@@ -53,13 +174,17 @@ func (c *VoiceClient) ProcessVoice(ctx context.Context, wavData []byte) (*VoiceR
 		return nil, fmt.Errorf("failed to write wav data: %w", err)
 	}
 
+	if err := writeFields(writer); err != nil {
+		return nil, fmt.Errorf("failed to write form fields: %w", err)
+	}
+
 	// Close multipart writer
 	if err := writer.Close(); err != nil {
 		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
 	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/voice/process", &buf)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, &buf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -79,18 +204,27 @@ func (c *VoiceClient) ProcessVoice(ctx context.Context, wavData []byte) (*VoiceR
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	// Surface backpressure separately from other failures
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &BusyError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	// Keep whatever the sidecar transcribed before timing out
+	if resp.StatusCode == http.StatusGatewayTimeout {
+		var partial struct {
+			PartialTranscript string `json:"partial_transcript"`
+			UserID            string `json:"user_id"`
+		}
+		json.Unmarshal(respBody, &partial) // A bare 504 simply has no partial
+		return nil, &TimeoutError{PartialTranscript: partial.PartialTranscript, UserID: partial.UserID}
+	}
+
 	// Check for non-2xx status codes
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("Voice sidecar returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	// Parse response
-	var voiceResp VoiceResponse
-	if err := json.Unmarshal(respBody, &voiceResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	return &voiceResp, nil
+	return respBody, nil
 }
 
 // Health checks the health of the Voice sidecar
@@ -116,3 +250,16 @@ func (c *VoiceClient) Health(ctx context.Context) (time.Duration, error) {
 
 	return latency, nil
 }
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait
+		}
+	}
+	return defaultBusyRetryAfter
+}
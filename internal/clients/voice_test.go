@@ -3,12 +3,16 @@ package clients
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 )
 
+// testWAV is a minimal valid 16kHz mono PCM WAV header with no samples
+var testWAV = []byte("RIFF\x24\x00\x00\x00WAVEfmt \x10\x00\x00\x00\x01\x00\x01\x00\x80\x3e\x00\x00\x00\x7d\x00\x00\x02\x00\x10\x00data\x00\x00\x00\x00")
+
 func TestVoiceClient_ProcessVoice_Identified(t *testing.T) {
 	// Create mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -47,8 +51,7 @@ func TestVoiceClient_ProcessVoice_Identified(t *testing.T) {
 	client := NewVoiceClient(server.URL, 5*time.Second)
 
 	// Make request
-	wavData := []byte("fake wav data")
-	resp, err := client.ProcessVoice(context.Background(), wavData)
+	resp, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
 	if err != nil {
 		t.Fatalf("ProcessVoice failed: %v", err)
 	}
@@ -83,7 +86,7 @@ func TestVoiceClient_ProcessVoice_NoSpeech(t *testing.T) {
 	client := NewVoiceClient(server.URL, 5*time.Second)
 
 	// Make request
-	resp, err := client.ProcessVoice(context.Background(), []byte("fake wav"))
+	resp, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
 	if err != nil {
 		t.Fatalf("ProcessVoice failed: %v", err)
 	}
@@ -109,7 +112,7 @@ func TestVoiceClient_ProcessVoice_Rejected(t *testing.T) {
 	client := NewVoiceClient(server.URL, 5*time.Second)
 
 	// Make request
-	resp, err := client.ProcessVoice(context.Background(), []byte("fake wav"))
+	resp, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
 	if err != nil {
 		t.Fatalf("ProcessVoice failed: %v", err)
 	}
@@ -140,7 +143,7 @@ func TestVoiceClient_ProcessVoice_Fallback(t *testing.T) {
 	client := NewVoiceClient(server.URL, 5*time.Second)
 
 	// Make request
-	resp, err := client.ProcessVoice(context.Background(), []byte("fake wav"))
+	resp, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
 	if err != nil {
 		t.Fatalf("ProcessVoice failed: %v", err)
 	}
@@ -153,6 +156,31 @@ func TestVoiceClient_ProcessVoice_Fallback(t *testing.T) {
 	}
 }
 
+func TestVoiceClient_ProcessVoice_Candidates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "identified", "user_id": "mom", "confidence": 0.61, "transcript": "hi",
+			"candidates": [{"user_id": "mom", "confidence": 0.61}, {"user_id": "teen", "confidence": 0.58}]}`))
+	}))
+	defer server.Close()
+
+	client := NewVoiceClient(server.URL, 5*time.Second)
+	resp, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
+	if err != nil {
+		t.Fatalf("ProcessVoice failed: %v", err)
+	}
+
+	want := []SpeakerCandidate{{UserID: "mom", Confidence: 0.61}, {UserID: "teen", Confidence: 0.58}}
+	if len(resp.Candidates) != len(want) {
+		t.Fatalf("expected %d candidates, got %+v", len(want), resp.Candidates)
+	}
+	for i := range want {
+		if resp.Candidates[i] != want[i] {
+			t.Errorf("candidate %d: expected %+v, got %+v", i, want[i], resp.Candidates[i])
+		}
+	}
+}
+
 func TestVoiceClient_Health_Success(t *testing.T) {
 	// Create mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -176,3 +204,237 @@ func TestVoiceClient_Health_Success(t *testing.T) {
 		t.Error("expected positive latency")
 	}
 }
+
+func TestVoiceClient_ProcessVoice_InvalidWAV(t *testing.T) {
+	// Create mock server that must never be reached
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request for invalid WAV payload")
+	}))
+	defer server.Close()
+
+	// Create client
+	client := NewVoiceClient(server.URL, 5*time.Second)
+
+	tests := map[string][]byte{
+		"non-wav":   []byte("fake wav data"),
+		"truncated": testWAV[:20],
+		"empty":     {},
+	}
+
+	for name, data := range tests {
+		_, err := client.ProcessVoice(context.Background(), data, ProcessVoiceOptions{})
+		if !errors.Is(err, ErrInvalidWAV) {
+			t.Errorf("%s: expected ErrInvalidWAV, got %v", name, err)
+		}
+	}
+}
+
+func TestVoiceClient_ProcessVoice_SkipWAVValidation(t *testing.T) {
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(VoiceResponse{Status: "no_speech"})
+	}))
+	defer server.Close()
+
+	// Create client with validation bypassed
+	client := NewVoiceClient(server.URL, 5*time.Second)
+	client.skipWAVValidation = true
+
+	resp, err := client.ProcessVoice(context.Background(), []byte("fake wav data"), ProcessVoiceOptions{})
+	if err != nil {
+		t.Fatalf("ProcessVoice failed: %v", err)
+	}
+
+	if resp.Status != "no_speech" {
+		t.Errorf("expected status 'no_speech', got %s", resp.Status)
+	}
+}
+
+func TestVoiceClient_ProcessVoice_Busy(t *testing.T) {
+	// Create mock server that is overloaded
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// Create client
+	client := NewVoiceClient(server.URL, 5*time.Second)
+
+	_, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
+
+	var busyErr *BusyError
+	if !errors.As(err, &busyErr) {
+		t.Fatalf("expected BusyError, got %v", err)
+	}
+	if busyErr.RetryAfter != 7*time.Second {
+		t.Errorf("expected retry after 7s, got %v", busyErr.RetryAfter)
+	}
+}
+
+func TestVoiceClient_ProcessVoice_BusyWithoutRetryAfter(t *testing.T) {
+	// Create mock server that is overloaded
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// Create client
+	client := NewVoiceClient(server.URL, 5*time.Second)
+
+	_, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
+
+	var busyErr *BusyError
+	if !errors.As(err, &busyErr) {
+		t.Fatalf("expected BusyError, got %v", err)
+	}
+	if busyErr.RetryAfter != defaultBusyRetryAfter {
+		t.Errorf("expected default retry after, got %v", busyErr.RetryAfter)
+	}
+}
+
+func TestVoiceClient_ProcessVoice_TimeoutWithPartial(t *testing.T) {
+	// Create mock server that gives up mid-transcription
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGatewayTimeout)
+		w.Write([]byte(`{"error":"timeout","partial_transcript":"turn on the","user_id":"mom"}`))
+	}))
+	defer server.Close()
+
+	client := NewVoiceClient(server.URL, 5*time.Second)
+
+	_, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected TimeoutError, got %v", err)
+	}
+	if timeoutErr.PartialTranscript != "turn on the" || timeoutErr.UserID != "mom" {
+		t.Errorf("unexpected partial data: %+v", timeoutErr)
+	}
+}
+
+func TestVoiceClient_ProcessVoice_TimeoutWithoutPartial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer server.Close()
+
+	client := NewVoiceClient(server.URL, 5*time.Second)
+
+	_, err := client.ProcessVoice(context.Background(), testWAV, ProcessVoiceOptions{})
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected TimeoutError, got %v", err)
+	}
+	if timeoutErr.PartialTranscript != "" {
+		t.Errorf("expected no partial transcript, got %q", timeoutErr.PartialTranscript)
+	}
+}
+
+func TestVoiceClient_ProcessVoice_Options(t *testing.T) {
+	tests := []struct {
+		name              string
+		opts              ProcessVoiceOptions
+		wantMinConfidence string
+		wantEnroll        string
+	}{
+		{"zero value sends no fields", ProcessVoiceOptions{}, "", ""},
+		{"min confidence", ProcessVoiceOptions{MinConfidence: 0.75}, "0.75", ""},
+		{"enroll", ProcessVoiceOptions{Enroll: true}, "", "true"},
+		{"both", ProcessVoiceOptions{MinConfidence: 0.5, Enroll: true}, "0.5", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form map[string][]string
+
+			// Create mock server that records the form fields
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseMultipartForm(32 << 20); err != nil {
+					t.Fatalf("failed to parse multipart form: %v", err)
+				}
+				form = r.MultipartForm.Value
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(VoiceResponse{Status: "identified", UserID: "mom"})
+			}))
+			defer server.Close()
+
+			client := NewVoiceClient(server.URL, 5*time.Second)
+			if _, err := client.ProcessVoice(context.Background(), testWAV, tt.opts); err != nil {
+				t.Fatalf("ProcessVoice failed: %v", err)
+			}
+
+			if got := firstValue(form, "min_confidence"); got != tt.wantMinConfidence {
+				t.Errorf("expected min_confidence %q, got %q", tt.wantMinConfidence, got)
+			}
+			if got := firstValue(form, "enroll"); got != tt.wantEnroll {
+				t.Errorf("expected enroll %q, got %q", tt.wantEnroll, got)
+			}
+			if tt.opts == (ProcessVoiceOptions{}) && len(form) != 0 {
+				t.Errorf("expected no extra fields for zero options, got %v", form)
+			}
+		})
+	}
+}
+
+func firstValue(form map[string][]string, key string) string {
+	if values := form[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func TestVoiceClient_Enroll(t *testing.T) {
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/voice/enroll" {
+			t.Errorf("expected /voice/enroll, got %s", r.URL.Path)
+		}
+
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			t.Fatalf("failed to parse multipart form: %v", err)
+		}
+		if _, _, err := r.FormFile("file"); err != nil {
+			t.Fatalf("expected file in form: %v", err)
+		}
+		if got := r.FormValue("user_id"); got != "child" {
+			t.Errorf("expected user_id 'child', got %q", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(EnrollResponse{
+			Status:  "needs_more_samples",
+			UserID:  "child",
+			Samples: 1,
+		})
+	}))
+	defer server.Close()
+
+	client := NewVoiceClient(server.URL, 5*time.Second)
+
+	resp, err := client.Enroll(context.Background(), "child", testWAV)
+	if err != nil {
+		t.Fatalf("Enroll failed: %v", err)
+	}
+
+	if resp.Status != "needs_more_samples" {
+		t.Errorf("expected status 'needs_more_samples', got %s", resp.Status)
+	}
+	if resp.Samples != 1 {
+		t.Errorf("expected 1 sample, got %d", resp.Samples)
+	}
+}
+
+func TestVoiceClient_Enroll_InvalidWAV(t *testing.T) {
+	client := NewVoiceClient("http://127.0.0.1:1", 5*time.Second)
+
+	_, err := client.Enroll(context.Background(), "child", []byte("not a wav"))
+	if !errors.Is(err, ErrInvalidWAV) {
+		t.Errorf("expected ErrInvalidWAV, got %v", err)
+	}
+}
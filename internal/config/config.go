@@ -2,32 +2,380 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Run modes
+const (
+	ModeLive   = "live"    // Talk to the real sidecars (default)
+	ModeDryRun = "dry_run" // Use canned stub sidecars, for integration testing
+)
+
+// Content filter policies, chosen per user
+const (
+	FilterOff   = "off"   // Pass messages through unchanged (default)
+	FilterMask  = "mask"  // Replace filtered words with asterisks
+	FilterBlock = "block" // Reject messages containing filtered words
+)
+
 // Config holds the complete application configuration
 type Config struct {
-	Server        ServerConfig   `yaml:"server"`
-	Sidecars      SidecarConfig  `yaml:"sidecars"`
-	ValidUserIDs  []string       `yaml:"valid_user_ids"`
+	Mode               string                             `yaml:"mode"`
+	Server             ServerConfig                       `yaml:"server"`
+	Sidecars           SidecarConfig                      `yaml:"sidecars"`
+	Voice              VoiceConfig                        `yaml:"voice"`
+	Log                LogConfig                          `yaml:"log"`
+	Chat               ChatConfig                         `yaml:"chat"`
+	ChatCache          ChatCacheConfig                    `yaml:"chat_cache"`
+	Debug              DebugConfig                        `yaml:"debug"`
+	Warmup             WarmupConfig                       `yaml:"warmup"`
+	HealthWatch        HealthWatchConfig                  `yaml:"health_watch"`
+	LearningQueue      LearningQueueConfig                `yaml:"learning_queue"`
+	EvalLog            EvalLogConfig                      `yaml:"eval_log"`
+	ContentFilter      ContentFilterConfig                `yaml:"content_filter"`
+	ResponseTransforms map[string]ResponseTransformConfig `yaml:"response_transforms"` // User ID -> transforms applied to LLM replies
+	ValidUserIDs       []string                           `yaml:"valid_user_ids"`
+	DefaultUserID      string                             `yaml:"default_user_id"`  // Used when voice fallback carries no user
+	AssistantName      string                             `yaml:"assistant_name"`   // How the assistant refers to itself
+	ModelByUser        map[string]string                  `yaml:"model_by_user"`    // User ID -> LLM model, unset users get the sidecar's choice
+	DefaultLanguage    string                             `yaml:"default_language"` // Language the LLM answers in when none is detected or requested, empty leaves it to the sidecar
+	LanguageByUser     map[string]string                  `yaml:"language_by_user"` // User ID -> language, overrides default_language
+	QuietHours         map[string][]QuietWindow           `yaml:"quiet_hours"`      // User ID -> daily windows when chat and voice are refused
+	FallbackModels     []string                           `yaml:"fallback_models"`  // LLM models tried in order when the chosen one fails
+	AdminUserIDs       []string                           `yaml:"admin_user_ids"`   // Users allowed on /admin/ routes
+}
+
+// defaultAssistantName is used when assistant_name is unset
+const defaultAssistantName = "Jarvis"
+
+// GetAssistantName returns the configured assistant name, defaulting to "Jarvis"
+func (c *Config) GetAssistantName() string {
+	if name := strings.TrimSpace(c.AssistantName); name != "" {
+		return name
+	}
+	return defaultAssistantName
+}
+
+// GetModelForUser returns the LLM model configured for userID, or "" to let
+// the sidecar choose
+func (c *Config) GetModelForUser(userID string) string {
+	return c.ModelByUser[userID]
+}
+
+// GetLanguageForUser returns the language the LLM should answer userID in:
+// requested (from the chat request or the voice sidecar's detection) when
+// set, else the user's language_by_user entry, else default_language. An
+// empty result leaves the choice to the sidecar.
+func (c *Config) GetLanguageForUser(userID, requested string) string {
+	if requested = strings.TrimSpace(requested); requested != "" {
+		return requested
+	}
+	if language := c.LanguageByUser[userID]; language != "" {
+		return language
+	}
+	return strings.TrimSpace(c.DefaultLanguage)
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port                int `yaml:"port"`
-	ReadTimeoutSeconds  int `yaml:"read_timeout_seconds"`
-	WriteTimeoutSeconds int `yaml:"write_timeout_seconds"`
+	Port                     int              `yaml:"port"`
+	ReadTimeoutSeconds       int              `yaml:"read_timeout_seconds"`
+	WriteTimeoutSeconds      int              `yaml:"write_timeout_seconds"`
+	ReadHeaderTimeoutSeconds int              `yaml:"read_header_timeout_seconds"` // Deadline for request headers (default 10), guards against slow clients
+	IdleTimeoutSeconds       int              `yaml:"idle_timeout_seconds"`        // Keep-alive connections idle longer are closed (default 120)
+	RouteTimeouts            map[string]int   `yaml:"route_timeouts_seconds"`      // Path -> seconds, overrides write_timeout_seconds
+	MaxRequestTimeoutSeconds int              `yaml:"max_request_timeout_seconds"` // Cap on X-Timeout-Seconds, 0 caps at the route timeout
+	MaxBodyBytes             int64            `yaml:"max_body_bytes"`
+	RouteMaxBodyBytes        map[string]int64 `yaml:"route_max_body_bytes"` // Path -> bytes, overrides max_body_bytes
+	MaxHeaderBytes           int              `yaml:"max_header_bytes"`     // Cap on the request line and headers (default 64 KiB)
+	MaxHeaderCount           int              `yaml:"max_header_count"`     // Header lines allowed per request (default 100)
+	TLS                      TLSConfig        `yaml:"tls"`
+	TrustedProxies           []string         `yaml:"trusted_proxies"` // IPs or CIDRs whose X-Forwarded-For/X-Real-IP are believed
+	DisabledRoutes           []string         `yaml:"disabled_routes"` // Paths left unmounted, answering 404, e.g. /learn
+}
+
+// TLSConfig holds the certificate served over HTTPS. Both files unset
+// means plain HTTP.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled reports whether the server should serve HTTPS
+func (t *TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
 }
 
 // SidecarConfig holds URLs and timeouts for all sidecars
 type SidecarConfig struct {
-	VoiceURL       string `yaml:"voice_url"`
-	LLMURL         string `yaml:"llm_url"`
-	LearningURL    string `yaml:"learning_url"`
-	TimeoutSeconds int    `yaml:"timeout_seconds"`
+	VoiceURL        string            `yaml:"voice_url"`
+	LLMURL          URLList           `yaml:"llm_url"` // A single URL or a list of load-balanced instances
+	LearningURL     string            `yaml:"learning_url"`
+	TimeoutSeconds  int               `yaml:"timeout_seconds"`
+	HealthTimeoutMs int               `yaml:"health_timeout_ms"` // Deadline for each /health check (default 2000)
+	HealthRetries   int               `yaml:"health_retries"`    // Extra attempts, within the deadline, before a sidecar counts as down
+	LLMConcurrency  ConcurrencyConfig `yaml:"llm_concurrency"`
+	UserAgent       string            `yaml:"user_agent"` // Sent on every sidecar request (default orchestrator/<version>)
+	HTTPProxy       string            `yaml:"http_proxy"` // Proxy every sidecar request goes through, e.g. http://proxy:3128; empty connects directly
+	RetryAfter      RetryAfterConfig  `yaml:"retry_after_seconds"`
+}
+
+// RetryAfterConfig holds the Retry-After hint, in seconds, sent with 503s
+// when a sidecar is unavailable. 0 leaves the hint out.
+type RetryAfterConfig struct {
+	Voice    int `yaml:"voice"`
+	LLM      int `yaml:"llm"`
+	Learning int `yaml:"learning"`
+}
+
+// ConcurrencyConfig caps simultaneous in-flight calls to a sidecar
+type ConcurrencyConfig struct {
+	MaxInFlight    int `yaml:"max_in_flight"`    // 0 disables the limit
+	MaxQueued      int `yaml:"max_queued"`       // Calls allowed to wait for a slot, 0 fails fast
+	QueueTimeoutMs int `yaml:"queue_timeout_ms"` // Longest wait for a slot, 0 waits for the request deadline
+}
+
+// GetQueueTimeout returns the longest wait for a free slot as time.Duration
+func (c *ConcurrencyConfig) GetQueueTimeout() time.Duration {
+	return time.Duration(c.QueueTimeoutMs) * time.Millisecond
+}
+
+// VoiceConfig holds pre-flight checks applied to uploads before the voice sidecar
+type VoiceConfig struct {
+	MinDurationMs           int               `yaml:"min_duration_ms"`            // 0 disables the duration check
+	SilenceThreshold        float64           `yaml:"silence_threshold"`          // Peak level (0.0-1.0) below which audio is silent, 0 disables
+	IncludeTimings          bool              `yaml:"include_timings"`            // Report sidecar latencies in /voice responses
+	AllowedMIMETypes        []string          `yaml:"allowed_mime_types"`         // Declared upload types accepted by /voice (default WAV only)
+	TreatRejectedAsFallback bool              `yaml:"treat_rejected_as_fallback"` // Answer rejected speakers as "guest" instead of stopping
+	StatusAliases           map[string]string `yaml:"status_aliases"`             // Sidecar status -> identified, fallback, no_speech or rejected
+	ConfidenceDecimals      int               `yaml:"confidence_decimals"`        // Decimals kept on confidence values in responses (default 2), -1 keeps full precision
+}
+
+// VoiceStatuses are the voice sidecar statuses the orchestrator understands
+var VoiceStatuses = []string{"identified", "fallback", "no_speech", "rejected"}
+
+// isVoiceStatus reports whether status is one of VoiceStatuses
+func isVoiceStatus(status string) bool {
+	for _, known := range VoiceStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalStatus maps a voice sidecar status through status_aliases;
+// statuses without an alias are returned unchanged
+func (v *VoiceConfig) CanonicalStatus(status string) string {
+	if canonical, ok := v.StatusAliases[status]; ok {
+		return canonical
+	}
+	return status
+}
+
+// defaultAllowedMIMETypes are the /voice upload types accepted when none are
+// configured: the names WAV goes by, the only format the voice client accepts
+var defaultAllowedMIMETypes = []string{"audio/wav", "audio/x-wav", "audio/wave"}
+
+// GetAllowedMIMETypes returns the upload types /voice accepts
+func (v *VoiceConfig) GetAllowedMIMETypes() []string {
+	if len(v.AllowedMIMETypes) == 0 {
+		return defaultAllowedMIMETypes
+	}
+	return v.AllowedMIMETypes
+}
+
+// LogConfig holds structured logging settings
+type LogConfig struct {
+	Format string `yaml:"format"` // "json" (default) or "text"
+	Level  string `yaml:"level"`  // "debug", "info" (default), "warn" or "error"
+}
+
+// ChatConfig holds settings for /chat requests
+type ChatConfig struct {
+	MaxCandidates         int    `yaml:"max_candidates"`          // Cap on candidate replies per request (default 3)
+	MaxContextBytes       int    `yaml:"max_context_bytes"`       // Cap on the total size of context documents (default 64 KiB)
+	MaxHistoryTurns       int    `yaml:"max_history_turns"`       // Most recent history turns forwarded to the LLM, 0 forwards all
+	EmptyResponseFallback string `yaml:"empty_response_fallback"` // Sent instead of an empty LLM reply
+	SummarizeAfterTurns   int    `yaml:"summarize_after_turns"`   // Longer histories have their older turns summarized by the LLM, 0 never does
+	SummaryKeepTurns      int    `yaml:"summary_keep_turns"`      // Recent turns kept verbatim next to the summary (default half of summarize_after_turns)
+}
+
+// defaultEmptyResponseFallback replaces empty LLM replies unless configured otherwise
+const defaultEmptyResponseFallback = "Désolé, je n'ai pas de réponse pour le moment. Pouvez-vous reformuler ?"
+
+// GetEmptyResponseFallback returns the message sent instead of an empty LLM reply
+func (c *ChatConfig) GetEmptyResponseFallback() string {
+	if strings.TrimSpace(c.EmptyResponseFallback) == "" {
+		return defaultEmptyResponseFallback
+	}
+	return c.EmptyResponseFallback
+}
+
+// GetMaxContextBytes returns the cap on context document size, defaulting to 64 KiB
+func (c *ChatConfig) GetMaxContextBytes() int {
+	if c.MaxContextBytes <= 0 {
+		return 64 << 10
+	}
+	return c.MaxContextBytes
+}
+
+// GetSummaryKeepTurns returns how many recent turns survive summarization
+// verbatim, defaulting to half of summarize_after_turns
+func (c *ChatConfig) GetSummaryKeepTurns() int {
+	if c.SummaryKeepTurns <= 0 {
+		return c.SummarizeAfterTurns / 2
+	}
+	return c.SummaryKeepTurns
+}
+
+// GetMaxCandidates returns the cap on candidate replies, defaulting to 3
+func (c *ChatConfig) GetMaxCandidates() int {
+	if c.MaxCandidates <= 0 {
+		return 3
+	}
+	return c.MaxCandidates
+}
+
+// ChatCacheConfig holds settings for caching identical /chat replies per user
+type ChatCacheConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	TTLSeconds int  `yaml:"ttl_seconds"`
+	MaxEntries int  `yaml:"max_entries"`
+}
+
+// GetTTL returns the configured cache entry lifetime as time.Duration
+func (c *ChatCacheConfig) GetTTL() time.Duration {
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+// DebugConfig holds diagnostics settings
+type DebugConfig struct {
+	PprofEnabled bool `yaml:"pprof_enabled"` // Serve /debug/pprof/ to localhost clients
+	RawResponses bool `yaml:"raw_responses"` // Let /chat and /voice?debug=raw include the sidecars' raw responses
+}
+
+// WarmupConfig holds settings for pinging sidecars in the background at startup
+type WarmupConfig struct {
+	Enabled             bool `yaml:"enabled"`
+	IntervalSeconds     int  `yaml:"interval_seconds"`      // Pause between attempts (default 2)
+	MaxAttempts         int  `yaml:"max_attempts"`          // Attempts per sidecar before giving up (default 30)
+	Gate                bool `yaml:"gate"`                  // Answer /chat and /voice with 503 not_ready until warm-up is done
+	RequiredSidecars    int  `yaml:"required_sidecars"`     // Healthy sidecars needed to be ready (default all)
+	ReadyTimeoutSeconds int  `yaml:"ready_timeout_seconds"` // Stop waiting for them after this long (default: when attempts run out)
+}
+
+// GetInterval returns the pause between warm-up attempts as time.Duration
+func (w *WarmupConfig) GetInterval() time.Duration {
+	if w.IntervalSeconds <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(w.IntervalSeconds) * time.Second
+}
+
+// GetMaxAttempts returns the number of warm-up attempts per sidecar
+func (w *WarmupConfig) GetMaxAttempts() int {
+	if w.MaxAttempts <= 0 {
+		return 30
+	}
+	return w.MaxAttempts
+}
+
+// GetRequiredSidecars returns how many of total sidecars must answer healthy
+// before the orchestrator is ready
+func (w *WarmupConfig) GetRequiredSidecars(total int) int {
+	if w.RequiredSidecars <= 0 || w.RequiredSidecars > total {
+		return total
+	}
+	return w.RequiredSidecars
+}
+
+// GetReadyTimeout returns how long to wait for the required sidecars, or 0
+// to wait until every warm-up attempt is spent
+func (w *WarmupConfig) GetReadyTimeout() time.Duration {
+	return time.Duration(w.ReadyTimeoutSeconds) * time.Second
+}
+
+// normalizeSidecarURL turns a configured sidecar address into the base URL
+// clients append paths to: "localhost:8000" gains an http:// scheme and
+// trailing slashes are dropped, so paths never end up doubled or schemeless
+func normalizeSidecarURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%q: scheme must be http or https", raw)
+	}
+	if u.Host == "" || u.Hostname() == "" {
+		return "", fmt.Errorf("%q: missing host", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%q: must not have a query or fragment", raw)
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// GetHTTPProxy returns the parsed http_proxy, or nil when sidecars are
+// reached directly
+func (s *SidecarConfig) GetHTTPProxy() (*url.URL, error) {
+	raw := strings.TrimSpace(s.HTTPProxy)
+	if raw == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("%q: scheme must be http, https or socks5", raw)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%q: missing host", raw)
+	}
+	return u, nil
+}
+
+// URLList is a list of sidecar URLs that also accepts a single YAML string
+type URLList []string
+
+// UnmarshalYAML accepts either a scalar URL or a sequence of URLs
+func (u *URLList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var url string
+		if err := value.Decode(&url); err != nil {
+			return err
+		}
+		*u = URLList{url}
+		return nil
+	}
+
+	var urls []string
+	if err := value.Decode(&urls); err != nil {
+		return err
+	}
+	*u = urls
+	return nil
+}
+
+// String joins the URLs for logging
+func (u URLList) String() string {
+	return strings.Join(u, ",")
 }
 
 // GetReadTimeout returns the configured read timeout as time.Duration
@@ -35,17 +383,346 @@ func (s *ServerConfig) GetReadTimeout() time.Duration {
 	return time.Duration(s.ReadTimeoutSeconds) * time.Second
 }
 
+// GetReadHeaderTimeout returns how long a client may take to send request
+// headers, defaulting to 10 seconds
+func (s *ServerConfig) GetReadHeaderTimeout() time.Duration {
+	if s.ReadHeaderTimeoutSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(s.ReadHeaderTimeoutSeconds) * time.Second
+}
+
+// GetIdleTimeout returns how long an idle keep-alive connection stays open,
+// defaulting to 120 seconds
+func (s *ServerConfig) GetIdleTimeout() time.Duration {
+	if s.IdleTimeoutSeconds <= 0 {
+		return 120 * time.Second
+	}
+	return time.Duration(s.IdleTimeoutSeconds) * time.Second
+}
+
 // GetWriteTimeout returns the configured write timeout as time.Duration
 func (s *ServerConfig) GetWriteTimeout() time.Duration {
 	return time.Duration(s.WriteTimeoutSeconds) * time.Second
 }
 
+// GetRouteTimeout returns the handler timeout for a route, falling back to the write timeout
+func (s *ServerConfig) GetRouteTimeout(path string) time.Duration {
+	if seconds, ok := s.RouteTimeouts[path]; ok && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return s.GetWriteTimeout()
+}
+
+// GetMaxRequestTimeout returns the longest timeout a request on path may ask
+// for with X-Timeout-Seconds, defaulting to the route's own timeout
+func (s *ServerConfig) GetMaxRequestTimeout(path string) time.Duration {
+	if s.MaxRequestTimeoutSeconds > 0 {
+		return time.Duration(s.MaxRequestTimeoutSeconds) * time.Second
+	}
+	return s.GetRouteTimeout(path)
+}
+
+// GetMaxRouteTimeout returns the longest handler timeout across all routes,
+// including what requests may ask for with X-Timeout-Seconds
+func (s *ServerConfig) GetMaxRouteTimeout() time.Duration {
+	max := s.GetWriteTimeout()
+	for path := range s.RouteTimeouts {
+		if timeout := s.GetRouteTimeout(path); timeout > max {
+			max = timeout
+		}
+	}
+	if requested := time.Duration(s.MaxRequestTimeoutSeconds) * time.Second; requested > max {
+		max = requested
+	}
+	return max
+}
+
+// Request body limits applied when the config leaves them unset
+const (
+	defaultMaxBodyBytes      int64 = 1 << 20  // JSON endpoints
+	defaultVoiceMaxBodyBytes int64 = 32 << 20 // Multipart WAV and learning content uploads
+)
+
+// IsRouteEnabled reports whether path should be mounted. Paths are matched
+// exactly, so disabling /health leaves /health/llm alone.
+func (s *ServerConfig) IsRouteEnabled(path string) bool {
+	for _, disabled := range s.DisabledRoutes {
+		if disabled == path {
+			return false
+		}
+	}
+	return true
+}
+
+// GetRouteMaxBodyBytes returns the request body limit for a route, falling back
+// to max_body_bytes. Audio and learning upload routes keep a larger built-in default.
+func (s *ServerConfig) GetRouteMaxBodyBytes(path string) int64 {
+	if limit, ok := s.RouteMaxBodyBytes[path]; ok && limit > 0 {
+		return limit
+	}
+	if path == "/voice" || path == "/reidentify" || path == "/enroll" || path == "/learn/stream" {
+		return defaultVoiceMaxBodyBytes
+	}
+	if s.MaxBodyBytes > 0 {
+		return s.MaxBodyBytes
+	}
+	return defaultMaxBodyBytes
+}
+
+// Request header limits applied when the config leaves them unset
+const (
+	defaultMaxHeaderBytes = 64 << 10
+	defaultMaxHeaderCount = 100
+)
+
+// GetMaxHeaderBytes returns the cap on the size of request headers
+func (s *ServerConfig) GetMaxHeaderBytes() int {
+	if s.MaxHeaderBytes > 0 {
+		return s.MaxHeaderBytes
+	}
+	return defaultMaxHeaderBytes
+}
+
+// GetMaxHeaderCount returns how many header lines a request may carry
+func (s *ServerConfig) GetMaxHeaderCount() int {
+	if s.MaxHeaderCount > 0 {
+		return s.MaxHeaderCount
+	}
+	return defaultMaxHeaderCount
+}
+
+// GetHealthCheckTimeout returns the deadline for a single sidecar health check
+func (s *SidecarConfig) GetHealthCheckTimeout() time.Duration {
+	if s.HealthTimeoutMs <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(s.HealthTimeoutMs) * time.Millisecond
+}
+
 // GetSidecarTimeout returns the configured sidecar timeout as time.Duration
 func (s *SidecarConfig) GetSidecarTimeout() time.Duration {
 	return time.Duration(s.TimeoutSeconds) * time.Second
 }
 
-// Load reads and parses the configuration file
+// GetMinDuration returns the configured minimum recording length as time.Duration
+func (v *VoiceConfig) GetMinDuration() time.Duration {
+	return time.Duration(v.MinDurationMs) * time.Millisecond
+}
+
+// Confidence rounding applied when the config leaves it unset
+const (
+	defaultConfidenceDecimals = 2
+	maxConfidenceDecimals     = 15 // float64 carries no more meaningful digits
+)
+
+// GetConfidenceDecimals returns the number of decimals confidence values are
+// rounded to, or -1 to leave them at full precision
+func (v *VoiceConfig) GetConfidenceDecimals() int {
+	if v.ConfidenceDecimals == 0 {
+		return defaultConfidenceDecimals
+	}
+	return v.ConfidenceDecimals
+}
+
+// HealthWatchConfig holds settings for the background sidecar health watcher
+type HealthWatchConfig struct {
+	Enabled           bool                `yaml:"enabled"`
+	IntervalSeconds   int                 `yaml:"interval_seconds"`   // Pause between rounds of checks (default 10)
+	FailureThreshold  int                 `yaml:"failure_threshold"`  // Consecutive failures before a sidecar is unreachable (default 2)
+	RecoveryThreshold int                 `yaml:"recovery_threshold"` // Consecutive successes before it is ok again (default 1)
+	Webhook           HealthWebhookConfig `yaml:"webhook"`
+}
+
+// HealthWebhookConfig holds settings for the webhook told about sidecar
+// health transitions
+type HealthWebhookConfig struct {
+	URL             string `yaml:"url"`              // Receives a JSON POST per transition, empty disables
+	TimeoutMs       int    `yaml:"timeout_ms"`       // Deadline for each POST (default 2000)
+	DebounceSeconds int    `yaml:"debounce_seconds"` // Least time between two notifications about one sidecar (default 60)
+}
+
+// GetTimeout returns the deadline for each webhook POST as time.Duration
+func (w *HealthWebhookConfig) GetTimeout() time.Duration {
+	if w.TimeoutMs <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(w.TimeoutMs) * time.Millisecond
+}
+
+// GetDebounce returns the least time between two notifications about one
+// sidecar as time.Duration
+func (w *HealthWebhookConfig) GetDebounce() time.Duration {
+	if w.DebounceSeconds <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(w.DebounceSeconds) * time.Second
+}
+
+// GetInterval returns the pause between health watch rounds as time.Duration
+func (h *HealthWatchConfig) GetInterval() time.Duration {
+	if h.IntervalSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(h.IntervalSeconds) * time.Second
+}
+
+// GetFailureThreshold returns the consecutive failures needed to mark a sidecar unreachable
+func (h *HealthWatchConfig) GetFailureThreshold() int {
+	if h.FailureThreshold <= 0 {
+		return 2
+	}
+	return h.FailureThreshold
+}
+
+// GetRecoveryThreshold returns the consecutive successes needed to mark a sidecar ok again
+func (h *HealthWatchConfig) GetRecoveryThreshold() int {
+	if h.RecoveryThreshold <= 0 {
+		return 1
+	}
+	return h.RecoveryThreshold
+}
+
+// LearningQueueConfig holds settings for queuing learning submissions on disk
+// while the Learning sidecar is down
+type LearningQueueConfig struct {
+	Enabled              bool   `yaml:"enabled"`
+	Path                 string `yaml:"path"`                   // Queue file (default data/learning-queue.jsonl)
+	RetryIntervalSeconds int    `yaml:"retry_interval_seconds"` // Pause between attempts to drain the queue (default 30)
+}
+
+// GetPath returns the queue file path, defaulting to data/learning-queue.jsonl
+func (l *LearningQueueConfig) GetPath() string {
+	if l.Path == "" {
+		return "data/learning-queue.jsonl"
+	}
+	return l.Path
+}
+
+// GetRetryInterval returns the pause between queue drain attempts as time.Duration
+func (l *LearningQueueConfig) GetRetryInterval() time.Duration {
+	if l.RetryIntervalSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(l.RetryIntervalSeconds) * time.Second
+}
+
+// EvalLogConfig holds settings for sampling chat and voice interactions into
+// a JSONL file used to build evaluation datasets
+type EvalLogConfig struct {
+	Enabled           bool     `yaml:"enabled"`
+	Path              string   `yaml:"path"`                // Log file (default data/eval/interactions.jsonl)
+	SampleRate        float64  `yaml:"sample_rate"`         // Fraction of interactions logged, from 0 to 1
+	MaxFileBytes      int64    `yaml:"max_file_bytes"`      // The file is rotated past this size (default 10 MiB)
+	MaxFiles          int      `yaml:"max_files"`           // Rotated files kept besides the current one (default 5)
+	BufferSize        int      `yaml:"buffer_size"`         // Interactions waiting to be written before new ones are dropped (default 256)
+	ExcludeUsers      []string `yaml:"exclude_users"`       // Users whose interactions are never logged
+	MaskFilteredWords bool     `yaml:"mask_filtered_words"` // Mask content_filter words in logged text
+}
+
+// GetPath returns the eval log path, defaulting to data/eval/interactions.jsonl
+func (e *EvalLogConfig) GetPath() string {
+	if e.Path == "" {
+		return "data/eval/interactions.jsonl"
+	}
+	return e.Path
+}
+
+// GetMaxFileBytes returns the size past which the eval log is rotated,
+// defaulting to 10 MiB
+func (e *EvalLogConfig) GetMaxFileBytes() int64 {
+	if e.MaxFileBytes <= 0 {
+		return 10 << 20
+	}
+	return e.MaxFileBytes
+}
+
+// GetMaxFiles returns how many rotated eval logs are kept, defaulting to 5
+func (e *EvalLogConfig) GetMaxFiles() int {
+	if e.MaxFiles <= 0 {
+		return 5
+	}
+	return e.MaxFiles
+}
+
+// GetBufferSize returns how many interactions may wait to be written,
+// defaulting to 256
+func (e *EvalLogConfig) GetBufferSize() int {
+	if e.BufferSize <= 0 {
+		return 256
+	}
+	return e.BufferSize
+}
+
+// IsExcluded reports whether userID's interactions are kept out of the eval log
+func (e *EvalLogConfig) IsExcluded(userID string) bool {
+	for _, id := range e.ExcludeUsers {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentFilterConfig holds the word list checked against messages and
+// transcripts before they reach the LLM, and how each user is treated
+type ContentFilterConfig struct {
+	Words    []string          `yaml:"words"`
+	Policies map[string]string `yaml:"policies"` // User ID -> off, mask or block
+}
+
+// GetPolicy returns the filter policy for a user, defaulting to off
+func (c *ContentFilterConfig) GetPolicy(userID string) string {
+	if policy, ok := c.Policies[userID]; ok && policy != "" {
+		return policy
+	}
+	return FilterOff
+}
+
+// QuietWindow is a daily span of local time, "HH:MM" to "HH:MM". A window
+// whose end comes before its start runs past midnight.
+type QuietWindow struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Contains reports whether t's time of day falls in the window, start
+// included and end excluded. Unparseable windows contain nothing.
+func (q QuietWindow) Contains(t time.Time) bool {
+	start, err := parseTimeOfDay(q.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseTimeOfDay(q.End)
+	if err != nil {
+		return false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// parseTimeOfDay converts "HH:MM" into minutes after midnight
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ResponseTransformConfig lists the transforms applied to a user's LLM
+// replies, in the order below. The zero value leaves replies unchanged.
+type ResponseTransformConfig struct {
+	StripMarkdown bool   `yaml:"strip_markdown"` // Remove Markdown formatting, e.g. for text-to-speech
+	AppendSuffix  string `yaml:"append_suffix"`  // Text added to the end, e.g. a disclaimer
+}
+
+// Load reads and parses the configuration file, then applies ORCH_*
+// environment overrides before validating the result
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -57,6 +734,11 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Environment variables win over the file
+	if err := cfg.applyEnvOverrides(); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -71,25 +753,258 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	for path, seconds := range c.Server.RouteTimeouts {
+		if seconds < 0 {
+			return fmt.Errorf("invalid route timeout for %s: %d", path, seconds)
+		}
+	}
+
+	if c.Server.ReadHeaderTimeoutSeconds < 0 || c.Server.IdleTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid server timeouts: read_header_timeout_seconds and idle_timeout_seconds must not be negative")
+	}
+
+	if c.Server.MaxHeaderBytes < 0 || c.Server.MaxHeaderCount < 0 {
+		return fmt.Errorf("invalid header limits: max_header_bytes and max_header_count must not be negative")
+	}
+
+	if c.Server.MaxRequestTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid max_request_timeout_seconds: %d", c.Server.MaxRequestTimeoutSeconds)
+	}
+
+	if c.Server.MaxBodyBytes < 0 {
+		return fmt.Errorf("invalid max_body_bytes: %d", c.Server.MaxBodyBytes)
+	}
+
+	for _, path := range c.Server.DisabledRoutes {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("disabled_routes: %q must be a path starting with /", path)
+		}
+	}
+
+	for path, limit := range c.Server.RouteMaxBodyBytes {
+		if limit < 0 {
+			return fmt.Errorf("invalid body limit for %s: %d", path, limit)
+		}
+	}
+
+	if tls := c.Server.TLS; tls.Enabled() {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			return fmt.Errorf("server tls requires both cert_file and key_file")
+		}
+		for _, file := range []string{tls.CertFile, tls.KeyFile} {
+			if _, err := os.Stat(file); err != nil {
+				return fmt.Errorf("server tls: %w", err)
+			}
+		}
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy: %q (expected an IP or CIDR)", proxy)
+		}
+	}
+
+	if c.Mode != "" && c.Mode != ModeLive && c.Mode != ModeDryRun {
+		return fmt.Errorf("invalid mode: %q (expected %q or %q)", c.Mode, ModeLive, ModeDryRun)
+	}
+
 	if c.Sidecars.VoiceURL == "" {
 		return fmt.Errorf("voice_url is required")
 	}
+	voiceURL, err := normalizeSidecarURL(c.Sidecars.VoiceURL)
+	if err != nil {
+		return fmt.Errorf("invalid voice_url: %w", err)
+	}
+	c.Sidecars.VoiceURL = voiceURL
 
-	if c.Sidecars.LLMURL == "" {
+	if len(c.Sidecars.LLMURL) == 0 {
 		return fmt.Errorf("llm_url is required")
 	}
 
+	for i, raw := range c.Sidecars.LLMURL {
+		if raw == "" {
+			return fmt.Errorf("llm_url entries must not be empty")
+		}
+		llmURL, err := normalizeSidecarURL(raw)
+		if err != nil {
+			return fmt.Errorf("invalid llm_url: %w", err)
+		}
+		c.Sidecars.LLMURL[i] = llmURL
+	}
+
+	if _, err := c.Sidecars.GetHTTPProxy(); err != nil {
+		return fmt.Errorf("invalid http_proxy: %w", err)
+	}
+
+	if c.Sidecars.HealthRetries < 0 {
+		return fmt.Errorf("invalid sidecars health_retries: %d", c.Sidecars.HealthRetries)
+	}
+
+	if lc := c.Sidecars.LLMConcurrency; lc.MaxInFlight < 0 || lc.MaxQueued < 0 || lc.QueueTimeoutMs < 0 {
+		return fmt.Errorf("invalid llm_concurrency: values must not be negative")
+	}
+
 	if c.Sidecars.LearningURL == "" {
 		return fmt.Errorf("learning_url is required")
 	}
+	learningURL, err := normalizeSidecarURL(c.Sidecars.LearningURL)
+	if err != nil {
+		return fmt.Errorf("invalid learning_url: %w", err)
+	}
+	c.Sidecars.LearningURL = learningURL
+
+	if ra := c.Sidecars.RetryAfter; ra.Voice < 0 || ra.LLM < 0 || ra.Learning < 0 {
+		return fmt.Errorf("invalid retry_after_seconds: values must not be negative")
+	}
+
+	if c.Warmup.RequiredSidecars < 0 || c.Warmup.ReadyTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid warmup: required_sidecars and ready_timeout_seconds must not be negative")
+	}
+
+	if c.Voice.MinDurationMs < 0 {
+		return fmt.Errorf("invalid voice min_duration_ms: %d", c.Voice.MinDurationMs)
+	}
+
+	if c.Voice.SilenceThreshold < 0 || c.Voice.SilenceThreshold > 1 {
+		return fmt.Errorf("invalid voice silence_threshold: %v", c.Voice.SilenceThreshold)
+	}
+
+	if c.Voice.ConfidenceDecimals < -1 || c.Voice.ConfidenceDecimals > maxConfidenceDecimals {
+		return fmt.Errorf("invalid voice confidence_decimals %d: must be -1 or between 1 and %d", c.Voice.ConfidenceDecimals, maxConfidenceDecimals)
+	}
+
+	for alias, status := range c.Voice.StatusAliases {
+		if !isVoiceStatus(status) {
+			return fmt.Errorf("voice status_aliases: %q maps to unknown status %q (expected one of %s)", alias, status, strings.Join(VoiceStatuses, ", "))
+		}
+	}
+
+	if c.Chat.MaxHistoryTurns < 0 {
+		return fmt.Errorf("invalid chat max_history_turns: %d", c.Chat.MaxHistoryTurns)
+	}
+
+	if c.Chat.SummarizeAfterTurns < 0 || c.Chat.SummaryKeepTurns < 0 {
+		return fmt.Errorf("invalid chat summarize_after_turns or summary_keep_turns: values must not be negative")
+	}
+
+	if after := c.Chat.SummarizeAfterTurns; after > 0 {
+		keep := c.Chat.GetSummaryKeepTurns()
+		if keep >= after {
+			return fmt.Errorf("chat summary_keep_turns (%d) must be below summarize_after_turns (%d)", keep, after)
+		}
+		// The summary turn must survive history trimming
+		if max := c.Chat.MaxHistoryTurns; max > 0 && keep+1 > max {
+			return fmt.Errorf("chat summary_keep_turns (%d) plus the summary must fit in max_history_turns (%d)", keep, max)
+		}
+	}
+
+	if c.ChatCache.Enabled && (c.ChatCache.TTLSeconds <= 0 || c.ChatCache.MaxEntries <= 0) {
+		return fmt.Errorf("chat_cache requires positive ttl_seconds and max_entries")
+	}
 
 	if len(c.ValidUserIDs) == 0 {
 		return fmt.Errorf("at least one valid_user_id is required")
 	}
 
+	if raw := c.HealthWatch.Webhook.URL; raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid health_watch webhook url %q: must be an http:// or https:// URL", raw)
+		}
+	}
+	if c.HealthWatch.Webhook.TimeoutMs < 0 || c.HealthWatch.Webhook.DebounceSeconds < 0 {
+		return fmt.Errorf("invalid health_watch webhook: timeout_ms and debounce_seconds must not be negative")
+	}
+
+	if c.EvalLog.SampleRate < 0 || c.EvalLog.SampleRate > 1 {
+		return fmt.Errorf("invalid eval_log sample_rate: %v (expected 0 to 1)", c.EvalLog.SampleRate)
+	}
+	if c.EvalLog.MaxFileBytes < 0 || c.EvalLog.MaxFiles < 0 || c.EvalLog.BufferSize < 0 {
+		return fmt.Errorf("invalid eval_log: max_file_bytes, max_files and buffer_size must not be negative")
+	}
+	for _, id := range c.EvalLog.ExcludeUsers {
+		if !c.IsValidUserID(id) {
+			return fmt.Errorf("eval_log exclude_users: %q is not a valid_user_id", id)
+		}
+	}
+
+	for userID, policy := range c.ContentFilter.Policies {
+		if policy != FilterOff && policy != FilterMask && policy != FilterBlock {
+			return fmt.Errorf("invalid content filter policy for %s: %q (expected %q, %q or %q)", userID, policy, FilterOff, FilterMask, FilterBlock)
+		}
+	}
+
+	for userID, language := range c.LanguageByUser {
+		if !c.IsValidUserID(userID) {
+			return fmt.Errorf("language_by_user: %q is not a valid_user_id", userID)
+		}
+		if strings.TrimSpace(language) == "" {
+			return fmt.Errorf("language_by_user: empty language for %q", userID)
+		}
+	}
+
+	for userID, model := range c.ModelByUser {
+		if !c.IsValidUserID(userID) {
+			return fmt.Errorf("model_by_user: %q is not a valid_user_id", userID)
+		}
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("model_by_user: empty model for %s", userID)
+		}
+	}
+
+	for i, model := range c.FallbackModels {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("fallback_models: entry %d is empty", i)
+		}
+	}
+
+	for userID, windows := range c.QuietHours {
+		if !c.IsValidUserID(userID) {
+			return fmt.Errorf("quiet_hours: %q is not a valid_user_id", userID)
+		}
+		for _, window := range windows {
+			start, err := parseTimeOfDay(window.Start)
+			if err != nil {
+				return fmt.Errorf("quiet_hours for %s: %w", userID, err)
+			}
+			end, err := parseTimeOfDay(window.End)
+			if err != nil {
+				return fmt.Errorf("quiet_hours for %s: %w", userID, err)
+			}
+			if start == end {
+				return fmt.Errorf("quiet_hours for %s: window %s-%s is empty", userID, window.Start, window.End)
+			}
+		}
+	}
+
+	if c.DefaultUserID != "" && !c.IsValidUserID(c.DefaultUserID) {
+		return fmt.Errorf("default_user_id %q is not a valid_user_id", c.DefaultUserID)
+	}
+
+	for _, id := range c.AdminUserIDs {
+		if !c.IsValidUserID(id) {
+			return fmt.Errorf("admin_user_ids: %q is not a valid_user_id", id)
+		}
+	}
+
 	return nil
 }
 
+// IsDryRun reports whether the orchestrator should use stub sidecars
+func (c *Config) IsDryRun() bool {
+	return c.Mode == ModeDryRun
+}
+
+// IsAdmin reports whether userID is one of the admin user IDs
+func (c *Config) IsAdmin(userID string) bool {
+	for _, id := range c.AdminUserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // IsValidUserID checks if a user ID is in the list of valid user IDs
 func (c *Config) IsValidUserID(userID string) bool {
 	for _, id := range c.ValidUserIDs {
@@ -0,0 +1,568 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestURLList_Unmarshal(t *testing.T) {
+	tests := map[string]URLList{
+		`llm_url: "http://a:1"`:                    {"http://a:1"},
+		"llm_url:\n  - http://a:1\n  - http://b:2": {"http://a:1", "http://b:2"},
+	}
+
+	for input, want := range tests {
+		var sidecars SidecarConfig
+		if err := yaml.Unmarshal([]byte(input), &sidecars); err != nil {
+			t.Fatalf("failed to parse %q: %v", input, err)
+		}
+		if !reflect.DeepEqual(sidecars.LLMURL, want) {
+			t.Errorf("expected %v, got %v", want, sidecars.LLMURL)
+		}
+	}
+}
+
+func TestValidate_DefaultUserID(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.DefaultUserID = "child"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid default_user_id, got %v", err)
+	}
+
+	cfg.DefaultUserID = "grandma"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown default_user_id")
+	}
+}
+
+func TestValidate_ModelByUser(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.ModelByUser = map[string]string{"child": "llama3.2:3b"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid model_by_user, got %v", err)
+	}
+
+	cfg.ModelByUser = map[string]string{"grandma": "llama3.2:3b"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown user in model_by_user")
+	}
+
+	cfg.ModelByUser = map[string]string{"dad": " "}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for empty model in model_by_user")
+	}
+}
+
+func TestValidate_LanguageByUser(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.LanguageByUser = map[string]string{"child": "en"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid language_by_user, got %v", err)
+	}
+
+	cfg.LanguageByUser = map[string]string{"grandma": "it"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown user in language_by_user")
+	}
+
+	cfg.LanguageByUser = map[string]string{"dad": " "}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for empty language in language_by_user")
+	}
+}
+
+func TestGetLanguageForUser(t *testing.T) {
+	cfg := &Config{DefaultLanguage: "fr", LanguageByUser: map[string]string{"child": "en"}}
+
+	tests := []struct {
+		userID, requested, want string
+	}{
+		{"dad", "", "fr"},     // Household default
+		{"child", "", "en"},   // Per-user override
+		{"child", "de", "de"}, // Requested or detected language wins
+		{"dad", " ", "fr"},
+	}
+	for _, tt := range tests {
+		if got := cfg.GetLanguageForUser(tt.userID, tt.requested); got != tt.want {
+			t.Errorf("GetLanguageForUser(%q, %q) = %q, want %q", tt.userID, tt.requested, got, tt.want)
+		}
+	}
+
+	if got := (&Config{}).GetLanguageForUser("dad", ""); got != "" {
+		t.Errorf("expected no language without a default, got %q", got)
+	}
+}
+
+func TestValidate_VoiceStatusAliases(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.Voice.StatusAliases = map[string]string{"known": "identified", "unknown": "rejected"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid status_aliases, got %v", err)
+	}
+	if got := cfg.Voice.CanonicalStatus("known"); got != "identified" {
+		t.Errorf("expected known to map to identified, got %q", got)
+	}
+	if got := cfg.Voice.CanonicalStatus("fallback"); got != "fallback" {
+		t.Errorf("expected unaliased status unchanged, got %q", got)
+	}
+
+	cfg.Voice.StatusAliases = map[string]string{"known": "recognized"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for alias to an unknown status")
+	}
+}
+
+func TestValidate_AdminUserIDs(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.AdminUserIDs = []string{"dad"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid admin_user_ids, got %v", err)
+	}
+	if !cfg.IsAdmin("dad") || cfg.IsAdmin("child") {
+		t.Error("expected only dad to be an admin")
+	}
+
+	cfg.AdminUserIDs = []string{"grandma"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown user in admin_user_ids")
+	}
+}
+
+func TestValidate_FallbackModels(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.FallbackModels = []string{"llama3.2:3b", "phi3:mini"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid fallback_models, got %v", err)
+	}
+
+	cfg.FallbackModels = []string{"llama3.2:3b", ""}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for empty entry in fallback_models")
+	}
+}
+
+func TestValidate_ChatSummary(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.Chat = ChatConfig{SummarizeAfterTurns: 20, MaxHistoryTurns: 12}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid summary settings, got %v", err)
+	}
+	if keep := cfg.Chat.GetSummaryKeepTurns(); keep != 10 {
+		t.Errorf("expected summary_keep_turns to default to 10, got %d", keep)
+	}
+
+	cfg.Chat = ChatConfig{SummarizeAfterTurns: 10, SummaryKeepTurns: 10}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when summary_keep_turns is not below summarize_after_turns")
+	}
+
+	cfg.Chat = ChatConfig{SummarizeAfterTurns: 20, MaxHistoryTurns: 8}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when the summary would be trimmed by max_history_turns")
+	}
+}
+
+func TestValidate_DisabledRoutes(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.Server.DisabledRoutes = []string{"/learn"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid disabled_routes, got %v", err)
+	}
+	if cfg.Server.IsRouteEnabled("/learn") || !cfg.Server.IsRouteEnabled("/chat") {
+		t.Error("expected only /learn to be disabled")
+	}
+
+	cfg.Server.DisabledRoutes = []string{"learn"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for a disabled route without a leading /")
+	}
+}
+
+func TestValidate_EvalLog(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.EvalLog = EvalLogConfig{Enabled: true, SampleRate: 0.1, ExcludeUsers: []string{"child"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid eval_log, got %v", err)
+	}
+
+	cfg.EvalLog.SampleRate = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for a sample_rate above 1")
+	}
+
+	cfg.EvalLog = EvalLogConfig{ExcludeUsers: []string{"grandpa"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for an unknown user in exclude_users")
+	}
+}
+
+func TestValidate_HTTPProxy(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no proxy to be valid, got %v", err)
+	}
+
+	cfg.Sidecars.HTTPProxy = "http://proxy.internal:3128"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a valid http_proxy, got %v", err)
+	}
+
+	for _, proxy := range []string{"ftp://proxy.internal", "http://", "proxy.internal:3128"} {
+		cfg.Sidecars.HTTPProxy = proxy
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected error for http_proxy %q", proxy)
+		}
+	}
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.Server.TrustedProxies = []string{"10.0.0.1", "192.168.0.0/16", "::1"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid trusted proxies, got %v", err)
+	}
+
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/33"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for an invalid trusted proxy")
+	}
+}
+
+func TestValidate_NormalizesSidecarURLs(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{Port: 10080},
+		Sidecars: SidecarConfig{
+			VoiceURL:    "localhost:8000",
+			LLMURL:      URLList{"http://llm-a:8001/", "https://llm-b:8001/api//"},
+			LearningURL: "http://localhost:8002",
+		},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid sidecar URLs, got %v", err)
+	}
+	if cfg.Sidecars.VoiceURL != "http://localhost:8000" {
+		t.Errorf("expected a scheme on the voice URL, got %q", cfg.Sidecars.VoiceURL)
+	}
+	if want := (URLList{"http://llm-a:8001", "https://llm-b:8001/api"}); !reflect.DeepEqual(cfg.Sidecars.LLMURL, want) {
+		t.Errorf("expected trailing slashes stripped, got %v", cfg.Sidecars.LLMURL)
+	}
+	if cfg.Sidecars.LearningURL != "http://localhost:8002" {
+		t.Errorf("expected a valid URL unchanged, got %q", cfg.Sidecars.LearningURL)
+	}
+}
+
+func TestValidate_RejectsInvalidSidecarURLs(t *testing.T) {
+	tests := map[string]string{
+		"unsupported scheme": "ftp://localhost:8000",
+		"missing host":       "http://:8000",
+		"query string":       "http://localhost:8000?x=1",
+		"unparseable":        "http://local host:8000",
+	}
+
+	for name, voiceURL := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{
+				Server:       ServerConfig{Port: 10080},
+				Sidecars:     SidecarConfig{VoiceURL: voiceURL, LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+				ValidUserIDs: []string{"dad", "child"},
+			}
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("expected %q to be rejected", voiceURL)
+			}
+		})
+	}
+}
+
+func TestValidate_ContentFilterPolicies(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.ContentFilter.Policies = map[string]string{"child": FilterBlock, "dad": FilterOff}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid policies, got %v", err)
+	}
+	if got := cfg.ContentFilter.GetPolicy("teen"); got != FilterOff {
+		t.Errorf("expected unlisted users to default to %q, got %q", FilterOff, got)
+	}
+
+	cfg.ContentFilter.Policies = map[string]string{"child": "censor"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+// writeConfigFile writes a minimal valid config file and returns its path
+func writeConfigFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+server:
+  port: 10080
+sidecars:
+  voice_url: "http://localhost:10001"
+  llm_url: "http://localhost:10002"
+  learning_url: "http://localhost:10003"
+valid_user_ids: [dad, mom]
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	path := writeConfigFile(t)
+	t.Setenv("ORCH_SERVER_PORT", "9000")
+	t.Setenv("ORCH_VOICE_URL", "http://voice:10001")
+	t.Setenv("ORCH_LLM_URL", "http://llm-a:10002, http://llm-b:10002")
+	t.Setenv("ORCH_CHAT_CACHE_ENABLED", "false")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Server.Port != 9000 {
+		t.Errorf("expected port 9000 from env, got %d", cfg.Server.Port)
+	}
+	if cfg.Sidecars.VoiceURL != "http://voice:10001" {
+		t.Errorf("expected voice_url from env, got %q", cfg.Sidecars.VoiceURL)
+	}
+	if want := (URLList{"http://llm-a:10002", "http://llm-b:10002"}); !reflect.DeepEqual(cfg.Sidecars.LLMURL, want) {
+		t.Errorf("expected llm_url %v from env, got %v", want, cfg.Sidecars.LLMURL)
+	}
+	if cfg.Sidecars.LearningURL != "http://localhost:10003" {
+		t.Errorf("expected unset variables to keep the file value, got %q", cfg.Sidecars.LearningURL)
+	}
+}
+
+func TestLoad_EnvOverridesAreValidated(t *testing.T) {
+	tests := map[string]string{
+		"ORCH_SERVER_PORT":     "70000", // Parses, but fails validation
+		"ORCH_DEFAULT_USER_ID": "grandma",
+		"ORCH_WARMUP_ENABLED":  "maybe", // Does not parse
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t)
+			t.Setenv(name, value)
+
+			if _, err := Load(path); err == nil {
+				t.Errorf("expected %s=%s to be rejected", name, value)
+			}
+		})
+	}
+}
+
+func TestValidate_TLSFiles(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "cert.pem")
+	os.WriteFile(existing, []byte("placeholder"), 0o644)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr bool
+	}{
+		{"disabled", TLSConfig{}, false},
+		{"both files present", TLSConfig{CertFile: existing, KeyFile: existing}, false},
+		{"missing key file", TLSConfig{CertFile: existing, KeyFile: missing}, true},
+		{"missing cert file", TLSConfig{CertFile: missing, KeyFile: existing}, true},
+		{"key file unset", TLSConfig{CertFile: existing}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:       ServerConfig{Port: 10080, TLS: tt.tls},
+				Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+				ValidUserIDs: []string{"dad"},
+			}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestQuietWindow_Contains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name   string
+		window QuietWindow
+		t      time.Time
+		want   bool
+	}{
+		{"inside same-day window", QuietWindow{Start: "13:00", End: "15:00"}, at(14, 0), true},
+		{"start is included", QuietWindow{Start: "13:00", End: "15:00"}, at(13, 0), true},
+		{"end is excluded", QuietWindow{Start: "13:00", End: "15:00"}, at(15, 0), false},
+		{"late evening in overnight window", QuietWindow{Start: "21:00", End: "07:00"}, at(23, 30), true},
+		{"early morning in overnight window", QuietWindow{Start: "21:00", End: "07:00"}, at(6, 59), true},
+		{"afternoon outside overnight window", QuietWindow{Start: "21:00", End: "07:00"}, at(12, 0), false},
+		{"unparseable window", QuietWindow{Start: "9pm", End: "07:00"}, at(23, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.t); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidate_QuietHours(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.QuietHours = map[string][]QuietWindow{"child": {{Start: "20:30", End: "07:00"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid quiet hours, got %v", err)
+	}
+
+	cfg.QuietHours = map[string][]QuietWindow{"stranger": {{Start: "20:30", End: "07:00"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown user in quiet_hours")
+	}
+
+	cfg.QuietHours = map[string][]QuietWindow{"child": {{Start: "25:00", End: "07:00"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for an invalid time of day")
+	}
+
+	cfg.QuietHours = map[string][]QuietWindow{"child": {{Start: "07:00", End: "07:00"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for an empty window")
+	}
+}
+
+func TestValidate_ConfidenceDecimals(t *testing.T) {
+	tests := []struct {
+		decimals int
+		wantErr  bool
+	}{
+		{decimals: 0},
+		{decimals: 4},
+		{decimals: -1},
+		{decimals: -2, wantErr: true},
+		{decimals: 16, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{
+			Server:       ServerConfig{Port: 10080},
+			Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+			ValidUserIDs: []string{"dad", "child"},
+			Voice:        VoiceConfig{ConfidenceDecimals: tt.decimals},
+		}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("confidence_decimals %d: expected error %v, got %v", tt.decimals, tt.wantErr, err)
+		}
+	}
+
+	if got := (&VoiceConfig{}).GetConfidenceDecimals(); got != 2 {
+		t.Errorf("expected 2 decimals by default, got %d", got)
+	}
+}
+
+func TestValidate_HealthWebhook(t *testing.T) {
+	cfg := &Config{
+		Server:       ServerConfig{Port: 10080},
+		Sidecars:     SidecarConfig{VoiceURL: "http://v", LLMURL: URLList{"http://l"}, LearningURL: "http://le"},
+		ValidUserIDs: []string{"dad", "child"},
+	}
+
+	cfg.HealthWatch.Webhook.URL = "https://alerts.internal/hooks/orchestrator"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a valid webhook url, got %v", err)
+	}
+
+	for _, webhookURL := range []string{"alerts.internal/hook", "ftp://alerts.internal", "http://"} {
+		cfg.HealthWatch.Webhook.URL = webhookURL
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected error for webhook url %q", webhookURL)
+		}
+	}
+
+	cfg.HealthWatch.Webhook.URL = ""
+	cfg.HealthWatch.Webhook.DebounceSeconds = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for a negative debounce_seconds")
+	}
+
+	webhook := HealthWebhookConfig{}
+	if webhook.GetTimeout() != 2*time.Second || webhook.GetDebounce() != time.Minute {
+		t.Errorf("unexpected defaults: timeout %v, debounce %v", webhook.GetTimeout(), webhook.GetDebounce())
+	}
+}
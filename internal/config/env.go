@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment variable overriding a
+// config file value, e.g. ORCH_SERVER_PORT for server.port
+const EnvPrefix = "ORCH_"
+
+// envOverride sets one config field from an environment variable
+type envOverride struct {
+	name  string // Variable name without EnvPrefix
+	apply func(cfg *Config, value string) error
+}
+
+// envOverrides lists every supported variable. List values are comma-separated.
+var envOverrides = []envOverride{
+	{"MODE", setString(func(c *Config) *string { return &c.Mode })},
+	{"SERVER_PORT", setInt(func(c *Config) *int { return &c.Server.Port })},
+	{"SERVER_READ_TIMEOUT_SECONDS", setInt(func(c *Config) *int { return &c.Server.ReadTimeoutSeconds })},
+	{"SERVER_WRITE_TIMEOUT_SECONDS", setInt(func(c *Config) *int { return &c.Server.WriteTimeoutSeconds })},
+	{"SERVER_READ_HEADER_TIMEOUT_SECONDS", setInt(func(c *Config) *int { return &c.Server.ReadHeaderTimeoutSeconds })},
+	{"SERVER_IDLE_TIMEOUT_SECONDS", setInt(func(c *Config) *int { return &c.Server.IdleTimeoutSeconds })},
+	{"SERVER_MAX_BODY_BYTES", setInt64(func(c *Config) *int64 { return &c.Server.MaxBodyBytes })},
+	{"SERVER_MAX_HEADER_BYTES", setInt(func(c *Config) *int { return &c.Server.MaxHeaderBytes })},
+	{"SERVER_MAX_HEADER_COUNT", setInt(func(c *Config) *int { return &c.Server.MaxHeaderCount })},
+	{"SERVER_TLS_CERT_FILE", setString(func(c *Config) *string { return &c.Server.TLS.CertFile })},
+	{"SERVER_TLS_KEY_FILE", setString(func(c *Config) *string { return &c.Server.TLS.KeyFile })},
+	{"SERVER_TRUSTED_PROXIES", func(c *Config, v string) error {
+		c.Server.TrustedProxies = splitList(v)
+		return nil
+	}},
+	{"VOICE_URL", setString(func(c *Config) *string { return &c.Sidecars.VoiceURL })},
+	{"LLM_URL", func(c *Config, v string) error {
+		c.Sidecars.LLMURL = URLList(splitList(v))
+		return nil
+	}},
+	{"LEARNING_URL", setString(func(c *Config) *string { return &c.Sidecars.LearningURL })},
+	{"SIDECAR_TIMEOUT_SECONDS", setInt(func(c *Config) *int { return &c.Sidecars.TimeoutSeconds })},
+	{"HEALTH_TIMEOUT_MS", setInt(func(c *Config) *int { return &c.Sidecars.HealthTimeoutMs })},
+	{"USER_AGENT", setString(func(c *Config) *string { return &c.Sidecars.UserAgent })},
+	{"HTTP_PROXY", setString(func(c *Config) *string { return &c.Sidecars.HTTPProxy })},
+	{"LOG_FORMAT", setString(func(c *Config) *string { return &c.Log.Format })},
+	{"LOG_LEVEL", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"CHAT_CACHE_ENABLED", setBool(func(c *Config) *bool { return &c.ChatCache.Enabled })},
+	{"WARMUP_ENABLED", setBool(func(c *Config) *bool { return &c.Warmup.Enabled })},
+	{"HEALTH_WATCH_ENABLED", setBool(func(c *Config) *bool { return &c.HealthWatch.Enabled })},
+	{"HEALTH_WEBHOOK_URL", setString(func(c *Config) *string { return &c.HealthWatch.Webhook.URL })},
+	{"LEARNING_QUEUE_ENABLED", setBool(func(c *Config) *bool { return &c.LearningQueue.Enabled })},
+	{"LEARNING_QUEUE_PATH", setString(func(c *Config) *string { return &c.LearningQueue.Path })},
+	{"PPROF_ENABLED", setBool(func(c *Config) *bool { return &c.Debug.PprofEnabled })},
+	{"VALID_USER_IDS", func(c *Config, v string) error {
+		c.ValidUserIDs = splitList(v)
+		return nil
+	}},
+	{"DEFAULT_USER_ID", setString(func(c *Config) *string { return &c.DefaultUserID })},
+	{"ASSISTANT_NAME", setString(func(c *Config) *string { return &c.AssistantName })},
+}
+
+// applyEnvOverrides replaces config file values with those set in the
+// environment. Unset and empty variables leave the file value alone.
+func (c *Config) applyEnvOverrides() error {
+	for _, o := range envOverrides {
+		value := strings.TrimSpace(os.Getenv(EnvPrefix + o.name))
+		if value == "" {
+			continue
+		}
+		if err := o.apply(c, value); err != nil {
+			return fmt.Errorf("invalid %s%s: %w", EnvPrefix, o.name, err)
+		}
+	}
+	return nil
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setInt64(field func(*Config) *int64) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", v)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+// splitList parses a comma-separated list, dropping empty entries
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
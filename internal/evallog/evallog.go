@@ -0,0 +1,195 @@
+// Package evallog samples LLM interactions into a rotating JSONL file, one
+// interaction per line, for building evaluation datasets.
+package evallog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Interaction is one logged exchange with the LLM
+type Interaction struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"` // "chat" or "voice"
+	UserID    string    `json:"user_id"`
+	Model     string    `json:"model"`
+	Prompt    string    `json:"prompt"`
+	Response  string    `json:"response"`
+	MessageID string    `json:"message_id,omitempty"`
+}
+
+// Options tune a Sampler
+type Options struct {
+	SampleRate   float64 // Fraction of interactions kept, from 0 to 1
+	MaxFileBytes int64   // The file is rotated before it grows past this size
+	MaxFiles     int     // Rotated files kept, as path.1 (newest) to path.N
+	BufferSize   int     // Interactions waiting to be written before new ones are dropped
+}
+
+// Sampler keeps a random fraction of interactions and appends them to a file
+// from a background goroutine, so recording never blocks a request. When the
+// writer falls behind, interactions are dropped rather than queued without bound.
+type Sampler struct {
+	path   string
+	opts   Options
+	logger *slog.Logger
+	sample func() float64 // Returns a number in [0, 1), replaced in tests
+
+	mu      sync.RWMutex // Guards closed against Record racing Close
+	closed  bool
+	records chan Interaction
+	done    chan struct{}
+	dropped int64
+}
+
+// New starts a sampler writing to path, creating its directory if needed
+func New(path string, opts Options, logger *slog.Logger) (*Sampler, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create eval log directory: %w", err)
+	}
+
+	s := &Sampler{
+		path:    path,
+		opts:    opts,
+		logger:  logger,
+		sample:  rand.Float64,
+		records: make(chan Interaction, opts.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Record samples an interaction and, if it is kept, queues it for writing.
+// It reports whether the interaction was queued.
+func (s *Sampler) Record(interaction Interaction) bool {
+	if s.sample() >= s.opts.SampleRate {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+
+	select {
+	case s.records <- interaction:
+		return true
+	default:
+		if n := atomic.AddInt64(&s.dropped, 1); n == 1 || n%100 == 0 {
+			s.logger.Warn("eval log buffer full, dropping interactions", "dropped", n)
+		}
+		return false
+	}
+}
+
+// Close stops accepting interactions and returns once the queued ones are written
+func (s *Sampler) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+// run writes queued interactions until Close
+func (s *Sampler) run() {
+	defer close(s.done)
+
+	w := &rotatingFile{path: s.path, maxBytes: s.opts.MaxFileBytes, maxFiles: s.opts.MaxFiles}
+	defer w.close()
+
+	for interaction := range s.records {
+		line, err := json.Marshal(interaction)
+		if err != nil {
+			s.logger.Error("failed to encode eval interaction", "error", err)
+			continue
+		}
+		if err := w.writeLine(line); err != nil {
+			s.logger.Error("failed to write eval log", "path", s.path, "error", err)
+		}
+	}
+}
+
+// rotatingFile appends lines to path, moving it to path.1 (and older files
+// one step up, up to path.maxFiles) before a line would take it past maxBytes
+type rotatingFile struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	f    *os.File
+	size int64
+}
+
+// writeLine appends line and a newline, rotating first if needed
+func (r *rotatingFile) writeLine(line []byte) error {
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+
+	line = append(line, '\n')
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(line)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.f.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// open opens path for appending and picks up its current size
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open eval log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat eval log: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files up, dropping the oldest, and starts a new file
+func (r *rotatingFile) rotate() error {
+	r.close()
+
+	if r.maxFiles > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+		for i := r.maxFiles - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate eval log: %w", err)
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return fmt.Errorf("failed to rotate eval log: %w", err)
+	}
+
+	return r.open()
+}
+
+// close closes the current file, if open
+func (r *rotatingFile) close() {
+	if r.f != nil {
+		r.f.Close()
+		r.f = nil
+	}
+}
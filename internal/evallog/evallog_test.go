@@ -0,0 +1,153 @@
+package evallog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// readLines decodes every interaction in a JSONL file
+func readLines(t *testing.T, path string) []Interaction {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			t.Fatalf("line %q is not a JSON interaction: %v", scanner.Text(), err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions
+}
+
+func TestSampler_SampleRate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eval.jsonl")
+	s, err := New(path, Options{SampleRate: 0.25, BufferSize: 100}, discardLogger)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// Spread the draws evenly over [0, 1)
+	draw := 0
+	s.sample = func() float64 {
+		draw++
+		return float64(draw-1) / 100
+	}
+
+	kept := 0
+	for i := 0; i < 100; i++ {
+		if s.Record(Interaction{UserID: "dad", Prompt: fmt.Sprintf("prompt %d", i)}) {
+			kept++
+		}
+	}
+	s.Close()
+
+	if kept != 25 {
+		t.Errorf("expected 25 of 100 interactions kept at rate 0.25, got %d", kept)
+	}
+	if lines := readLines(t, path); len(lines) != 25 {
+		t.Errorf("expected 25 lines in the log, got %d", len(lines))
+	}
+}
+
+func TestSampler_FileFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eval", "interactions.jsonl")
+	s, err := New(path, Options{SampleRate: 1, BufferSize: 10}, discardLogger)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	when := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.Record(Interaction{Time: when, Source: "chat", UserID: "mom", Model: "llama3.2:3b", Prompt: "hi", Response: "hello", MessageID: "msg_1"})
+	s.Record(Interaction{Time: when, Source: "voice", UserID: "teen", Model: "phi3:mini", Prompt: "weather?", Response: "sunny"})
+	s.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	want := `{"time":"2026-03-01T12:00:00Z","source":"chat","user_id":"mom","model":"llama3.2:3b","prompt":"hi","response":"hello","message_id":"msg_1"}
+{"time":"2026-03-01T12:00:00Z","source":"voice","user_id":"teen","model":"phi3:mini","prompt":"weather?","response":"sunny"}
+`
+	if string(data) != want {
+		t.Errorf("unexpected log contents:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestSampler_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eval.jsonl")
+	s, err := New(path, Options{SampleRate: 1, MaxFileBytes: 200, MaxFiles: 2, BufferSize: 100}, discardLogger)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		s.Record(Interaction{UserID: "dad", Prompt: fmt.Sprintf("prompt %d", i), Response: "ok"})
+	}
+	s.Close()
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", name, err)
+		}
+		if info.Size() > 200 {
+			t.Errorf("expected %s to stay within 200 bytes, got %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated files to be kept, got err %v", err)
+	}
+
+	// The newest interaction is last in the current file
+	lines := readLines(t, path)
+	if last := lines[len(lines)-1]; last.Prompt != "prompt 19" {
+		t.Errorf("expected the newest interaction last, got %q", last.Prompt)
+	}
+}
+
+func TestSampler_RecordNeverBlocks(t *testing.T) {
+	// No writer goroutine drains this sampler, so its buffer stays full
+	s := &Sampler{
+		opts:    Options{SampleRate: 1},
+		logger:  discardLogger,
+		sample:  func() float64 { return 0 },
+		records: make(chan Interaction, 1),
+	}
+
+	if !s.Record(Interaction{UserID: "dad"}) {
+		t.Fatal("expected the first interaction to be queued")
+	}
+	if s.Record(Interaction{UserID: "dad"}) {
+		t.Error("expected an interaction to be dropped while the buffer is full")
+	}
+	if s.dropped != 1 {
+		t.Errorf("expected 1 dropped interaction, got %d", s.dropped)
+	}
+}
+
+func TestSampler_RecordAfterClose(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "eval.jsonl"), Options{SampleRate: 1, BufferSize: 1}, discardLogger)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	s.Close()
+
+	if s.Record(Interaction{UserID: "dad"}) {
+		t.Error("expected a closed sampler to refuse interactions")
+	}
+}
@@ -0,0 +1,72 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WordFilter finds configured words in text, matching whole words
+// case-insensitively so "class" is not caught by "ass"
+type WordFilter struct {
+	words map[string]struct{}
+}
+
+// NewWordFilter creates a filter for the given word list. Blank entries are ignored.
+func NewWordFilter(words []string) *WordFilter {
+	f := &WordFilter{words: make(map[string]struct{}, len(words))}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			f.words[word] = struct{}{}
+		}
+	}
+	return f
+}
+
+// Matches returns the filtered words found in text, in order of appearance
+func (f *WordFilter) Matches(text string) []string {
+	var matches []string
+	f.scan(text, func(start, end int) {
+		matches = append(matches, text[start:end])
+	})
+	return matches
+}
+
+// Mask replaces every filtered word in text with asterisks, reporting whether
+// anything was replaced
+func (f *WordFilter) Mask(text string) (string, bool) {
+	var b strings.Builder
+	last := 0
+	f.scan(text, func(start, end int) {
+		b.WriteString(text[last:start])
+		b.WriteString(strings.Repeat("*", len([]rune(text[start:end]))))
+		last = end
+	})
+	if last == 0 {
+		return text, false
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
+}
+
+// scan calls match with the byte range of each filtered word in text
+func (f *WordFilter) scan(text string, match func(start, end int)) {
+	if len(f.words) == 0 {
+		return
+	}
+
+	start := -1
+	for i, r := range text + " " {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if _, ok := f.words[strings.ToLower(text[start:i])]; ok {
+				match(start, i)
+			}
+			start = -1
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordFilter_Matches(t *testing.T) {
+	f := NewWordFilter([]string{"darn", " Heck ", ""})
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"what the heck is that", []string{"heck"}},
+		{"DARN it, heck!", []string{"DARN", "heck"}},
+		{"darning socks is fine", nil},
+		{"nothing to see here", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := f.Matches(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Matches(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWordFilter_Mask(t *testing.T) {
+	f := NewWordFilter([]string{"darn", "zut"})
+
+	tests := []struct {
+		text       string
+		want       string
+		wantMasked bool
+	}{
+		{"darn it", "**** it", true},
+		{"oh ZUT, darn!", "oh ***, ****!", true},
+		{"zutalors stays", "zutalors stays", false},
+		{"all good", "all good", false},
+	}
+
+	for _, tt := range tests {
+		got, masked := f.Mask(tt.text)
+		if got != tt.want || masked != tt.wantMasked {
+			t.Errorf("Mask(%q) = %q, %v; want %q, %v", tt.text, got, masked, tt.want, tt.wantMasked)
+		}
+	}
+}
+
+func TestWordFilter_Accents(t *testing.T) {
+	f := NewWordFilter([]string{"crétin"})
+
+	got, masked := f.Mask("quel Crétin!")
+	if !masked || got != "quel ******!" {
+		t.Errorf("expected accented word to be masked by rune count, got %q", got)
+	}
+}
+
+func TestWordFilter_Empty(t *testing.T) {
+	f := NewWordFilter(nil)
+
+	if got := f.Matches("anything goes"); got != nil {
+		t.Errorf("expected no matches, got %v", got)
+	}
+}
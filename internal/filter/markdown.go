@@ -0,0 +1,37 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdFence      = regexp.MustCompile("(?m)^\\s*```.*$\\n?")
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdHeading    = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	mdQuote      = regexp.MustCompile(`(?m)^\s*>\s?`)
+	mdBullet     = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	mdRule       = regexp.MustCompile(`(?m)^\s*([-*_])(\s*[-*_]){2,}\s*$\n?`)
+	mdBold       = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdItalicStar = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	mdItalicBar  = regexp.MustCompile(`\b_(\S(?:[^_]*?\S)?)_\b`)
+	mdCode       = regexp.MustCompile("`([^`]*)`")
+)
+
+// StripMarkdown removes Markdown formatting, keeping the readable text, so
+// replies can be read aloud without "asterisk" or "hash" being spoken
+func StripMarkdown(text string) string {
+	text = mdFence.ReplaceAllString(text, "")
+	text = mdRule.ReplaceAllString(text, "")
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllString(text, "$1")
+	text = mdHeading.ReplaceAllString(text, "")
+	text = mdQuote.ReplaceAllString(text, "")
+	text = mdBullet.ReplaceAllString(text, "$1")
+	text = mdBold.ReplaceAllString(text, "$2")
+	text = mdItalicStar.ReplaceAllString(text, "$1")
+	text = mdItalicBar.ReplaceAllString(text, "$1")
+	text = mdCode.ReplaceAllString(text, "$1")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,26 @@
+package filter
+
+import "testing"
+
+func TestStripMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"plain text stays":                    "plain text stays",
+		"# Title\nBody":                       "Title\nBody",
+		"This is **bold** and *italic*":       "This is bold and italic",
+		"__also bold__ and _also italic_":     "also bold and also italic",
+		"See [the docs](https://example.com)": "See the docs",
+		"![a cat](cat.png)":                   "a cat",
+		"- one\n- two\n* three":               "one\ntwo\nthree",
+		"> quoted":                            "quoted",
+		"Run `go test` now":                   "Run go test now",
+		"```go\nfmt.Println(1)\n```":          "fmt.Println(1)",
+		"above\n---\nbelow":                   "above\nbelow",
+		"2 * 3 = 6 and snake_case_name stay":  "2 * 3 = 6 and snake_case_name stay",
+	}
+
+	for input, want := range tests {
+		if got := StripMarkdown(input); got != want {
+			t.Errorf("StripMarkdown(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
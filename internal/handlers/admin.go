@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// RequireAdmin lets through only requests whose X-User-ID header names one
+// of the configured admin users; everyone else gets 403. The header is a
+// claim, not a credential: any caller can send it. It only picks out which
+// household member acts, so admin routes must also be limited to trusted
+// clients, which the server does by serving them to localhost alone.
+func RequireAdmin(cfg *config.Config, logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get(userIDHeader)
+		if !cfg.IsValidUserID(userID) || !cfg.IsAdmin(userID) {
+			logger.Warn("admin route refused", "path", r.URL.Path, "user_id", userID)
+			writeErrorCode(w, http.StatusForbidden, "admin_required", "admin user required",
+				"name an admin user in the "+userIDHeader+" header")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CacheClearer empties a cache and reports how many entries it held
+type CacheClearer interface {
+	ClearCache() int
+}
+
+// ClearCacheHandler handles POST /admin/cache/clear requests
+type ClearCacheHandler struct {
+	cache  CacheClearer
+	logger *slog.Logger
+}
+
+// NewClearCacheHandler creates a handler that empties the chat reply cache
+func NewClearCacheHandler(cache CacheClearer, logger *slog.Logger) *ClearCacheHandler {
+	return &ClearCacheHandler{cache: cache, logger: logger}
+}
+
+// ServeHTTP implements http.Handler
+func (h *ClearCacheHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+		return
+	}
+
+	cleared := h.cache.ClearCache()
+	h.logger.Info("chat cache cleared", "user_id", r.Header.Get(userIDHeader), "entries", cleared)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int{"cleared": cleared})
+}
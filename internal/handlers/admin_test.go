@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// countingClearer counts ClearCache calls
+type countingClearer struct {
+	calls int
+}
+
+func (c *countingClearer) ClearCache() int {
+	c.calls++
+	return 3
+}
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		wantCode  int
+		wantClear bool
+	}{
+		{"admin", "dad", http.StatusOK, true},
+		{"regular user", "child", http.StatusForbidden, false},
+		{"unknown user", "grandma", http.StatusForbidden, false},
+		{"no user", "", http.StatusForbidden, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+				AdminUserIDs: []string{"dad", "grandma"},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			clearer := &countingClearer{}
+			handler := RequireAdmin(cfg, logger, NewClearCacheHandler(clearer, logger))
+
+			req := httptest.NewRequest("POST", "/admin/cache/clear", nil)
+			if tt.userID != "" {
+				req.Header.Set(userIDHeader, tt.userID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if (clearer.calls == 1) != tt.wantClear {
+				t.Errorf("expected cache cleared=%v, got %d calls", tt.wantClear, clearer.calls)
+			}
+
+			var resp map[string]interface{}
+			json.NewDecoder(w.Body).Decode(&resp)
+			if tt.wantClear && resp["cleared"] != float64(3) {
+				t.Errorf("expected cleared count 3, got %v", resp["cleared"])
+			}
+			if !tt.wantClear && resp["code"] != "admin_required" {
+				t.Errorf("expected code admin_required, got %v", resp["code"])
+			}
+		})
+	}
+}
+
+func TestChatHandler_ClearCache(t *testing.T) {
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad"},
+		ChatCache:    config.ChatCacheConfig{Enabled: true, MaxEntries: 10},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(&mockLLMClient{}, cfg, logger)
+	handler.cache.Set(chatCacheKey("dad", "", "hello"), clients.ChatResponse{Response: "hi"})
+
+	if n := handler.ClearCache(); n != 1 {
+		t.Errorf("expected 1 cached reply cleared, got %d", n)
+	}
+	if n := NewChatHandler(&mockLLMClient{}, &config.Config{}, logger).ClearCache(); n != 0 {
+		t.Errorf("expected nothing to clear without a cache, got %d", n)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// capabilitiesTTL is how long an aggregated /capabilities answer is reused
+const capabilitiesTTL = 30 * time.Second
+
+// CapabilitiesHandler handles GET /capabilities requests
+type CapabilitiesHandler struct {
+	sidecars map[string]interface{} // Only clients implementing clients.CapabilitiesProvider are asked
+	config   *config.Config
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	cached  *capabilitiesResponse
+	expires time.Time
+}
+
+// capabilitiesResponse lists what each sidecar reports it supports.
+// Sidecars that cannot tell are left out.
+type capabilitiesResponse struct {
+	Sidecars map[string]*clients.Capabilities `json:"sidecars"`
+}
+
+// NewCapabilitiesHandler creates a new capabilities handler
+func NewCapabilitiesHandler(
+	voiceClient clients.VoiceClientInterface,
+	llmClient clients.LLMClientInterface,
+	learningClient clients.LearningClientInterface,
+	cfg *config.Config,
+	logger *slog.Logger,
+) *CapabilitiesHandler {
+	return &CapabilitiesHandler{
+		sidecars: map[string]interface{}{
+			"voice":    voiceClient,
+			"llm":      llmClient,
+			"learning": learningClient,
+		},
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *CapabilitiesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Only accept GET
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.capabilities())
+}
+
+// capabilities returns the cached answer, asking the sidecars again once it
+// is older than capabilitiesTTL
+func (h *CapabilitiesHandler) capabilities() *capabilitiesResponse {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Now().Before(h.expires) {
+		return h.cached
+	}
+
+	h.cached = h.fetch()
+	h.expires = time.Now().Add(capabilitiesTTL)
+	return h.cached
+}
+
+// fetch asks every sidecar in parallel, each under the health check deadline
+func (h *CapabilitiesHandler) fetch() *capabilitiesResponse {
+	type capabilitiesResult struct {
+		name string
+		caps *clients.Capabilities
+	}
+	results := make(chan capabilitiesResult, len(h.sidecars))
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Sidecars.GetHealthCheckTimeout())
+	defer cancel()
+
+	for name, client := range h.sidecars {
+		go func(name string, client interface{}) {
+			provider, ok := client.(clients.CapabilitiesProvider)
+			if !ok {
+				results <- capabilitiesResult{name: name}
+				return
+			}
+			caps, err := provider.Capabilities(ctx)
+			if errors.Is(err, clients.ErrCapabilitiesUnsupported) {
+				h.logger.Debug("sidecar does not report capabilities", "sidecar", name)
+			} else if err != nil {
+				h.logger.Warn("sidecar capabilities request failed", "sidecar", name, "error", err)
+			}
+			results <- capabilitiesResult{name: name, caps: caps}
+		}(name, client)
+	}
+
+	response := &capabilitiesResponse{Sidecars: make(map[string]*clients.Capabilities)}
+	for range h.sidecars {
+		result := <-results
+		if result.caps != nil {
+			response.Sidecars[result.name] = result.caps
+		}
+	}
+	return response
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// capableLLMClient is a mock LLM client that also reports capabilities
+type capableLLMClient struct {
+	mockLLMClient
+	calls atomic.Int32
+}
+
+func (m *capableLLMClient) Capabilities(ctx context.Context) (*clients.Capabilities, error) {
+	m.calls.Add(1)
+	return &clients.Capabilities{Models: []string{"llama3.1:8b", "llama3.2:3b"}}, nil
+}
+
+// capableVoiceClient is a mock Voice client whose sidecar may lack the endpoint
+type capableVoiceClient struct {
+	mockVoiceClient
+	unsupported bool
+}
+
+func (m *capableVoiceClient) Capabilities(ctx context.Context) (*clients.Capabilities, error) {
+	if m.unsupported {
+		return nil, clients.ErrCapabilitiesUnsupported
+	}
+	return &clients.Capabilities{
+		Languages: []string{"en", "fr"},
+		Features:  map[string]bool{"enrollment": true},
+	}, nil
+}
+
+func getCapabilities(t *testing.T, handler *CapabilitiesHandler) capabilitiesResponse {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/capabilities", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp capabilitiesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestCapabilitiesHandler_Aggregates(t *testing.T) {
+	llm := &capableLLMClient{}
+	voice := &capableVoiceClient{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewCapabilitiesHandler(voice, llm, &mockLearningClient{}, &config.Config{}, logger)
+
+	resp := getCapabilities(t, handler)
+
+	if got := resp.Sidecars["llm"]; got == nil || len(got.Models) != 2 {
+		t.Errorf("expected llm models, got %+v", got)
+	}
+	if got := resp.Sidecars["voice"]; got == nil || !got.Features["enrollment"] || len(got.Languages) != 2 {
+		t.Errorf("expected voice languages and enrollment, got %+v", got)
+	}
+	if _, ok := resp.Sidecars["learning"]; ok {
+		t.Error("expected learning to be omitted, its client cannot report capabilities")
+	}
+}
+
+func TestCapabilitiesHandler_OmitsUnsupported(t *testing.T) {
+	voice := &capableVoiceClient{unsupported: true}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewCapabilitiesHandler(voice, &capableLLMClient{}, &mockLearningClient{}, &config.Config{}, logger)
+
+	resp := getCapabilities(t, handler)
+
+	if _, ok := resp.Sidecars["voice"]; ok {
+		t.Error("expected voice to be omitted when its sidecar lacks the endpoint")
+	}
+	if _, ok := resp.Sidecars["llm"]; !ok {
+		t.Error("expected llm capabilities to still be reported")
+	}
+}
+
+func TestCapabilitiesHandler_Caches(t *testing.T) {
+	llm := &capableLLMClient{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewCapabilitiesHandler(&capableVoiceClient{}, llm, &mockLearningClient{}, &config.Config{}, logger)
+
+	getCapabilities(t, handler)
+	getCapabilities(t, handler)
+
+	if n := llm.calls.Load(); n != 1 {
+		t.Errorf("expected one sidecar call within the TTL, got %d", n)
+	}
+}
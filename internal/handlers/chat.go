@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/assistant/orchestrator/internal/cache"
 	"github.com/assistant/orchestrator/internal/clients"
 	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/pkg/apierror"
 )
 
 // ChatHandler handles POST /chat requests
@@ -14,22 +22,96 @@ type ChatHandler struct {
 	llmClient clients.LLMClientInterface
 	config    *config.Config
 	logger    *slog.Logger
+	cache     *cache.LRU[clients.ChatResponse] // nil when caching is disabled
+	content   *contentPolicy
+	transform *responseTransforms
+	quiet     *quietHours
+	eval      *evalSampling // nil when the eval log is off
 }
 
 // NewChatHandler creates a new chat handler
 func NewChatHandler(llmClient clients.LLMClientInterface, cfg *config.Config, logger *slog.Logger) *ChatHandler {
-	return &ChatHandler{
+	h := &ChatHandler{
 		llmClient: llmClient,
 		config:    cfg,
 		logger:    logger,
+		content:   newContentPolicy(cfg),
+		transform: newResponseTransforms(cfg),
+		quiet:     newQuietHours(cfg),
 	}
+
+	if cfg.ChatCache.Enabled {
+		h.cache = cache.NewLRU[clients.ChatResponse](cfg.ChatCache.MaxEntries, cfg.ChatCache.GetTTL())
+	}
+
+	return h
 }
 
+// UseEvalLog makes the handler offer each LLM reply to the eval log sampler
+func (h *ChatHandler) UseEvalLog(recorder evalRecorder) {
+	h.eval = newEvalSampling(recorder, h.config)
+}
+
+// userIDHeader names the user of a text/plain chat request
+const userIDHeader = "X-User-ID"
+
 // chatRequest represents the incoming request structure
 type chatRequest struct {
-	UserID              string                     `json:"user_id"`
-	Message             string                     `json:"message"`
-	ConversationHistory []clients.ConversationTurn `json:"conversation_history"`
+	UserID              string        `json:"user_id"`
+	Message             string        `json:"message"`
+	ConversationHistory []historyTurn `json:"conversation_history"`
+	RequestID           string        `json:"request_id"` // Optional, echoed back for correlation
+	Candidates          int           `json:"candidates"` // Candidate replies wanted (default 1)
+	Context             contextDocs   `json:"context"`    // Optional documents to ground the reply in
+	Language            string        `json:"language"`   // Optional language to answer in, overriding the configured one
+}
+
+// contextDocs is a list of context documents that also accepts a single string
+type contextDocs []string
+
+// UnmarshalJSON accepts either a string or an array of strings
+func (c *contextDocs) UnmarshalJSON(data []byte) error {
+	var doc string
+	if err := json.Unmarshal(data, &doc); err == nil {
+		*c = contextDocs{doc}
+		return nil
+	}
+
+	var docs []string
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return errors.New("context must be a string or an array of strings")
+	}
+	*c = docs
+	return nil
+}
+
+// size returns the total length of the documents in bytes
+func (c contextDocs) size() int {
+	total := 0
+	for _, doc := range c {
+		total += len(doc)
+	}
+	return total
+}
+
+// historyTurn is a conversation turn as sent by clients. Besides role and
+// content, the Windows client includes bookkeeping fields that are accepted
+// but not forwarded to the LLM sidecar.
+type historyTurn struct {
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	UserID    string `json:"user_id,omitempty"`
+	ModelUsed string `json:"model_used,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// toConversationTurns converts client history into the LLM sidecar format
+func toConversationTurns(history []historyTurn) []clients.ConversationTurn {
+	turns := make([]clients.ConversationTurn, len(history))
+	for i, turn := range history {
+		turns[i] = clients.ConversationTurn{Role: turn.Role, Content: turn.Content}
+	}
+	return turns
 }
 
 // ServeHTTP implements http.Handler
@@ -40,17 +122,31 @@ func (h *ChatHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse request body
+	// Parse request body; a text/plain body is the message itself, with
+	// the user in a header, and gets a text/plain reply
+	plainText := isPlainText(r)
 	var req chatRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if plainText {
+		message, err := decodeTextBody(r)
+		if err != nil {
+			h.logger.Warn("failed to read text chat request", "error", err)
+			writeBodyError(w, err)
+			return
+		}
+		req = chatRequest{UserID: r.Header.Get(userIDHeader), Message: strings.TrimSpace(message)}
+	} else if err := decodeJSONBody(r, &req); err != nil {
 		h.logger.Warn("failed to parse chat request", "error", err)
-		writeError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		writeBodyError(w, err)
 		return
 	}
 
 	// Validate user_id
 	if req.UserID == "" {
-		writeError(w, http.StatusBadRequest, "user_id is required", "")
+		detail := ""
+		if plainText {
+			detail = "text/plain requests name the user in the " + userIDHeader + " header"
+		}
+		writeError(w, http.StatusBadRequest, "user_id is required", detail)
 		return
 	}
 
@@ -60,40 +156,288 @@ func (h *ChatHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if window, quiet := h.quiet.active(req.UserID); quiet {
+		h.logger.Info("chat refused during quiet hours", "user_id", req.UserID)
+		writeQuietHours(w, window)
+		return
+	}
+
 	// Validate message
 	if req.Message == "" {
 		writeError(w, http.StatusBadRequest, "message is required", "")
 		return
 	}
 
+	if len(req.RequestID) > maxRequestIDLength {
+		writeError(w, http.StatusBadRequest, "request_id too long", fmt.Sprintf("request_id must be at most %d characters", maxRequestIDLength))
+		return
+	}
+	w = withRequestID(w, req.RequestID)
+
+	if req.Candidates < 0 {
+		writeError(w, http.StatusBadRequest, "invalid candidates", "candidates must be at least 1")
+		return
+	}
+	if req.Candidates == 0 {
+		req.Candidates = 1
+	}
+	if max := h.config.Chat.GetMaxCandidates(); req.Candidates > max {
+		h.logger.Info("capping chat candidates", "requested", req.Candidates, "max", max)
+		req.Candidates = max
+	}
+
+	if size, max := req.Context.size(), h.config.Chat.GetMaxContextBytes(); size > max {
+		writeErrorCode(w, http.StatusBadRequest, "context_too_large", "context too large", fmt.Sprintf("context is %d bytes, at most %d allowed", size, max))
+		return
+	}
+
+	// Condense older turns into a summary; the client keeps the full history
+	req.ConversationHistory = h.summarizeHistory(r.Context(), req.UserID, req.ConversationHistory)
+
+	// Keep the most recent turns so long conversations fit the LLM's context
+	if max := h.config.Chat.MaxHistoryTurns; max > 0 && len(req.ConversationHistory) > max {
+		h.logger.Info("trimming conversation history", "user_id", req.UserID, "turns", len(req.ConversationHistory), "max", max)
+		req.ConversationHistory = req.ConversationHistory[len(req.ConversationHistory)-max:]
+	}
+
+	// Apply the user's content filter policy
+	message, allowed := h.content.apply(req.UserID, req.Message)
+	if !allowed {
+		h.logger.Warn("chat message blocked by content filter", "user_id", req.UserID)
+		writeErrorCode(w, http.StatusBadRequest, "content_blocked", "message blocked by content filter", "")
+		return
+	}
+	req.Message = message
+
 	h.logger.Info("processing chat request", "user_id", req.UserID)
 
+	// Only single-reply messages without history, context or an explicit
+	// language are cacheable. The model comes from model_by_user, or from the
+	// message itself when none is set, so user, model and message determine
+	// the reply; keying on the model keeps a model change from serving stale
+	// replies.
+	model := h.config.GetModelForUser(req.UserID)
+	cacheKey := ""
+	if h.cache != nil && len(req.ConversationHistory) == 0 && len(req.Context) == 0 && req.Candidates == 1 && req.Language == "" && !bypassCache(r) {
+		cacheKey = chatCacheKey(req.UserID, model, req.Message)
+		if cached, ok := h.cache.Get(cacheKey); ok {
+			h.logger.Info("chat cache hit", "user_id", req.UserID)
+			cached.Cached = true
+			cached.Usage = nil // No tokens were spent on this reply
+			cached.MessageID = newMessageID()
+			cached.RequestID = req.RequestID
+			h.transformReply(req.UserID, &cached)
+			debug := newRawDebug(r, h.config)
+			debug.add("llm", cached.Raw)
+			writeChatReply(w, plainText, &cached, debug)
+			return
+		}
+	}
+
 	// Call LLM sidecar
 	llmReq := &clients.ChatRequest{
 		UserID:              req.UserID,
 		Message:             req.Message,
-		ConversationHistory: req.ConversationHistory,
+		ConversationHistory: toConversationTurns(req.ConversationHistory),
+		AssistantName:       h.config.GetAssistantName(),
+		Context:             req.Context,
+		Model:               model,
+		Language:            h.config.GetLanguageForUser(req.UserID, req.Language),
+	}
+	if req.Candidates > 1 {
+		llmReq.N = req.Candidates
 	}
 
-	llmResp, err := h.llmClient.Chat(r.Context(), llmReq)
+	llmResp, err := h.chatWithFallback(r, llmReq)
 	if err != nil {
-		h.logger.Error("LLM sidecar request failed", "error", err)
-		writeError(w, http.StatusServiceUnavailable, "llm sidecar unavailable", err.Error())
+		writeLLMClientError(w, h.logger, err, h.config.Sidecars.RetryAfter.LLM)
 		return
 	}
 
+	// Blank replies are a sidecar quirk and fallback replies a stopgap,
+	// neither worth remembering
+	if cacheKey != "" && strings.TrimSpace(llmResp.Response) != "" && llmResp.FallbackModel == "" {
+		h.cache.Set(cacheKey, *llmResp)
+	}
+
+	llmResp.MessageID = newMessageID()
+	llmResp.RequestID = req.RequestID
+	h.eval.record("chat", req.UserID, llmResp.ModelUsed, req.Message, llmResp.Response, llmResp.MessageID)
+	// Check the raw reply, so a transform adding text cannot hide an empty one
+	llmResp.Response, llmResp.EmptyResponse = fillEmptyReply(h.config, h.logger, req.UserID, llmResp.Response)
+	h.transformReply(req.UserID, llmResp)
+
 	// Return LLM response
+	debug := newRawDebug(r, h.config)
+	debug.add("llm", llmResp.Raw)
+	writeChatReply(w, plainText, llmResp, debug)
+}
+
+// summaryRole is the role of the turn standing in for summarized history
+const summaryRole = "system"
+
+// summarizeHistory replaces all but the most recent turns with a single
+// summary turn once history is longer than summarize_after_turns. History is
+// returned unchanged when the LLM sidecar cannot summarize it.
+func (h *ChatHandler) summarizeHistory(ctx context.Context, userID string, history []historyTurn) []historyTurn {
+	after := h.config.Chat.SummarizeAfterTurns
+	if after <= 0 || len(history) <= after {
+		return history
+	}
+	summarizer, ok := h.llmClient.(clients.Summarizer)
+	if !ok {
+		return history
+	}
+
+	keep := h.config.Chat.GetSummaryKeepTurns()
+	older, recent := history[:len(history)-keep], history[len(history)-keep:]
+	resp, err := summarizer.Summarize(ctx, &clients.SummarizeRequest{
+		UserID:              userID,
+		ConversationHistory: toConversationTurns(older),
+		Model:               h.config.GetModelForUser(userID),
+	})
+	if err == nil && strings.TrimSpace(resp.Summary) == "" {
+		err = errors.New("empty summary")
+	}
+	if err != nil {
+		h.logger.Warn("conversation summary failed, sending history as is", "user_id", userID, "turns", len(history), "error", err)
+		return history
+	}
+
+	h.logger.Info("summarized conversation history", "user_id", userID, "summarized_turns", len(older), "kept_turns", len(recent))
+	summarized := make([]historyTurn, 0, len(recent)+1)
+	summarized = append(summarized, historyTurn{Role: summaryRole, Content: "Summary of the earlier conversation: " + resp.Summary})
+	return append(summarized, recent...)
+}
+
+// chatWithFallback calls the LLM sidecar, retrying with each configured
+// fallback model in turn when the call fails. An overloaded sidecar or a
+// gone client ends the chain early, since no other model would fare better.
+func (h *ChatHandler) chatWithFallback(r *http.Request, llmReq *clients.ChatRequest) (*clients.ChatResponse, error) {
+	resp, err := h.llmClient.Chat(r.Context(), llmReq)
+	if err == nil {
+		return resp, nil
+	}
+
+	var overloadedErr *clients.OverloadedError
+	for _, model := range h.config.FallbackModels {
+		if errors.As(err, &overloadedErr) || r.Context().Err() != nil {
+			break
+		}
+		if model == llmReq.Model {
+			continue
+		}
+		h.logger.Warn("LLM request failed, trying fallback model", "user_id", llmReq.UserID, "model", llmReq.Model, "fallback_model", model, "error", err)
+		llmReq.Model = model
+		resp, err = h.llmClient.Chat(r.Context(), llmReq)
+		if err == nil {
+			resp.FallbackModel = model
+			if resp.ModelUsed == "" {
+				resp.ModelUsed = model
+			}
+			return resp, nil
+		}
+	}
+	return nil, err
+}
+
+// ClearCache empties the reply cache and returns how many replies it held
+func (h *ChatHandler) ClearCache() int {
+	if h.cache == nil {
+		return 0
+	}
+	return h.cache.Clear()
+}
+
+// writeChatReply writes a successful reply: the JSON response, with the
+// debug info when requested, or only its text for text/plain requests
+func writeChatReply(w http.ResponseWriter, plainText bool, resp *clients.ChatResponse, debug *debugInfo) {
+	if plainText {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, resp.Response)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(llmResp)
+	if debug != nil {
+		json.NewEncoder(w).Encode(struct {
+			*clients.ChatResponse
+			Debug *debugInfo `json:"_debug"`
+		}{resp, debug})
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// fillEmptyReply substitutes the configured fallback for an empty or
+// whitespace-only LLM reply, reporting whether it did
+func fillEmptyReply(cfg *config.Config, logger *slog.Logger, userID, reply string) (string, bool) {
+	if strings.TrimSpace(reply) != "" {
+		return reply, false
+	}
+	logger.Warn("LLM returned an empty response, sending the fallback message", "user_id", userID)
+	return cfg.Chat.GetEmptyResponseFallback(), true
+}
+
+// transformReply applies the user's response transforms to a reply and its
+// candidates. Candidates get a new slice, since cached replies share theirs.
+func (h *ChatHandler) transformReply(userID string, resp *clients.ChatResponse) {
+	resp.Response = h.transform.apply(userID, resp.Response)
+	if len(resp.Candidates) > 0 {
+		candidates := make([]string, len(resp.Candidates))
+		for i, candidate := range resp.Candidates {
+			candidates[i] = h.transform.apply(userID, candidate)
+		}
+		resp.Candidates = candidates
+	}
+}
+
+// bypassCache reports whether the client asked for a fresh reply
+func bypassCache(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache")
+}
+
+// chatCacheKey builds a cache key from the user, the model requested for
+// them ("" when the sidecar chooses) and a normalized message
+func chatCacheKey(userID, model, message string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(message), " "))
+	normalized = strings.TrimRight(normalized, " ?!.")
+	return userID + "\x00" + model + "\x00" + normalized
 }
 
-// writeError writes a structured error response
+// writeLLMClientError maps an LLM client error to an HTTP response;
+// retryAfter is the hint, in seconds, sent when the sidecar is unreachable
+func writeLLMClientError(w http.ResponseWriter, logger *slog.Logger, err error, retryAfter int) {
+	var overloadedErr *clients.OverloadedError
+	if errors.As(err, &overloadedErr) {
+		logger.Warn("LLM sidecar overloaded", "reason", overloadedErr.Reason)
+		w.Header().Set("Retry-After", "1")
+		writeErrorCode(w, http.StatusServiceUnavailable, "llm_overloaded", "llm sidecar overloaded", err.Error())
+		return
+	}
+	logger.Error("LLM sidecar request failed", "error", err)
+	writeUnavailable(w, "llm sidecar unavailable", err.Error(), retryAfter)
+}
+
+// writeError writes a structured error response with the generic code for status
 func writeError(w http.ResponseWriter, status int, message, detail string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error":  message,
-		"detail": detail,
-	})
+	writeAPIError(w, status, apierror.New(status, "", message, detail))
+}
+
+// writeUnavailable writes a 503 for a sidecar that could not be reached,
+// adding a Retry-After header and retry_after_seconds field when retryAfter
+// is positive
+func writeUnavailable(w http.ResponseWriter, message, detail string, retryAfter int) {
+	resp := apierror.New(http.StatusServiceUnavailable, "", message, detail)
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		resp.RetryAfterSeconds = retryAfter
+	}
+	writeAPIError(w, http.StatusServiceUnavailable, resp)
+}
+
+// writeErrorCode writes a structured error response with a machine-readable code
+func writeErrorCode(w http.ResponseWriter, status int, code, message, detail string) {
+	writeAPIError(w, status, apierror.New(status, code, message, detail))
 }
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
-	"log/slog"
-	"io"
+	"time"
 
 	"github.com/assistant/orchestrator/internal/clients"
 	"github.com/assistant/orchestrator/internal/config"
@@ -208,3 +214,1419 @@ func TestChatHandler_MethodNotAllowed(t *testing.T) {
 		t.Errorf("expected status 405, got %d", w.Code)
 	}
 }
+
+// newCachingChatHandler returns a handler with caching enabled and a counter of LLM calls
+func newCachingChatHandler(calls *int) *ChatHandler {
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		ChatCache: config.ChatCacheConfig{
+			Enabled:    true,
+			TTLSeconds: 60,
+			MaxEntries: 10,
+		},
+	}
+
+	mockClient := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			*calls++
+			return &clients.ChatResponse{
+				Response:  "It is 3pm in Tokyo",
+				ModelUsed: "llama3.2:3b",
+				UserID:    req.UserID,
+			}, nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewChatHandler(mockClient, cfg, logger)
+}
+
+// sendChat posts a chat message and decodes the response
+func sendChat(t *testing.T, handler http.Handler, body map[string]interface{}, header http.Header) clients.ChatResponse {
+	t.Helper()
+
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp clients.ChatResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestChatHandler_CacheHit(t *testing.T) {
+	calls := 0
+	handler := newCachingChatHandler(&calls)
+
+	first := sendChat(t, handler, map[string]interface{}{"user_id": "child", "message": "What time is it in Tokyo?"}, nil)
+	second := sendChat(t, handler, map[string]interface{}{"user_id": "child", "message": "  what time is it in  tokyo"}, nil)
+
+	if calls != 1 {
+		t.Errorf("expected 1 LLM call, got %d", calls)
+	}
+	if first.Cached {
+		t.Error("expected first response not to be cached")
+	}
+	if !second.Cached {
+		t.Error("expected second response to be cached")
+	}
+	if second.Response != first.Response {
+		t.Errorf("expected cached response %q, got %q", first.Response, second.Response)
+	}
+}
+
+func TestChatHandler_CacheMiss(t *testing.T) {
+	calls := 0
+	handler := newCachingChatHandler(&calls)
+
+	sendChat(t, handler, map[string]interface{}{"user_id": "child", "message": "What time is it in Tokyo?"}, nil)
+	// Different user
+	sendChat(t, handler, map[string]interface{}{"user_id": "teen", "message": "What time is it in Tokyo?"}, nil)
+	// History-dependent request
+	resp := sendChat(t, handler, map[string]interface{}{
+		"user_id": "child",
+		"message": "What time is it in Tokyo?",
+		"conversation_history": []map[string]string{
+			{"role": "user", "content": "I am going to Japan"},
+		},
+	}, nil)
+
+	if calls != 3 {
+		t.Errorf("expected 3 LLM calls, got %d", calls)
+	}
+	if resp.Cached {
+		t.Error("expected history-dependent response not to be cached")
+	}
+}
+
+func TestChatHandler_CacheKeyedByModel(t *testing.T) {
+	calls := 0
+	handler := newCachingChatHandler(&calls)
+
+	body := map[string]interface{}{"user_id": "child", "message": "What time is it in Tokyo?"}
+	sendChat(t, handler, body, nil)
+	handler.config.ModelByUser = map[string]string{"child": "llama3.1:8b"}
+	resp := sendChat(t, handler, body, nil)
+
+	if calls != 2 || resp.Cached {
+		t.Errorf("expected a new model to miss the cache, got %d calls, cached=%v", calls, resp.Cached)
+	}
+}
+
+func TestChatHandler_CacheBypass(t *testing.T) {
+	calls := 0
+	handler := newCachingChatHandler(&calls)
+
+	body := map[string]interface{}{"user_id": "child", "message": "What time is it in Tokyo?"}
+	sendChat(t, handler, body, nil)
+	resp := sendChat(t, handler, body, http.Header{"Cache-Control": {"no-cache"}})
+
+	if calls != 2 {
+		t.Errorf("expected 2 LLM calls, got %d", calls)
+	}
+	if resp.Cached {
+		t.Error("expected bypassed response not to be cached")
+	}
+}
+
+// postChatBody posts a raw JSON body to a fresh chat handler
+func postChatBody(t *testing.T, body string, llm clients.LLMClientInterface) *httptest.ResponseRecorder {
+	t.Helper()
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(llm, cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestChatHandler_UnknownField(t *testing.T) {
+	w := postChatBody(t, `{"user_id": "dad", "message": "hi", "temperature": 2}`, nil)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+
+	var errResp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+
+	if errResp["code"] != "unknown_field" {
+		t.Errorf("expected code 'unknown_field', got %s", errResp["code"])
+	}
+	if !strings.Contains(errResp["detail"], "temperature") {
+		t.Errorf("expected detail to name the field, got %s", errResp["detail"])
+	}
+}
+
+func TestChatHandler_TypeMismatch(t *testing.T) {
+	w := postChatBody(t, `{"user_id": 42, "message": "hi"}`, nil)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+
+	var errResp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+
+	if errResp["code"] != "invalid_type" {
+		t.Errorf("expected code 'invalid_type', got %s", errResp["code"])
+	}
+	if !strings.Contains(errResp["detail"], `"user_id" must be a string`) {
+		t.Errorf("expected detail to name the field and type, got %s", errResp["detail"])
+	}
+}
+
+func TestChatHandler_AcceptsClientHistoryFields(t *testing.T) {
+	var forwarded []clients.ConversationTurn
+	mockClient := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			forwarded = req.ConversationHistory
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+		},
+	}
+
+	// History as sent by the Windows client
+	w := postChatBody(t, `{
+		"user_id": "dad",
+		"message": "and then?",
+		"conversation_history": [
+			{"role": "user", "content": "hi", "user_id": "dad", "timestamp": "2024-02-26T10:00:00Z"},
+			{"role": "assistant", "content": "hello", "user_id": "dad", "model_used": "llama3.1:8b", "timestamp": "2024-02-26T10:00:01Z"}
+		]
+	}`, mockClient)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(forwarded) != 2 || forwarded[1].Content != "hello" {
+		t.Errorf("expected history forwarded as role/content turns, got %+v", forwarded)
+	}
+}
+
+func TestChatHandler_TrimsLongHistory(t *testing.T) {
+	var forwarded []clients.ConversationTurn
+	mockClient := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			forwarded = req.ConversationHistory
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		Chat:         config.ChatConfig{MaxHistoryTurns: 4},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockClient, cfg, logger)
+
+	history := make([]historyTurn, 10)
+	for i := range history {
+		history[i] = historyTurn{Role: "user", Content: fmt.Sprintf("turn %d", i)}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":              "dad",
+		"message":              "and then?",
+		"conversation_history": history,
+	})
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(forwarded) != 4 {
+		t.Fatalf("expected 4 forwarded turns, got %d", len(forwarded))
+	}
+	if forwarded[0].Content != "turn 6" || forwarded[3].Content != "turn 9" {
+		t.Errorf("expected the most recent turns, got %+v", forwarded)
+	}
+}
+
+func TestChatHandler_ShortHistoryNotTrimmed(t *testing.T) {
+	var forwarded []clients.ConversationTurn
+	mockClient := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			forwarded = req.ConversationHistory
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		Chat:         config.ChatConfig{MaxHistoryTurns: 4},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockClient, cfg, logger)
+
+	body := `{"user_id": "dad", "message": "and then?", "conversation_history": [
+		{"role": "user", "content": "hi"},
+		{"role": "assistant", "content": "hello"}
+	]}`
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(forwarded) != 2 {
+		t.Errorf("expected both turns forwarded, got %d", len(forwarded))
+	}
+}
+
+// summarizingLLMClient is a mockLLMClient that can also summarize
+type summarizingLLMClient struct {
+	mockLLMClient
+	summarizeFunc func(ctx context.Context, req *clients.SummarizeRequest) (*clients.SummarizeResponse, error)
+}
+
+func (m *summarizingLLMClient) Summarize(ctx context.Context, req *clients.SummarizeRequest) (*clients.SummarizeResponse, error) {
+	return m.summarizeFunc(ctx, req)
+}
+
+func TestChatHandler_SummarizesLongHistory(t *testing.T) {
+	var summarized, forwarded []clients.ConversationTurn
+	mockClient := &summarizingLLMClient{
+		mockLLMClient: mockLLMClient{
+			chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+				forwarded = req.ConversationHistory
+				return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+			},
+		},
+		summarizeFunc: func(ctx context.Context, req *clients.SummarizeRequest) (*clients.SummarizeResponse, error) {
+			summarized = req.ConversationHistory
+			return &clients.SummarizeResponse{Summary: "we talked about turns 0 to 7"}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		Chat:         config.ChatConfig{SummarizeAfterTurns: 6, SummaryKeepTurns: 2},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockClient, cfg, logger)
+
+	history := make([]historyTurn, 10)
+	for i := range history {
+		history[i] = historyTurn{Role: "user", Content: fmt.Sprintf("turn %d", i)}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":              "dad",
+		"message":              "and then?",
+		"conversation_history": history,
+	})
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(summarized) != 8 || summarized[7].Content != "turn 7" {
+		t.Errorf("expected the 8 older turns to be summarized, got %+v", summarized)
+	}
+	if len(forwarded) != 3 {
+		t.Fatalf("expected a summary and 2 recent turns, got %+v", forwarded)
+	}
+	if forwarded[0].Role != summaryRole || !strings.Contains(forwarded[0].Content, "turns 0 to 7") {
+		t.Errorf("expected the summary turn first, got %+v", forwarded[0])
+	}
+	if forwarded[1].Content != "turn 8" || forwarded[2].Content != "turn 9" {
+		t.Errorf("expected the most recent turns verbatim, got %+v", forwarded[1:])
+	}
+}
+
+func TestChatHandler_SummaryFailureSendsHistoryAsIs(t *testing.T) {
+	var forwarded []clients.ConversationTurn
+	mockClient := &summarizingLLMClient{
+		mockLLMClient: mockLLMClient{
+			chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+				forwarded = req.ConversationHistory
+				return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+			},
+		},
+		summarizeFunc: func(ctx context.Context, req *clients.SummarizeRequest) (*clients.SummarizeResponse, error) {
+			return nil, clients.ErrSummarizeUnsupported
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		Chat:         config.ChatConfig{SummarizeAfterTurns: 2},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockClient, cfg, logger)
+
+	body := `{"user_id": "dad", "message": "and then?", "conversation_history": [
+		{"role": "user", "content": "hi"},
+		{"role": "assistant", "content": "hello"},
+		{"role": "user", "content": "how are you?"}
+	]}`
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(forwarded) != 3 {
+		t.Errorf("expected the full history when summarizing fails, got %+v", forwarded)
+	}
+}
+
+func TestChatHandler_LLMOverloaded(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return nil, &clients.OverloadedError{Sidecar: "LLM", Reason: "queue_full"}
+		},
+	}
+
+	w := postChatBody(t, `{"user_id": "dad", "message": "hello"}`, mockLLM)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+
+	var errResp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if errResp["code"] != "llm_overloaded" {
+		t.Errorf("expected code 'llm_overloaded', got %q", errResp["code"])
+	}
+}
+
+func TestChatHandler_UnavailableRetryAfter(t *testing.T) {
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		Sidecars:     config.SidecarConfig{RetryAfter: config.RetryAfterConfig{LLM: 15}},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id": "dad", "message": "hello"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "15" {
+		t.Errorf("expected Retry-After 15, got %q", got)
+	}
+
+	var errResp struct {
+		Error             string `json:"error"`
+		RetryAfterSeconds int    `json:"retry_after_seconds"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if errResp.Error != "llm sidecar unavailable" || errResp.RetryAfterSeconds != 15 {
+		t.Errorf("unexpected error response: %+v", errResp)
+	}
+}
+
+func TestChatHandler_UnavailableWithoutRetryAfter(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	w := postChatBody(t, `{"user_id": "dad", "message": "hello"}`, mockLLM)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "" {
+		t.Errorf("expected no Retry-After by default, got %q", got)
+	}
+	if strings.Contains(w.Body.String(), "retry_after_seconds") {
+		t.Errorf("expected no retry_after_seconds by default, got %s", w.Body.String())
+	}
+}
+
+func TestChatHandler_ForwardsAssistantName(t *testing.T) {
+	tests := []struct {
+		name          string
+		assistantName string
+		want          string
+	}{
+		{"configured", "Friday", "Friday"},
+		{"unset defaults to Jarvis", "", "Jarvis"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					got = req.AssistantName
+					return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs:  []string{"dad", "mom", "teen", "child"},
+				AssistantName: tt.assistantName,
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockLLM, cfg, logger)
+
+			sendChat(t, handler, map[string]interface{}{"user_id": "dad", "message": "who are you?"}, nil)
+
+			if got != tt.want {
+				t.Errorf("expected assistant_name %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChatHandler_ModelByUser(t *testing.T) {
+	tests := []struct {
+		userID string
+		want   string
+	}{
+		{"child", "small-safe"},
+		{"dad", "big"},
+		{"mom", ""}, // Unlisted users leave the choice to the sidecar
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			var got string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					got = req.Model
+					return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+				ModelByUser:  map[string]string{"child": "small-safe", "dad": "big"},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockLLM, cfg, logger)
+
+			sendChat(t, handler, map[string]interface{}{"user_id": tt.userID, "message": "hello"}, nil)
+
+			if got != tt.want {
+				t.Errorf("expected model %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChatHandler_Language(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		language string
+		want     string
+	}{
+		{"default", "dad", "", "fr"},
+		{"per-user override", "child", "", "en"},
+		{"request language wins", "child", "es", "es"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					got = req.Language
+					return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs:    []string{"dad", "mom", "teen", "child"},
+				DefaultLanguage: "fr",
+				LanguageByUser:  map[string]string{"child": "en"},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockLLM, cfg, logger)
+
+			body := map[string]interface{}{"user_id": tt.userID, "message": "hello"}
+			if tt.language != "" {
+				body["language"] = tt.language
+			}
+			sendChat(t, handler, body, nil)
+
+			if got != tt.want {
+				t.Errorf("expected language %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChatHandler_FallbackModels(t *testing.T) {
+	var tried []string
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			tried = append(tried, req.Model)
+			if req.Model != "small" {
+				return nil, errors.New("model crashed")
+			}
+			return &clients.ChatResponse{Response: "from small", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs:   []string{"dad", "mom", "teen", "child"},
+		ModelByUser:    map[string]string{"dad": "big"},
+		FallbackModels: []string{"medium", "small", "tiny"},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+
+	resp := sendChat(t, handler, map[string]interface{}{"user_id": "dad", "message": "hello"}, nil)
+
+	if want := []string{"big", "medium", "small"}; !reflect.DeepEqual(tried, want) {
+		t.Errorf("expected models %v to be tried, got %v", want, tried)
+	}
+	if resp.Response != "from small" {
+		t.Errorf("expected the fallback's reply, got %q", resp.Response)
+	}
+	if resp.FallbackModel != "small" || resp.ModelUsed != "small" {
+		t.Errorf("expected reply noted as served by small, got fallback_model %q, model_used %q", resp.FallbackModel, resp.ModelUsed)
+	}
+}
+
+func TestChatHandler_FallbackModelsExhausted(t *testing.T) {
+	calls := 0
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			calls++
+			return nil, errors.New("model crashed")
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs:   []string{"dad", "mom", "teen", "child"},
+		FallbackModels: []string{"medium", "small"},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+
+	data, _ := json.Marshal(map[string]interface{}{"user_id": "dad", "message": "hello"})
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader(data))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if calls != 3 {
+		t.Errorf("expected primary and 2 fallbacks to be tried, got %d calls", calls)
+	}
+}
+
+func TestChatHandler_Candidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates interface{}
+		wantN      int
+	}{
+		{"default is one", nil, 0},
+		{"forwarded", 3, 3},
+		{"capped at max", 10, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotN int
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					gotN = req.N
+					resp := &clients.ChatResponse{Response: "first", UserID: req.UserID}
+					for i := 0; i < req.N; i++ {
+						resp.Candidates = append(resp.Candidates, fmt.Sprintf("reply %d", i))
+					}
+					return resp, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+				Chat:         config.ChatConfig{MaxCandidates: 4},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockLLM, cfg, logger)
+
+			body := map[string]interface{}{"user_id": "dad", "message": "name a color"}
+			if tt.candidates != nil {
+				body["candidates"] = tt.candidates
+			}
+			resp := sendChat(t, handler, body, nil)
+
+			if gotN != tt.wantN {
+				t.Errorf("expected n=%d forwarded, got %d", tt.wantN, gotN)
+			}
+			if len(resp.Candidates) != tt.wantN {
+				t.Errorf("expected %d candidates in response, got %v", tt.wantN, resp.Candidates)
+			}
+		})
+	}
+}
+
+func TestChatHandler_InvalidCandidates(t *testing.T) {
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(&mockLLMClient{}, cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id":"dad","message":"hi","candidates":-1}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestChatHandler_CandidatesBypassCache(t *testing.T) {
+	calls := 0
+	handler := newCachingChatHandler(&calls)
+
+	body := map[string]interface{}{"user_id": "dad", "message": "What time is it in Tokyo?", "candidates": 2}
+	sendChat(t, handler, body, nil)
+	sendChat(t, handler, body, nil)
+
+	if calls != 2 {
+		t.Errorf("expected multi-candidate requests to skip the cache, got %d LLM calls", calls)
+	}
+}
+
+func TestChatHandler_ForwardsContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		context interface{}
+		want    []string
+	}{
+		{"absent", nil, nil},
+		{"single string", "The wifi password is hunter2.", []string{"The wifi password is hunter2."}},
+		{"array", []string{"doc one", "doc two"}, []string{"doc one", "doc two"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					got = req.Context
+					return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockLLM, cfg, logger)
+
+			body := map[string]interface{}{"user_id": "dad", "message": "what is the wifi password?"}
+			if tt.context != nil {
+				body["context"] = tt.context
+			}
+			sendChat(t, handler, body, nil)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected context %q forwarded, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChatHandler_OversizedContext(t *testing.T) {
+	called := false
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			called = true
+			return &clients.ChatResponse{Response: "ok"}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		Chat:         config.ChatConfig{MaxContextBytes: 10},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+
+	// Each document fits, but together they exceed the limit
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id": "dad",
+		"message": "summarize",
+		"context": []string{"0123456", "789abc"},
+	})
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	var errResp map[string]string
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp["code"] != "context_too_large" {
+		t.Errorf("expected code context_too_large, got %q", errResp["code"])
+	}
+	if called {
+		t.Error("expected the LLM not to be called")
+	}
+}
+
+func TestChatHandler_InvalidContextType(t *testing.T) {
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(&mockLLMClient{}, cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id":"dad","message":"hi","context":42}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestChatHandler_ResponseTransforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform config.ResponseTransformConfig
+		want      string
+	}{
+		{"no-op default", config.ResponseTransformConfig{}, "**Paris** is the capital."},
+		{"strip markdown", config.ResponseTransformConfig{StripMarkdown: true}, "Paris is the capital."},
+		{"append suffix", config.ResponseTransformConfig{AppendSuffix: " (AI)"}, "**Paris** is the capital. (AI)"},
+		{"composed in order", config.ResponseTransformConfig{StripMarkdown: true, AppendSuffix: " *AI*"}, "Paris is the capital. *AI*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					return &clients.ChatResponse{Response: "**Paris** is the capital.", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs:       []string{"dad", "mom", "teen", "child"},
+				ResponseTransforms: map[string]config.ResponseTransformConfig{"child": tt.transform},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockLLM, cfg, logger)
+
+			resp := sendChat(t, handler, map[string]interface{}{"user_id": "child", "message": "capital of France?"}, nil)
+			if resp.Response != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, resp.Response)
+			}
+
+			// Other users are unaffected
+			resp = sendChat(t, handler, map[string]interface{}{"user_id": "dad", "message": "capital of France?"}, nil)
+			if resp.Response != "**Paris** is the capital." {
+				t.Errorf("expected dad's reply unchanged, got %q", resp.Response)
+			}
+		})
+	}
+}
+
+func TestChatHandler_ResponseTransformsKeepCacheRaw(t *testing.T) {
+	calls := 0
+	handler := newCachingChatHandler(&calls)
+	handler.transform = newResponseTransforms(&config.Config{
+		ResponseTransforms: map[string]config.ResponseTransformConfig{"dad": {AppendSuffix: "!"}},
+	})
+
+	body := map[string]interface{}{"user_id": "dad", "message": "What time is it in Tokyo?"}
+	first := sendChat(t, handler, body, nil)
+	second := sendChat(t, handler, body, nil)
+
+	if first.Response != "It is 3pm in Tokyo!" || second.Response != "It is 3pm in Tokyo!" {
+		t.Errorf("expected the suffix applied exactly once, got %q then %q", first.Response, second.Response)
+	}
+}
+
+func TestChatHandler_PassesThroughUsage(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{
+				Response:  "ok",
+				ModelUsed: "llama3.1:8b",
+				UserID:    req.UserID,
+				Usage:     &clients.TokenUsage{PromptTokens: 120, CompletionTokens: 30},
+			}, nil
+		},
+	}
+
+	w := postChatBody(t, `{"user_id": "dad", "message": "hello"}`, mockLLM)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	var usage clients.TokenUsage
+	if err := json.Unmarshal(resp["usage"], &usage); err != nil {
+		t.Fatalf("expected usage object, got %s: %v", resp["usage"], err)
+	}
+	if usage.PromptTokens != 120 || usage.CompletionTokens != 30 {
+		t.Errorf("expected usage 120/30, got %d/%d", usage.PromptTokens, usage.CompletionTokens)
+	}
+}
+
+func TestChatHandler_OmitsMissingUsage(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+		},
+	}
+
+	w := postChatBody(t, `{"user_id": "dad", "message": "hello"}`, mockLLM)
+
+	if strings.Contains(w.Body.String(), `"usage"`) {
+		t.Errorf("expected no usage field, got %s", w.Body.String())
+	}
+}
+
+func TestChatHandler_Suggestions(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID, Suggestions: []string{"Tell me more", "Thanks!"}}, nil
+		},
+	}
+
+	w := postChatBody(t, `{"user_id": "dad", "message": "hello"}`, mockLLM)
+
+	var resp clients.ChatResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if want := []string{"Tell me more", "Thanks!"}; !reflect.DeepEqual(resp.Suggestions, want) {
+		t.Errorf("expected suggestions %v, got %v", want, resp.Suggestions)
+	}
+
+	// Without suggestions the field is left out
+	mockLLM.chatFunc = func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+		return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+	}
+	w = postChatBody(t, `{"user_id": "dad", "message": "hello"}`, mockLLM)
+	if strings.Contains(w.Body.String(), `"suggestions"`) {
+		t.Errorf("expected no suggestions field, got %s", w.Body.String())
+	}
+}
+
+func TestChatHandler_ContentFilterPerUser(t *testing.T) {
+	tests := []struct {
+		userID      string
+		wantStatus  int
+		wantMessage string // Message forwarded to the LLM, empty when blocked
+	}{
+		{"child", http.StatusBadRequest, ""},
+		{"teen", http.StatusOK, "well **** that"},
+		{"dad", http.StatusOK, "well darn that"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			var forwarded string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					forwarded = req.Message
+					return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+				ContentFilter: config.ContentFilterConfig{
+					Words:    []string{"darn"},
+					Policies: map[string]string{"child": config.FilterBlock, "teen": config.FilterMask},
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockLLM, cfg, logger)
+
+			body, _ := json.Marshal(map[string]interface{}{"user_id": tt.userID, "message": "well darn that"})
+			req := httptest.NewRequest("POST", "/chat", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if forwarded != tt.wantMessage {
+				t.Errorf("expected LLM to receive %q, got %q", tt.wantMessage, forwarded)
+			}
+
+			if tt.wantStatus == http.StatusBadRequest {
+				var errResp map[string]string
+				if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+					t.Fatalf("failed to decode error response: %v", err)
+				}
+				if errResp["code"] != "content_blocked" {
+					t.Errorf("expected code 'content_blocked', got %q", errResp["code"])
+				}
+			}
+		})
+	}
+}
+
+func TestChatHandler_ContentFilterAllowsCleanMessage(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		ContentFilter: config.ContentFilterConfig{
+			Words:    []string{"darn"},
+			Policies: map[string]string{"child": config.FilterBlock},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+
+	resp := sendChat(t, handler, map[string]interface{}{"user_id": "child", "message": "tell me about dinosaurs"}, nil)
+	if resp.Response != "ok" {
+		t.Errorf("expected response 'ok', got %q", resp.Response)
+	}
+}
+
+func TestChatHandler_MessageIDs(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+
+	first := sendChat(t, handler, map[string]interface{}{"user_id": "dad", "message": "hi", "request_id": "client-42"}, nil)
+	second := sendChat(t, handler, map[string]interface{}{"user_id": "dad", "message": "hi"}, nil)
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuidPattern.MatchString(first.MessageID) {
+		t.Errorf("expected a UUID message_id, got %q", first.MessageID)
+	}
+	if first.MessageID == second.MessageID {
+		t.Errorf("expected distinct message_ids, got %q twice", first.MessageID)
+	}
+	if first.RequestID != "client-42" {
+		t.Errorf("expected request_id 'client-42' to be echoed, got %q", first.RequestID)
+	}
+	if second.RequestID != "" {
+		t.Errorf("expected no request_id when none was sent, got %q", second.RequestID)
+	}
+}
+
+func TestChatHandler_CacheHitGetsFreshMessageID(t *testing.T) {
+	calls := 0
+	handler := newCachingChatHandler(&calls)
+
+	first := sendChat(t, handler, map[string]interface{}{"user_id": "dad", "message": "hi"}, nil)
+	second := sendChat(t, handler, map[string]interface{}{"user_id": "dad", "message": "hi", "request_id": "again"}, nil)
+
+	if !second.Cached {
+		t.Fatal("expected second reply to be served from cache")
+	}
+	if second.MessageID == "" || second.MessageID == first.MessageID {
+		t.Errorf("expected a fresh message_id on cache hit, got %q then %q", first.MessageID, second.MessageID)
+	}
+	if second.RequestID != "again" {
+		t.Errorf("expected request_id 'again', got %q", second.RequestID)
+	}
+}
+
+func TestChatHandler_EmptyLLMResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		reply    string
+		fallback string
+		suffix   string
+		want     string
+	}{
+		{"empty uses default fallback", "", "", "", "Désolé, je n'ai pas de réponse pour le moment. Pouvez-vous reformuler ?"},
+		{"whitespace uses configured fallback", "  \n\t", "Say that again?", "", "Say that again?"},
+		{"suffix does not hide an empty reply", "", "Say that again?", " (AI)", "Say that again? (AI)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					return &clients.ChatResponse{Response: tt.reply, UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs:       []string{"dad", "mom", "teen", "child"},
+				Chat:               config.ChatConfig{EmptyResponseFallback: tt.fallback},
+				ResponseTransforms: map[string]config.ResponseTransformConfig{"dad": {AppendSuffix: tt.suffix}},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockClient, cfg, logger)
+
+			req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id":"dad","message":"hello"}`))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var resp clients.ChatResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Response != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, resp.Response)
+			}
+			if !resp.EmptyResponse {
+				t.Error("expected empty_response to be set")
+			}
+		})
+	}
+}
+
+func TestChatHandler_EmptyLLMResponseNotCached(t *testing.T) {
+	calls := 0
+	mockClient := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			calls++
+			if calls == 1 {
+				return &clients.ChatResponse{Response: "", UserID: req.UserID}, nil
+			}
+			return &clients.ChatResponse{Response: "hi there", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		ChatCache:    config.ChatCacheConfig{Enabled: true, TTLSeconds: 60, MaxEntries: 10},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockClient, cfg, logger)
+
+	var resp clients.ChatResponse
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id":"dad","message":"hello"}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		resp = clients.ChatResponse{}
+		json.NewDecoder(w.Body).Decode(&resp)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected the empty reply not to be cached, got %d LLM calls", calls)
+	}
+	if resp.Response != "hi there" || resp.EmptyResponse {
+		t.Errorf("expected the real reply on retry, got %+v", resp)
+	}
+}
+
+func TestChatHandler_PlainTextRoundTrip(t *testing.T) {
+	var gotReq *clients.ChatRequest
+	mockClient := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			gotReq = req
+			return &clients.ChatResponse{Response: "Paris", ModelUsed: "test-model", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockClient, cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader("What is the capital of France?\n"))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("X-User-ID", "mom")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected a text/plain reply, got %q", ct)
+	}
+	if w.Body.String() != "Paris" {
+		t.Errorf("expected only the reply text, got %q", w.Body.String())
+	}
+	if gotReq == nil || gotReq.UserID != "mom" || gotReq.Message != "What is the capital of France?" {
+		t.Errorf("expected the body as mom's message, got %+v", gotReq)
+	}
+}
+
+func TestChatHandler_PlainTextUserIDHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		want   string
+	}{
+		{"missing header", "", "user_id is required"},
+		{"unknown user", "stranger", "invalid user_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					t.Error("LLM should not be called")
+					return nil, nil
+				},
+			}
+
+			cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockClient, cfg, logger)
+
+			req := httptest.NewRequest("POST", "/chat", strings.NewReader("hello"))
+			req.Header.Set("Content-Type", "text/plain")
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", w.Code)
+			}
+			var resp map[string]interface{}
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp["error"] != tt.want {
+				t.Errorf("expected error %q, got %v", tt.want, resp["error"])
+			}
+		})
+	}
+}
+
+func TestChatHandler_PlainTextInvalidUTF8(t *testing.T) {
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(&mockLLMClient{}, cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader("\xff\xfe"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-User-ID", "dad")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["code"] != "invalid_text" {
+		t.Errorf("expected code invalid_text, got %v", resp["code"])
+	}
+}
+
+func TestChatHandler_QuietHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		hour       int
+		userID     string
+		wantStatus int
+	}{
+		{"restricted user inside window", 22, "child", http.StatusForbidden},
+		{"restricted user outside window", 16, "child", http.StatusOK},
+		{"unrestricted user inside window", 22, "dad", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llmCalled := false
+			mockClient := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					llmCalled = true
+					return &clients.ChatResponse{Response: "hi", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+				QuietHours:   map[string][]config.QuietWindow{"child": {{Start: "21:00", End: "07:00"}}},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockClient, cfg, logger)
+			handler.quiet.now = func() time.Time {
+				return time.Date(2024, 3, 1, tt.hour, 0, 0, 0, time.Local)
+			}
+
+			body := fmt.Sprintf(`{"user_id":%q,"message":"hello"}`, tt.userID)
+			req := httptest.NewRequest("POST", "/chat", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusForbidden {
+				return
+			}
+			if llmCalled {
+				t.Error("expected the LLM not to be called during quiet hours")
+			}
+			var resp map[string]interface{}
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp["code"] != "quiet_hours" {
+				t.Errorf("expected code quiet_hours, got %v", resp["code"])
+			}
+		})
+	}
+}
+
+func TestChatHandler_RawDebug(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		query   string
+		wantRaw bool
+	}{
+		{"enabled and requested", true, "?debug=raw", true},
+		{"enabled but not requested", true, "", false},
+		{"requested but disabled", false, "?debug=raw", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					return &clients.ChatResponse{
+						Response: "hi",
+						UserID:   req.UserID,
+						Raw:      json.RawMessage(`{"response":"hi","extra":"quirk"}`),
+					}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+				Debug:        config.DebugConfig{RawResponses: tt.enabled},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewChatHandler(mockClient, cfg, logger)
+
+			req := httptest.NewRequest("POST", "/chat"+tt.query, strings.NewReader(`{"user_id":"dad","message":"hello"}`))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Response string `json:"response"`
+				Debug    *struct {
+					Raw map[string]map[string]string `json:"raw"`
+				} `json:"_debug"`
+			}
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Response != "hi" {
+				t.Errorf("expected the usual response alongside, got %q", resp.Response)
+			}
+			if !tt.wantRaw {
+				if resp.Debug != nil {
+					t.Errorf("expected no _debug field, got %+v", resp.Debug)
+				}
+				return
+			}
+			if resp.Debug == nil || resp.Debug.Raw["llm"]["extra"] != "quirk" {
+				t.Errorf("expected the raw LLM response under _debug.raw.llm, got %+v", resp.Debug)
+			}
+		})
+	}
+}
+
+// sharedErrorBody is the error envelope both tiers must produce for the same
+// inputs; the Windows client's tests assert the identical body
+const sharedErrorBody = `{"code":"quiet_hours","message":"quiet time","error":"quiet time","detail":"until 07:00"}`
+
+func TestWriteErrorCode_SharedEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeErrorCode(w, http.StatusForbidden, "quiet_hours", "quiet time", "until 07:00")
+
+	if got := strings.TrimSpace(w.Body.String()); got != sharedErrorBody {
+		t.Errorf("expected %s, got %s", sharedErrorBody, got)
+	}
+
+	// A known request_id is echoed after the shared fields
+	w = httptest.NewRecorder()
+	writeErrorCode(withRequestID(w, "client-42"), http.StatusForbidden, "quiet_hours", "quiet time", "until 07:00")
+	if got, want := strings.TrimSpace(w.Body.String()), strings.TrimSuffix(sharedErrorBody, "}")+`,"request_id":"client-42"}`; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	// Errors without a code get the generic one for their status
+	w = httptest.NewRecorder()
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+	if got, want := strings.TrimSpace(w.Body.String()), `{"code":"method_not_allowed","message":"method not allowed","error":"method not allowed"}`; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestChatHandler_ErrorEchoesRequestID(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id":"dad","message":"hi","request_id":"client-42"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if errResp["request_id"] != "client-42" {
+		t.Errorf("expected request_id 'client-42', got %v", errResp["request_id"])
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/internal/filter"
+)
+
+// contentPolicy applies the configured word filter to text before it is sent
+// to the LLM, following each user's policy
+type contentPolicy struct {
+	filter *filter.WordFilter
+	config *config.ContentFilterConfig
+}
+
+func newContentPolicy(cfg *config.Config) *contentPolicy {
+	return &contentPolicy{
+		filter: filter.NewWordFilter(cfg.ContentFilter.Words),
+		config: &cfg.ContentFilter,
+	}
+}
+
+// apply returns the text to forward for userID, masked if the user's policy
+// says so. allowed is false when the policy blocks the text outright.
+func (p *contentPolicy) apply(userID, text string) (filtered string, allowed bool) {
+	switch p.config.GetPolicy(userID) {
+	case config.FilterMask:
+		filtered, _ = p.filter.Mask(text)
+		return filtered, true
+	case config.FilterBlock:
+		return text, len(p.filter.Matches(text)) == 0
+	default:
+		return text, true
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// debugInfo is what ?debug=raw adds to a response under _debug: each
+// sidecar's response body exactly as it was received
+type debugInfo struct {
+	Raw map[string]json.RawMessage `json:"raw"`
+}
+
+// newRawDebug returns the debug info for a request, or nil unless
+// debug.raw_responses is enabled and the request asks with ?debug=raw
+func newRawDebug(r *http.Request, cfg *config.Config) *debugInfo {
+	if !cfg.Debug.RawResponses || r.URL.Query().Get("debug") != "raw" {
+		return nil
+	}
+	return &debugInfo{Raw: make(map[string]json.RawMessage)}
+}
+
+// add records a sidecar's raw response; nil debug info ignores it
+func (d *debugInfo) add(sidecar string, raw json.RawMessage) {
+	if d == nil || raw == nil {
+		return
+	}
+	d.Raw[sidecar] = raw
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// bodyError describes why a JSON request body was rejected
+type bodyError struct {
+	code   string // "malformed_json", "unknown_field", "invalid_type", "invalid_text" or "body_too_large"
+	detail string
+}
+
+func (e *bodyError) Error() string {
+	return e.detail
+}
+
+// decodeJSONBody strictly decodes a request body into dst, rejecting unknown
+// fields and type mismatches with an error naming the offending field
+func decodeJSONBody(r *http.Request, dst interface{}) *bodyError {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &sizeErr):
+		return &bodyError{
+			code:   "body_too_large",
+			detail: fmt.Sprintf("body exceeds %d bytes", sizeErr.Limit),
+		}
+
+	case errors.As(err, &typeErr):
+		return &bodyError{
+			code:   "invalid_type",
+			detail: fmt.Sprintf("field %q must be %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value),
+		}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &bodyError{
+			code:   "unknown_field",
+			detail: fmt.Sprintf("unknown field %s", field),
+		}
+
+	case errors.Is(err, io.EOF):
+		return &bodyError{code: "malformed_json", detail: "request body is empty"}
+
+	default:
+		return &bodyError{code: "malformed_json", detail: err.Error()}
+	}
+}
+
+// isPlainText reports whether the request body is declared as text/plain
+func isPlainText(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/plain"
+}
+
+// decodeTextBody reads a text/plain request body, which must be UTF-8
+func decodeTextBody(r *http.Request) (string, *bodyError) {
+	data, err := io.ReadAll(r.Body)
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &sizeErr):
+		return "", &bodyError{
+			code:   "body_too_large",
+			detail: fmt.Sprintf("body exceeds %d bytes", sizeErr.Limit),
+		}
+	case err != nil:
+		return "", &bodyError{code: "invalid_text", detail: err.Error()}
+	case !utf8.Valid(data):
+		return "", &bodyError{code: "invalid_text", detail: "request body is not valid UTF-8"}
+	}
+	return string(data), nil
+}
+
+// jsonTypeName maps a Go kind to the JSON type a client should send
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "string":
+		return "a string"
+	case "bool":
+		return "a boolean"
+	case "slice", "array":
+		return "an array"
+	case "struct", "map":
+		return "an object"
+	default:
+		return "a number"
+	}
+}
+
+// writeBodyError writes a 400 response for a rejected request body, or 413
+// when the body exceeded the route's size limit
+func writeBodyError(w http.ResponseWriter, err *bodyError) {
+	if err.code == "body_too_large" {
+		writeErrorCode(w, http.StatusRequestEntityTooLarge, err.code, "request body too large", err.detail)
+		return
+	}
+	writeErrorCode(w, http.StatusBadRequest, err.code, "invalid request body", err.detail)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// EnrollHandler handles POST /enroll requests, registering a voice sample
+// for a family member with the Voice sidecar
+type EnrollHandler struct {
+	voiceClient clients.VoiceClientInterface
+	config      *config.Config
+	logger      *slog.Logger
+}
+
+// NewEnrollHandler creates a new enrollment handler
+func NewEnrollHandler(voiceClient clients.VoiceClientInterface, cfg *config.Config, logger *slog.Logger) *EnrollHandler {
+	return &EnrollHandler{
+		voiceClient: voiceClient,
+		config:      cfg,
+		logger:      logger,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *EnrollHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+		return
+	}
+
+	wavData, ok := readWAVUpload(w, r, h.logger, nil)
+	if !ok {
+		return
+	}
+
+	// Validate user_id
+	userID := r.FormValue("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "user_id is required", "")
+		return
+	}
+
+	if !h.config.IsValidUserID(userID) {
+		h.logger.Warn("invalid user_id", "user_id", userID)
+		writeError(w, http.StatusBadRequest, "invalid user_id", "user_id must be one of: dad, mom, teen, child")
+		return
+	}
+
+	h.logger.Info("processing enroll request", "user_id", userID, "size_bytes", len(wavData))
+
+	// Call Voice sidecar
+	enrollResp, err := h.voiceClient.Enroll(r.Context(), userID, wavData)
+	if err != nil {
+		writeVoiceClientError(w, h.logger, err, h.config.Sidecars.RetryAfter.Voice)
+		return
+	}
+
+	// Return enrollment status
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollResp)
+}
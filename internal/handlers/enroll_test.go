@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+func TestEnrollHandler_Success(t *testing.T) {
+	var gotUserID string
+
+	// Create mock client
+	mockVoice := &mockVoiceClient{
+		enrollFunc: func(ctx context.Context, userID string, wavData []byte) (*clients.EnrollResponse, error) {
+			gotUserID = userID
+			return &clients.EnrollResponse{
+				Status:  "enrolled",
+				UserID:  userID,
+				Samples: 3,
+			}, nil
+		},
+	}
+
+	// Create handler
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewEnrollHandler(mockVoice, cfg, logger)
+
+	// Create request
+	req := createAudioFormRequest(t, "/enroll", []byte("fake wav data"), map[string]string{"user_id": "teen"})
+	w := httptest.NewRecorder()
+
+	// Execute handler
+	handler.ServeHTTP(w, req)
+
+	// Verify response
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if gotUserID != "teen" {
+		t.Errorf("expected sidecar to receive user_id 'teen', got %q", gotUserID)
+	}
+
+	var resp clients.EnrollResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "enrolled" {
+		t.Errorf("expected status 'enrolled', got %s", resp.Status)
+	}
+	if resp.Samples != 3 {
+		t.Errorf("expected 3 samples, got %d", resp.Samples)
+	}
+}
+
+func TestEnrollHandler_InvalidUser(t *testing.T) {
+	tests := map[string]map[string]string{
+		"missing user_id": nil,
+		"unknown user_id": {"user_id": "grandpa"},
+	}
+
+	for name, fields := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Create mock client that must not be called
+			mockVoice := &mockVoiceClient{
+				enrollFunc: func(ctx context.Context, userID string, wavData []byte) (*clients.EnrollResponse, error) {
+					t.Error("voice sidecar should not be called")
+					return nil, nil
+				},
+			}
+
+			cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewEnrollHandler(mockVoice, cfg, logger)
+
+			req := createAudioFormRequest(t, "/enroll", []byte("fake wav data"), fields)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/internal/evallog"
+	"github.com/assistant/orchestrator/internal/filter"
+)
+
+// evalRecorder samples interactions into the eval log
+type evalRecorder interface {
+	Record(interaction evallog.Interaction) bool
+}
+
+// evalSampling applies the eval log's exclusions and masking before handing
+// interactions to the recorder. A nil *evalSampling records nothing.
+type evalSampling struct {
+	recorder evalRecorder
+	config   *config.EvalLogConfig
+	filter   *filter.WordFilter
+}
+
+func newEvalSampling(recorder evalRecorder, cfg *config.Config) *evalSampling {
+	return &evalSampling{
+		recorder: recorder,
+		config:   &cfg.EvalLog,
+		filter:   filter.NewWordFilter(cfg.ContentFilter.Words),
+	}
+}
+
+// record offers one LLM exchange to the eval log
+func (e *evalSampling) record(source, userID, model, prompt, response, messageID string) {
+	if e == nil || e.config.IsExcluded(userID) {
+		return
+	}
+	if e.config.MaskFilteredWords {
+		prompt, _ = e.filter.Mask(prompt)
+		response, _ = e.filter.Mask(response)
+	}
+	e.recorder.Record(evallog.Interaction{
+		Time:      time.Now().UTC(),
+		Source:    source,
+		UserID:    userID,
+		Model:     model,
+		Prompt:    prompt,
+		Response:  response,
+		MessageID: messageID,
+	})
+}
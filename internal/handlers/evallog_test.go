@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/internal/evallog"
+)
+
+// capturingRecorder keeps every interaction offered to the eval log
+type capturingRecorder struct {
+	interactions []evallog.Interaction
+}
+
+func (c *capturingRecorder) Record(interaction evallog.Interaction) bool {
+	c.interactions = append(c.interactions, interaction)
+	return true
+}
+
+func TestChatHandler_EvalLog(t *testing.T) {
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "darn right", ModelUsed: "llama3.2:3b", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs:  []string{"dad", "mom", "teen", "child"},
+		ContentFilter: config.ContentFilterConfig{Words: []string{"darn"}},
+		EvalLog:       config.EvalLogConfig{ExcludeUsers: []string{"child"}, MaskFilteredWords: true},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewChatHandler(mockLLM, cfg, logger)
+	recorder := &capturingRecorder{}
+	handler.UseEvalLog(recorder)
+
+	for _, userID := range []string{"dad", "child"} {
+		body := `{"user_id": "` + userID + `", "message": "is it darn cold?"}`
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/chat", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	if len(recorder.interactions) != 1 {
+		t.Fatalf("expected only dad's interaction to be offered, got %+v", recorder.interactions)
+	}
+	got := recorder.interactions[0]
+	if got.Source != "chat" || got.UserID != "dad" || got.Model != "llama3.2:3b" || got.MessageID == "" {
+		t.Errorf("unexpected interaction %+v", got)
+	}
+	if got.Prompt != "is it **** cold?" || got.Response != "**** right" {
+		t.Errorf("expected filtered words masked, got prompt %q and response %q", got.Prompt, got.Response)
+	}
+}
+
+func TestVoiceHandler_EvalLog(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "mom", Confidence: 0.9, Transcript: "what time is it?"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "Noon.", ModelUsed: "phi3:mini", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+	recorder := &capturingRecorder{}
+	handler.UseEvalLog(recorder)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(recorder.interactions) != 1 {
+		t.Fatalf("expected one interaction, got %d", len(recorder.interactions))
+	}
+	got := recorder.interactions[0]
+	if got.Source != "voice" || got.UserID != "mom" || got.Prompt != "what time is it?" || got.Response != "Noon." || got.Model != "phi3:mini" {
+		t.Errorf("unexpected interaction %+v", got)
+	}
+}
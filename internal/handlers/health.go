@@ -3,12 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
 )
 
 // HealthHandler handles GET /health requests
@@ -16,7 +17,34 @@ type HealthHandler struct {
 	voiceClient    clients.VoiceClientInterface
 	llmClient      clients.LLMClientInterface
 	learningClient clients.LearningClientInterface
+	config         *config.Config
 	logger         *slog.Logger
+	source         HealthSource    // nil probes the sidecars on every request
+	readiness      ReadinessSource // nil reports the orchestrator as ready
+}
+
+// Orchestrator lifecycle states reported by /health
+const (
+	OrchestratorStarting     = "starting"      // Sidecars are still being warmed up
+	OrchestratorReady        = "ready"         // Serving normally
+	OrchestratorShuttingDown = "shutting_down" // Draining requests before exit
+)
+
+// ReadinessSource reports the orchestrator's own lifecycle state
+type ReadinessSource interface {
+	OrchestratorState() string
+}
+
+// SidecarStatus is the last observed health of one sidecar
+type SidecarStatus struct {
+	Healthy bool
+	Latency time.Duration
+}
+
+// HealthSource supplies cached sidecar health, such as a background watcher.
+// ok is false until every sidecar has been checked at least once.
+type HealthSource interface {
+	SidecarStatuses() (statuses map[string]SidecarStatus, ok bool)
 }
 
 // NewHealthHandler creates a new health handler
@@ -24,26 +52,40 @@ func NewHealthHandler(
 	voiceClient clients.VoiceClientInterface,
 	llmClient clients.LLMClientInterface,
 	learningClient clients.LearningClientInterface,
+	cfg *config.Config,
 	logger *slog.Logger,
 ) *HealthHandler {
 	return &HealthHandler{
-		voiceClient:   voiceClient,
-		llmClient:     llmClient,
+		voiceClient:    voiceClient,
+		llmClient:      llmClient,
 		learningClient: learningClient,
-		logger:        logger,
+		config:         cfg,
+		logger:         logger,
 	}
 }
 
+// UseSource makes the handler answer from cached health instead of probing
+// the sidecars, falling back to probing until the source is ready
+func (h *HealthHandler) UseSource(source HealthSource) {
+	h.source = source
+}
+
+// UseReadiness makes the handler report the orchestrator state from source
+func (h *HealthHandler) UseReadiness(source ReadinessSource) {
+	h.readiness = source
+}
+
 // sidecarHealth represents the health status of a single sidecar
 type sidecarHealth struct {
-	Status     string `json:"status"`
-	LatencyMs  int64  `json:"latency_ms,omitempty"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
 }
 
 // healthResponse represents the aggregated health response
 type healthResponse struct {
-	Status   string                   `json:"status"`
-	Sidecars map[string]sidecarHealth `json:"sidecars"`
+	Status       string                   `json:"status"`
+	Orchestrator string                   `json:"orchestrator"` // starting, ready or shutting_down
+	Sidecars     map[string]sidecarHealth `json:"sidecars"`
 }
 
 // ServeHTTP implements http.Handler
@@ -54,80 +96,20 @@ func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-
-	// Channel to collect results
-	type healthResult struct {
-		name    string
-		status  string
-		latency time.Duration
+	sidecars, ok := h.cachedHealth()
+	if !ok {
+		sidecars = h.probe(r.Context())
 	}
-	results := make(chan healthResult, 3)
-
-	// WaitGroup for parallel health checks
-	var wg sync.WaitGroup
-	wg.Add(3)
-
-	// Check Voice sidecar
-	go func() {
-		defer wg.Done()
-		latency, err := h.voiceClient.Health(ctx)
-		status := "ok"
-		if err != nil {
-			h.logger.Warn("voice sidecar health check failed", "error", err)
-			status = "unreachable"
-		}
-		results <- healthResult{name: "voice", status: status, latency: latency}
-	}()
-
-	// Check LLM sidecar
-	go func() {
-		defer wg.Done()
-		latency, err := h.llmClient.Health(ctx)
-		status := "ok"
-		if err != nil {
-			h.logger.Warn("llm sidecar health check failed", "error", err)
-			status = "unreachable"
-		}
-		results <- healthResult{name: "llm", status: status, latency: latency}
-	}()
-
-	// Check Learning sidecar
-	go func() {
-		defer wg.Done()
-		latency, err := h.learningClient.Health(ctx)
-		status := "ok"
-		if err != nil {
-			h.logger.Warn("learning sidecar health check failed", "error", err)
-			status = "unreachable"
-		}
-		results <- healthResult{name: "learning", status: status, latency: latency}
-	}()
 
-	// Wait for all health checks to complete
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Collect results
-	sidecars := make(map[string]sidecarHealth)
+	// Count results
 	okCount := 0
-	unreachableCount := 0
-
-	for result := range results {
-		health := sidecarHealth{
-			Status: result.status,
-		}
-		
-		if result.status == "ok" {
-			health.LatencyMs = result.latency.Milliseconds()
+	unreachableCount := 0 // Includes timed out checks
+	for _, health := range sidecars {
+		if health.Status == "ok" {
 			okCount++
 		} else {
 			unreachableCount++
 		}
-
-		sidecars[result.name] = health
 	}
 
 	// Determine overall status
@@ -140,18 +122,214 @@ func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		overallStatus = "degraded"
 	}
 
-	h.logger.Info("health check completed", 
-		"status", overallStatus, 
-		"ok_count", okCount, 
-		"unreachable_count", unreachableCount)
+	h.logger.Info("health check completed",
+		"status", overallStatus,
+		"ok_count", okCount,
+		"unreachable_count", unreachableCount,
+		"cached", ok)
+
+	orchestrator := OrchestratorReady
+	if h.readiness != nil {
+		orchestrator = h.readiness.OrchestratorState()
+	}
 
 	// Return health response (always 200 OK)
 	response := healthResponse{
-		Status:   overallStatus,
-		Sidecars: sidecars,
+		Status:       overallStatus,
+		Orchestrator: orchestrator,
+		Sidecars:     sidecars,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// cachedHealth reads sidecar health from the source, if one is set and ready
+func (h *HealthHandler) cachedHealth() (map[string]sidecarHealth, bool) {
+	if h.source == nil {
+		return nil, false
+	}
+
+	statuses, ok := h.source.SidecarStatuses()
+	if !ok {
+		return nil, false
+	}
+
+	sidecars := make(map[string]sidecarHealth, len(statuses))
+	for name, status := range statuses {
+		health := sidecarHealth{Status: "unreachable"}
+		if status.Healthy {
+			health.Status = "ok"
+			health.LatencyMs = status.Latency.Milliseconds()
+		}
+		sidecars[name] = health
+	}
+	return sidecars, true
+}
+
+// checks returns each sidecar's health check by name
+func (h *HealthHandler) checks() map[string]func(context.Context) (time.Duration, error) {
+	return map[string]func(context.Context) (time.Duration, error){
+		"voice":    h.voiceClient.Health,
+		"llm":      h.llmClient.Health,
+		"learning": h.learningClient.Health,
+	}
+}
+
+// sidecarHealthResponse is the body of GET /health/{sidecar}
+type sidecarHealthResponse struct {
+	Sidecar   string `json:"sidecar"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+}
+
+// SidecarHandler returns the handler for GET /health/{name}, which checks a
+// single sidecar. Unlike /health it answers 503 when that sidecar is down,
+// so monitors can alert per dependency.
+func (h *HealthHandler) SidecarHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+			return
+		}
+
+		health, ok := h.cachedHealth()
+		sidecar, found := health[name]
+		if !ok || !found {
+			sidecar = h.probeOne(r.Context(), name, h.checks()[name])
+		}
+
+		status := http.StatusOK
+		if sidecar.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+		h.logger.Info("sidecar health check completed", "sidecar", name, "status", sidecar.Status, "cached", ok && found)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(sidecarHealthResponse{
+			Sidecar:   name,
+			Status:    sidecar.Status,
+			LatencyMs: sidecar.LatencyMs,
+		})
+	})
+}
+
+// probeOne checks a single sidecar under the health check deadline, giving
+// up early if parent is cancelled
+func (h *HealthHandler) probeOne(parent context.Context, name string, check func(context.Context) (time.Duration, error)) sidecarHealth {
+	ctx, cancel := context.WithTimeout(parent, h.config.Sidecars.GetHealthCheckTimeout())
+	defer cancel()
+
+	type healthResult struct {
+		latency time.Duration
+		err     error
+	}
+	result := make(chan healthResult, 1)
+	go func() {
+		latency, err := h.checkWithRetry(ctx, name, check)
+		result <- healthResult{latency: latency, err: err}
+	}()
+
+	select {
+	case res := <-result:
+		switch {
+		case errors.Is(res.err, context.DeadlineExceeded):
+			h.logger.Warn("sidecar health check timed out", "sidecar", name)
+			return sidecarHealth{Status: "timeout"}
+		case res.err != nil:
+			h.logger.Warn("sidecar health check failed", "sidecar", name, "error", res.err)
+			return sidecarHealth{Status: "unreachable"}
+		default:
+			return sidecarHealth{Status: "ok", LatencyMs: res.latency.Milliseconds()}
+		}
+	case <-ctx.Done():
+		h.logger.Warn("sidecar health check timed out", "sidecar", name)
+		return sidecarHealth{Status: "timeout"}
+	}
+}
+
+// healthRetryDelay is the pause between attempts of a failed health check
+const healthRetryDelay = 50 * time.Millisecond
+
+// checkWithRetry runs check, retrying failures up to the configured number
+// of times while ctx allows, so a single dropped packet doesn't report the
+// sidecar down. The latency is that of the attempt that succeeded.
+func (h *HealthHandler) checkWithRetry(ctx context.Context, name string, check func(context.Context) (time.Duration, error)) (time.Duration, error) {
+	latency, err := check(ctx)
+	for attempt := 1; err != nil && attempt <= h.config.Sidecars.HealthRetries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return latency, err
+		case <-time.After(healthRetryDelay):
+		}
+		h.logger.Debug("retrying sidecar health check", "sidecar", name, "attempt", attempt, "error", err)
+		latency, err = check(ctx)
+	}
+	return latency, err
+}
+
+// probe checks all sidecars in parallel under the health check deadline, so
+// a hung sidecar is reported as "timeout" instead of holding up the whole
+// response. Cancelling parent, as when the client goes away, stops waiting
+// at once and cancels the checks' context so they can exit too.
+func (h *HealthHandler) probe(parent context.Context) map[string]sidecarHealth {
+	checks := h.checks()
+
+	// Channel to collect results, buffered so late checks never block
+	type healthResult struct {
+		name    string
+		status  string
+		latency time.Duration
+	}
+	results := make(chan healthResult, len(checks))
+
+	ctx, cancel := context.WithTimeout(parent, h.config.Sidecars.GetHealthCheckTimeout())
+	defer cancel()
+
+	for name, check := range checks {
+		go func(name string, check func(context.Context) (time.Duration, error)) {
+			latency, err := h.checkWithRetry(ctx, name, check)
+			status := "ok"
+			if errors.Is(err, context.DeadlineExceeded) {
+				h.logger.Warn("sidecar health check timed out", "sidecar", name)
+				status = "timeout"
+			} else if err != nil {
+				h.logger.Warn("sidecar health check failed", "sidecar", name, "error", err)
+				status = "unreachable"
+			}
+			results <- healthResult{name: name, status: status, latency: latency}
+		}(name, check)
+	}
+
+	// Collect results until every check answered or the deadline passed
+	sidecars := make(map[string]sidecarHealth)
+	for len(sidecars) < len(checks) {
+		select {
+		case result := <-results:
+			health := sidecarHealth{
+				Status: result.status,
+			}
+
+			if result.status == "ok" {
+				health.LatencyMs = result.latency.Milliseconds()
+			}
+
+			sidecars[result.name] = health
+
+		case <-ctx.Done():
+			if parent.Err() != nil {
+				h.logger.Info("health check abandoned, request cancelled", "error", parent.Err())
+			}
+			for name := range checks {
+				if _, ok := sidecars[name]; !ok {
+					h.logger.Warn("sidecar health check timed out", "sidecar", name)
+					sidecars[name] = sidecarHealth{Status: "timeout"}
+				}
+			}
+		}
+	}
+
+	return sidecars
+}
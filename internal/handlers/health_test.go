@@ -3,13 +3,17 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/assistant/orchestrator/internal/config"
 )
 
 func TestHealthHandler_AllHealthy(t *testing.T) {
@@ -34,7 +38,7 @@ func TestHealthHandler_AllHealthy(t *testing.T) {
 
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewHealthHandler(mockVoice, mockLLM, mockLearning, logger)
+	handler := NewHealthHandler(mockVoice, mockLLM, mockLearning, &config.Config{}, logger)
 
 	// Create request
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -97,7 +101,7 @@ func TestHealthHandler_Degraded(t *testing.T) {
 
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewHealthHandler(mockVoice, mockLLM, mockLearning, logger)
+	handler := NewHealthHandler(mockVoice, mockLLM, mockLearning, &config.Config{}, logger)
 
 	// Create request
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -157,7 +161,7 @@ func TestHealthHandler_Error(t *testing.T) {
 
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewHealthHandler(mockVoice, mockLLM, mockLearning, logger)
+	handler := NewHealthHandler(mockVoice, mockLLM, mockLearning, &config.Config{}, logger)
 
 	// Create request
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -196,7 +200,7 @@ func TestHealthHandler_Error(t *testing.T) {
 func TestHealthHandler_MethodNotAllowed(t *testing.T) {
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewHealthHandler(nil, nil, nil, logger)
+	handler := NewHealthHandler(nil, nil, nil, &config.Config{}, logger)
 
 	// Create POST request (should be GET)
 	req := httptest.NewRequest("POST", "/health", nil)
@@ -210,3 +214,419 @@ func TestHealthHandler_MethodNotAllowed(t *testing.T) {
 		t.Errorf("expected status 405, got %d", w.Code)
 	}
 }
+
+// staticHealthSource serves fixed sidecar statuses
+type staticHealthSource struct {
+	statuses map[string]SidecarStatus
+	ready    bool
+}
+
+func (s *staticHealthSource) SidecarStatuses() (map[string]SidecarStatus, bool) {
+	return s.statuses, s.ready
+}
+
+func TestHealthHandler_UsesCachedSource(t *testing.T) {
+	// Create mock clients that must not be probed
+	probe := func(ctx context.Context) (time.Duration, error) {
+		t.Error("sidecar should not be probed when cached health is ready")
+		return 0, nil
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewHealthHandler(
+		&mockVoiceClient{healthFunc: probe},
+		&mockLLMClient{healthFunc: probe},
+		&mockLearningClient{healthFunc: probe},
+		&config.Config{},
+		logger,
+	)
+	handler.UseSource(&staticHealthSource{
+		ready: true,
+		statuses: map[string]SidecarStatus{
+			"voice":    {Healthy: true, Latency: 7 * time.Millisecond},
+			"llm":      {Healthy: false},
+			"learning": {Healthy: true, Latency: 3 * time.Millisecond},
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var resp healthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Status != "degraded" {
+		t.Errorf("expected status 'degraded', got %s", resp.Status)
+	}
+	if resp.Sidecars["llm"].Status != "unreachable" {
+		t.Errorf("expected llm status 'unreachable', got %s", resp.Sidecars["llm"].Status)
+	}
+	if resp.Sidecars["voice"].LatencyMs != 7 {
+		t.Errorf("expected voice latency 7ms, got %d", resp.Sidecars["voice"].LatencyMs)
+	}
+}
+
+func TestHealthHandler_ProbesUntilSourceReady(t *testing.T) {
+	healthy := func(ctx context.Context) (time.Duration, error) {
+		return time.Millisecond, nil
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewHealthHandler(
+		&mockVoiceClient{healthFunc: healthy},
+		&mockLLMClient{healthFunc: healthy},
+		&mockLearningClient{healthFunc: healthy},
+		&config.Config{},
+		logger,
+	)
+	handler.UseSource(&staticHealthSource{ready: false})
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var resp healthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "ok" {
+		t.Errorf("expected live probe status 'ok', got %s", resp.Status)
+	}
+}
+
+func TestHealthHandler_SlowSidecarTimesOut(t *testing.T) {
+	healthy := func(ctx context.Context) (time.Duration, error) {
+		return time.Millisecond, nil
+	}
+
+	// The LLM mock ignores its context and sleeps well past the deadline
+	release := make(chan struct{})
+	defer close(release)
+	mockLLM := &mockLLMClient{
+		healthFunc: func(ctx context.Context) (time.Duration, error) {
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+			return time.Millisecond, nil
+		},
+	}
+
+	// Create handler with a short per-check deadline
+	cfg := &config.Config{Sidecars: config.SidecarConfig{HealthTimeoutMs: 50}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewHealthHandler(&mockVoiceClient{healthFunc: healthy}, mockLLM, &mockLearningClient{healthFunc: healthy}, cfg, logger)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(w, req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected /health to return near the deadline, took %s", elapsed)
+	}
+
+	var resp healthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Sidecars["llm"].Status != "timeout" {
+		t.Errorf("expected llm status 'timeout', got %s", resp.Sidecars["llm"].Status)
+	}
+	if resp.Sidecars["voice"].Status != "ok" || resp.Sidecars["learning"].Status != "ok" {
+		t.Errorf("expected other sidecars ok, got %+v", resp.Sidecars)
+	}
+	if resp.Status != "degraded" {
+		t.Errorf("expected status 'degraded', got %s", resp.Status)
+	}
+}
+
+func TestHealthHandler_ContextAwareCheckTimesOut(t *testing.T) {
+	healthy := func(ctx context.Context) (time.Duration, error) {
+		return time.Millisecond, nil
+	}
+
+	// The voice mock honours its context and reports the deadline
+	mockVoice := &mockVoiceClient{
+		healthFunc: func(ctx context.Context) (time.Duration, error) {
+			<-ctx.Done()
+			return 0, fmt.Errorf("health check failed: %w", ctx.Err())
+		},
+	}
+
+	cfg := &config.Config{Sidecars: config.SidecarConfig{HealthTimeoutMs: 20}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewHealthHandler(mockVoice, &mockLLMClient{healthFunc: healthy}, &mockLearningClient{healthFunc: healthy}, cfg, logger)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var resp healthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Sidecars["voice"].Status != "timeout" {
+		t.Errorf("expected voice status 'timeout', got %s", resp.Sidecars["voice"].Status)
+	}
+}
+
+func TestHealthHandler_CancelledRequestStopsChecks(t *testing.T) {
+	started := make(chan struct{}, 3)
+	exited := make(chan struct{}, 3)
+	hanging := func(ctx context.Context) (time.Duration, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		exited <- struct{}{}
+		return 0, ctx.Err()
+	}
+
+	// A long deadline, so only the cancelled request can end the checks
+	cfg := &config.Config{Sidecars: config.SidecarConfig{HealthTimeoutMs: 60000}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewHealthHandler(&mockVoiceClient{healthFunc: hanging}, &mockLLMClient{healthFunc: hanging}, &mockLearningClient{healthFunc: hanging}, cfg, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/health", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(w, req)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the handler to return once the request was cancelled")
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-exited:
+		case <-time.After(time.Second):
+			t.Fatalf("expected every check to exit after cancellation, %d still running", 3-i)
+		}
+	}
+}
+
+func TestHealthHandler_RetriesFailedCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		retries    int
+		wantStatus string
+		wantCalls  int32
+	}{
+		{"no retries", 0, "unreachable", 1},
+		{"retry succeeds", 2, "ok", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy := func(ctx context.Context) (time.Duration, error) {
+				return time.Millisecond, nil
+			}
+
+			// The voice sidecar drops the first check, then answers in 7ms
+			var calls int32
+			mockVoice := &mockVoiceClient{
+				healthFunc: func(ctx context.Context) (time.Duration, error) {
+					if atomic.AddInt32(&calls, 1) == 1 {
+						return 0, errors.New("connection reset")
+					}
+					return 7 * time.Millisecond, nil
+				},
+			}
+
+			cfg := &config.Config{Sidecars: config.SidecarConfig{HealthRetries: tt.retries}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewHealthHandler(mockVoice, &mockLLMClient{healthFunc: healthy}, &mockLearningClient{healthFunc: healthy}, cfg, logger)
+
+			for _, path := range []string{"/health", "/health/voice"} {
+				atomic.StoreInt32(&calls, 0)
+				w := httptest.NewRecorder()
+				if path == "/health" {
+					handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				} else {
+					handler.SidecarHandler("voice").ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				}
+
+				var resp struct {
+					Status    string                   `json:"status"`
+					LatencyMs int64                    `json:"latency_ms"`
+					Sidecars  map[string]sidecarHealth `json:"sidecars"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("%s: failed to decode response: %v", path, err)
+				}
+				voice := sidecarHealth{Status: resp.Status, LatencyMs: resp.LatencyMs}
+				if path == "/health" {
+					voice = resp.Sidecars["voice"]
+				}
+
+				if voice.Status != tt.wantStatus {
+					t.Errorf("%s: expected voice status %q, got %q", path, tt.wantStatus, voice.Status)
+				}
+				if tt.wantStatus == "ok" && voice.LatencyMs != 7 {
+					t.Errorf("%s: expected the successful attempt's latency 7ms, got %d", path, voice.LatencyMs)
+				}
+				if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+					t.Errorf("%s: expected %d checks, got %d", path, tt.wantCalls, got)
+				}
+			}
+		})
+	}
+}
+
+// staticReadiness reports a fixed orchestrator state
+type staticReadiness string
+
+func (s staticReadiness) OrchestratorState() string {
+	return string(s)
+}
+
+func TestHealthHandler_OrchestratorState(t *testing.T) {
+	tests := []struct {
+		name   string
+		source ReadinessSource
+		want   string
+	}{
+		{"no source reports ready", nil, OrchestratorReady},
+		{"starting", staticReadiness(OrchestratorStarting), OrchestratorStarting},
+		{"ready", staticReadiness(OrchestratorReady), OrchestratorReady},
+		{"shutting down", staticReadiness(OrchestratorShuttingDown), OrchestratorShuttingDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create handler
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewHealthHandler(&mockVoiceClient{}, &mockLLMClient{}, &mockLearningClient{}, &config.Config{}, logger)
+			if tt.source != nil {
+				handler.UseReadiness(tt.source)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+
+			var resp healthResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Orchestrator != tt.want {
+				t.Errorf("expected orchestrator %q, got %q", tt.want, resp.Orchestrator)
+			}
+		})
+	}
+}
+
+func TestHealthHandler_SidecarRoutes(t *testing.T) {
+	for _, name := range []string{"voice", "llm", "learning"} {
+		for _, healthy := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s healthy=%v", name, healthy), func(t *testing.T) {
+				check := func(sidecar string) func(ctx context.Context) (time.Duration, error) {
+					return func(ctx context.Context) (time.Duration, error) {
+						if sidecar == name && !healthy {
+							return 0, fmt.Errorf("connection refused")
+						}
+						return 7 * time.Millisecond, nil
+					}
+				}
+				mockVoice := &mockVoiceClient{healthFunc: check("voice")}
+				mockLLM := &mockLLMClient{healthFunc: check("llm")}
+				mockLearning := &mockLearningClient{healthFunc: check("learning")}
+
+				logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+				handler := NewHealthHandler(mockVoice, mockLLM, mockLearning, &config.Config{}, logger)
+
+				w := httptest.NewRecorder()
+				handler.SidecarHandler(name).ServeHTTP(w, httptest.NewRequest("GET", "/health/"+name, nil))
+
+				wantCode, wantStatus := http.StatusOK, "ok"
+				if !healthy {
+					wantCode, wantStatus = http.StatusServiceUnavailable, "unreachable"
+				}
+				if w.Code != wantCode {
+					t.Errorf("expected status %d, got %d", wantCode, w.Code)
+				}
+
+				var resp sidecarHealthResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Sidecar != name || resp.Status != wantStatus {
+					t.Errorf("expected %s %s, got %+v", name, wantStatus, resp)
+				}
+				if healthy && resp.LatencyMs != 7 {
+					t.Errorf("expected latency 7ms, got %d", resp.LatencyMs)
+				}
+			})
+		}
+	}
+}
+
+func TestHealthHandler_SidecarRouteTimesOut(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		healthFunc: func(ctx context.Context) (time.Duration, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+	}
+
+	cfg := &config.Config{Sidecars: config.SidecarConfig{HealthTimeoutMs: 20}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewHealthHandler(mockVoice, &mockLLMClient{}, &mockLearningClient{}, cfg, logger)
+
+	w := httptest.NewRecorder()
+	handler.SidecarHandler("voice").ServeHTTP(w, httptest.NewRequest("GET", "/health/voice", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	var resp sidecarHealthResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Status != "timeout" {
+		t.Errorf("expected status timeout, got %q", resp.Status)
+	}
+}
+
+func TestHealthHandler_SidecarRouteUsesCachedSource(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		healthFunc: func(ctx context.Context) (time.Duration, error) {
+			t.Error("expected the cached status instead of a probe")
+			return 0, nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewHealthHandler(mockVoice, &mockLLMClient{}, &mockLearningClient{}, &config.Config{}, logger)
+	handler.UseSource(&staticHealthSource{
+		ready: true,
+		statuses: map[string]SidecarStatus{
+			"voice":    {Healthy: false},
+			"llm":      {Healthy: true, Latency: 3 * time.Millisecond},
+			"learning": {Healthy: true, Latency: 4 * time.Millisecond},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.SidecarHandler("voice").ServeHTTP(w, httptest.NewRequest("GET", "/health/voice", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 from the cached status, got %d", w.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/assistant/orchestrator/pkg/apierror"
+)
+
+// maxRequestIDLength bounds the client-supplied request_id echoed in responses
+const maxRequestIDLength = 128
+
+// newMessageID returns a random RFC 4122 version 4 UUID identifying one reply
+func newMessageID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// requestIDWriter carries the client's request_id to the error helpers, so
+// error bodies echo it like successful replies do
+type requestIDWriter struct {
+	http.ResponseWriter
+	requestID string
+}
+
+// withRequestID wraps w once a handler has validated the request_id; errors
+// written through the result carry it
+func withRequestID(w http.ResponseWriter, requestID string) http.ResponseWriter {
+	if requestID == "" {
+		return w
+	}
+	return &requestIDWriter{ResponseWriter: w, requestID: requestID}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *requestIDWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writeAPIError writes an error body, echoing the request_id w carries
+func writeAPIError(w http.ResponseWriter, status int, resp apierror.Response) {
+	if rw, ok := w.(*requestIDWriter); ok {
+		resp.RequestID = rw.requestID
+	}
+	apierror.Write(w, status, resp)
+}
@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/assistant/orchestrator/internal/clients"
 	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/pkg/apierror"
 )
 
 // LearnHandler handles POST /learn requests
@@ -14,6 +17,12 @@ type LearnHandler struct {
 	learningClient clients.LearningClientInterface
 	config         *config.Config
 	logger         *slog.Logger
+	queue          learningQueue // nil when submissions are not queued
+}
+
+// learningQueue holds submissions the Learning sidecar could not take
+type learningQueue interface {
+	Push(record interface{}) error
 }
 
 // NewLearnHandler creates a new learn handler
@@ -25,6 +34,12 @@ func NewLearnHandler(learningClient clients.LearningClientInterface, cfg *config
 	}
 }
 
+// UseQueue makes the handler queue submissions while the sidecar is down,
+// answering 202 Accepted instead of 503
+func (h *LearnHandler) UseQueue(q learningQueue) {
+	h.queue = q
+}
+
 // learnRequest represents the incoming request structure
 type learnRequest struct {
 	UserID  string `json:"user_id"`
@@ -42,9 +57,9 @@ func (h *LearnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req learnRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(r, &req); err != nil {
 		h.logger.Warn("failed to parse learn request", "error", err)
-		writeError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		writeBodyError(w, err)
 		return
 	}
 
@@ -84,7 +99,28 @@ func (h *LearnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	learningResp, err := h.learningClient.Submit(r.Context(), learningReq)
 	if err != nil {
 		h.logger.Error("Learning sidecar request failed", "error", err)
-		writeError(w, http.StatusServiceUnavailable, "learning sidecar unavailable", err.Error())
+
+		// Outages are worth retrying later; a refused submission is not
+		var rejected *clients.RejectedError
+		if h.queue != nil && !errors.As(err, &rejected) {
+			qerr := h.queue.Push(learningReq)
+			if qerr == nil {
+				h.logger.Info("learn request queued", "user_id", req.UserID)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusAccepted)
+				json.NewEncoder(w).Encode(map[string]string{"status": "queued"})
+				return
+			}
+			h.logger.Error("failed to queue learn request", "error", qerr)
+		}
+
+		writeUnavailable(w, "learning sidecar unavailable", err.Error(), h.config.Sidecars.RetryAfter.Learning)
+		return
+	}
+
+	if learningResp.Rejected() {
+		h.logger.Info("learn request rejected", "user_id", req.UserID, "status", learningResp.Status, "reason", learningResp.Reason)
+		writeLearnRejected(w, learningResp)
 		return
 	}
 
@@ -93,3 +129,29 @@ func (h *LearnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(learningResp)
 }
+
+// learnRejectedResponse is the shared error body with the sidecar's verdict
+type learnRejectedResponse struct {
+	apierror.Response
+	ID     string   `json:"id,omitempty"`
+	Status string   `json:"status"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// writeLearnRejected answers 422 with why the Learning sidecar refused a
+// submission, so the client can fix it rather than resend it
+func writeLearnRejected(w http.ResponseWriter, resp *clients.LearningResponse) {
+	detail := resp.Reason
+	if detail == "" {
+		detail = strings.Join(resp.Errors, "; ")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(learnRejectedResponse{
+		Response: apierror.New(http.StatusUnprocessableEntity, "learning_rejected", "submission rejected by the learning sidecar", detail),
+		ID:       resp.ID,
+		Status:   resp.Status,
+		Errors:   resp.Errors,
+	})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// maxLearnFieldBytes caps the user_id and source fields of a streamed upload
+const maxLearnFieldBytes = 1 << 10
+
+// LearnStreamHandler handles POST /learn/stream requests: multipart uploads
+// of content too large for /learn, such as transcripts or documents. The
+// "file" part is passed on to the Learning sidecar as it arrives rather than
+// read into memory, so the user_id and source fields must come before it.
+type LearnStreamHandler struct {
+	learningClient clients.LearningClientInterface
+	config         *config.Config
+	logger         *slog.Logger
+}
+
+// NewLearnStreamHandler creates a new streamed learn handler
+func NewLearnStreamHandler(learningClient clients.LearningClientInterface, cfg *config.Config, logger *slog.Logger) *LearnStreamHandler {
+	return &LearnStreamHandler{
+		learningClient: learningClient,
+		config:         cfg,
+		logger:         logger,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *LearnStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+		return
+	}
+
+	streamer, ok := h.learningClient.(clients.LearningStreamer)
+	if !ok {
+		writeErrorCode(w, http.StatusNotImplemented, "streaming_unsupported", "learning client cannot stream content",
+			"send the content to /learn instead")
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeErrorCode(w, http.StatusBadRequest, "malformed_multipart", "invalid multipart form", err.Error())
+		return
+	}
+
+	req := &clients.LearningStreamRequest{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			writeError(w, http.StatusBadRequest, "file is required", "")
+			return
+		}
+		if err != nil {
+			h.logger.Warn("failed to read learn upload", "error", err)
+			writeUploadReadError(w, err)
+			return
+		}
+
+		switch part.FormName() {
+		case "user_id", "source":
+			value, err := io.ReadAll(io.LimitReader(part, maxLearnFieldBytes+1))
+			if err != nil {
+				writeUploadReadError(w, err)
+				return
+			}
+			if len(value) > maxLearnFieldBytes {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is too long", part.FormName()), "")
+				return
+			}
+			if part.FormName() == "user_id" {
+				req.UserID = string(value)
+			} else {
+				req.Source = string(value)
+			}
+
+		case "file":
+			req.Filename = part.FileName()
+			h.submit(w, r, streamer, req, part)
+			return
+		}
+	}
+}
+
+// submit validates the fields read so far and streams the file part to the
+// Learning sidecar
+func (h *LearnStreamHandler) submit(w http.ResponseWriter, r *http.Request, streamer clients.LearningStreamer, req *clients.LearningStreamRequest, content io.Reader) {
+	// Validate user_id
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "user_id is required", "user_id must come before the file part")
+		return
+	}
+
+	if !h.config.IsValidUserID(req.UserID) {
+		h.logger.Warn("invalid user_id", "user_id", req.UserID)
+		writeError(w, http.StatusBadRequest, "invalid user_id", "user_id must be one of: dad, mom, teen, child")
+		return
+	}
+
+	// Validate source
+	if req.Source == "" {
+		writeError(w, http.StatusBadRequest, "source is required", "source must come before the file part")
+		return
+	}
+
+	h.logger.Info("streaming learn request", "user_id", req.UserID, "source", req.Source, "filename", req.Filename)
+
+	learningResp, err := streamer.SubmitStream(r.Context(), req, content)
+	if err != nil {
+		h.logger.Error("Learning sidecar stream failed", "error", err)
+
+		// The upload outgrowing its limit is the client's doing, not an outage
+		var sizeErr *http.MaxBytesError
+		if errors.As(err, &sizeErr) {
+			writeUploadReadError(w, err)
+			return
+		}
+		writeUnavailable(w, "learning sidecar unavailable", err.Error(), h.config.Sidecars.RetryAfter.Learning)
+		return
+	}
+
+	if learningResp.Rejected() {
+		h.logger.Info("learn request rejected", "user_id", req.UserID, "status", learningResp.Status, "reason", learningResp.Reason)
+		writeLearnRejected(w, learningResp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(learningResp)
+}
+
+// writeUploadReadError answers a failure reading a multipart upload: 413 when
+// it outgrew the body limit, 400 otherwise
+func writeUploadReadError(w http.ResponseWriter, err error) {
+	var sizeErr *http.MaxBytesError
+	if errors.As(err, &sizeErr) {
+		writeErrorCode(w, http.StatusRequestEntityTooLarge, "upload_too_large", "upload too large",
+			fmt.Sprintf("body exceeds %d bytes", sizeErr.Limit))
+		return
+	}
+	writeErrorCode(w, http.StatusBadRequest, "malformed_multipart", "invalid multipart form", err.Error())
+}
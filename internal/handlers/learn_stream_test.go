@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// learnStreamRequest builds a /learn/stream upload with the given fields,
+// in order, followed by the file part
+func learnStreamRequest(t *testing.T, fields [][2]string, content []byte) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			t.Fatalf("failed to write field: %v", err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", "notes.txt")
+	if err != nil {
+		t.Fatalf("failed to create file part: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/learn/stream", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestLearnStreamHandler_ReachesSidecar(t *testing.T) {
+	content := bytes.Repeat([]byte("Piano lesson moved to Thursday 5pm.\n"), 30000)
+
+	var received []byte
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/learning/submit/stream" {
+			t.Errorf("expected /learning/submit/stream, got %s", r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("sidecar failed to parse upload: %v", err)
+		}
+		if r.FormValue("user_id") != "mom" || r.FormValue("source") != "document" {
+			t.Errorf("unexpected fields user_id=%q source=%q", r.FormValue("user_id"), r.FormValue("source"))
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("expected a file part: %v", err)
+		}
+		if header.Filename != "notes.txt" {
+			t.Errorf("expected filename notes.txt, got %q", header.Filename)
+		}
+		received, _ = io.ReadAll(file)
+		json.NewEncoder(w).Encode(clients.LearningResponse{ID: "learn_doc", Status: "processing"})
+	}))
+	defer sidecar.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewLearnStreamHandler(clients.NewLearningClient(sidecar.URL, 5*time.Second), &config.Config{ValidUserIDs: []string{"mom"}}, logger)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, learnStreamRequest(t, [][2]string{{"user_id", "mom"}, {"source", "document"}}, content))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !bytes.Equal(received, content) {
+		t.Errorf("expected the sidecar to receive all %d bytes, got %d", len(content), len(received))
+	}
+	var resp clients.LearningResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != "learn_doc" {
+		t.Errorf("expected the sidecar's response, got %+v", resp)
+	}
+}
+
+func TestLearnStreamHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		client     clients.LearningClientInterface
+		request    func(t *testing.T) *http.Request
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "fields after the file",
+			client:     clients.NewStubLearningClient(),
+			request:    func(t *testing.T) *http.Request { return learnStreamRequest(t, nil, []byte("notes")) },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			name:   "invalid user",
+			client: clients.NewStubLearningClient(),
+			request: func(t *testing.T) *http.Request {
+				return learnStreamRequest(t, [][2]string{{"user_id", "neighbor"}, {"source", "document"}}, []byte("notes"))
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			name:   "too large",
+			client: clients.NewStubLearningClient(),
+			request: func(t *testing.T) *http.Request {
+				req := learnStreamRequest(t, [][2]string{{"user_id", "mom"}, {"source", "document"}}, make([]byte, 4096))
+				req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 1024)
+				return req
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "upload_too_large",
+		},
+		{
+			name:   "client cannot stream",
+			client: &mockLearningClient{},
+			request: func(t *testing.T) *http.Request {
+				return learnStreamRequest(t, [][2]string{{"user_id", "mom"}, {"source", "document"}}, []byte("notes"))
+			},
+			wantStatus: http.StatusNotImplemented,
+			wantCode:   "streaming_unsupported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewLearnStreamHandler(tt.client, &config.Config{ValidUserIDs: []string{"mom"}}, logger)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.request(t))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			var errResp map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if errResp["code"] != tt.wantCode {
+				t.Errorf("expected code %q, got %v", tt.wantCode, errResp["code"])
+			}
+		})
+	}
+}
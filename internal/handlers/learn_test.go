@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -188,3 +190,230 @@ func TestLearnHandler_MethodNotAllowed(t *testing.T) {
 		t.Errorf("expected status 405, got %d", w.Code)
 	}
 }
+
+func TestLearnHandler_SchemaViolations(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  string
+		wantField string
+	}{
+		{
+			name:      "unknown field",
+			body:      `{"user_id": "dad", "content": "test", "source": "test", "priority": "high"}`,
+			wantCode:  "unknown_field",
+			wantField: "priority",
+		},
+		{
+			name:      "user_id as number",
+			body:      `{"user_id": 7, "content": "test", "source": "test"}`,
+			wantCode:  "invalid_type",
+			wantField: "user_id",
+		},
+		{
+			name:      "content as array",
+			body:      `{"user_id": "dad", "content": ["a"], "source": "test"}`,
+			wantCode:  "invalid_type",
+			wantField: "content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create config
+			cfg := &config.Config{
+				ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+			}
+
+			// Create handler
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewLearnHandler(nil, cfg, logger)
+
+			// Create request
+			req := httptest.NewRequest("POST", "/learn", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			// Execute handler
+			handler.ServeHTTP(w, req)
+
+			// Verify response
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+
+			var errResp map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+
+			if errResp["code"] != tt.wantCode {
+				t.Errorf("expected code %q, got %q", tt.wantCode, errResp["code"])
+			}
+			if !strings.Contains(errResp["detail"], tt.wantField) {
+				t.Errorf("expected detail to name %q, got %q", tt.wantField, errResp["detail"])
+			}
+		})
+	}
+}
+
+// recordingQueue collects pushed records in memory
+type recordingQueue struct {
+	records []interface{}
+	err     error
+}
+
+func (q *recordingQueue) Push(record interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.records = append(q.records, record)
+	return nil
+}
+
+func TestLearnHandler_QueuesWhenSidecarDown(t *testing.T) {
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	mockClient := &mockLearningClient{
+		submitFunc: func(ctx context.Context, req *clients.LearningRequest) (*clients.LearningResponse, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewLearnHandler(mockClient, cfg, logger)
+	q := &recordingQueue{}
+	handler.UseQueue(q)
+
+	body := `{"user_id":"mom","content":"likes tea","source":"user_correction"}`
+	req := httptest.NewRequest("POST", "/learn", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]string
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["status"] != "queued" {
+		t.Errorf("expected status queued, got %q", resp["status"])
+	}
+	if len(q.records) != 1 {
+		t.Fatalf("expected 1 queued record, got %d", len(q.records))
+	}
+	if queued := q.records[0].(*clients.LearningRequest); queued.UserID != "mom" || queued.Content != "likes tea" {
+		t.Errorf("unexpected queued record: %+v", queued)
+	}
+}
+
+func TestLearnHandler_RejectedSubmissionNotQueued(t *testing.T) {
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	mockClient := &mockLearningClient{
+		submitFunc: func(ctx context.Context, req *clients.LearningRequest) (*clients.LearningResponse, error) {
+			return nil, &clients.RejectedError{StatusCode: http.StatusUnprocessableEntity, Body: "bad source"}
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewLearnHandler(mockClient, cfg, logger)
+	q := &recordingQueue{}
+	handler.UseQueue(q)
+
+	body := `{"user_id":"mom","content":"likes tea","source":"user_correction"}`
+	req := httptest.NewRequest("POST", "/learn", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if len(q.records) != 0 {
+		t.Errorf("expected nothing queued, got %d records", len(q.records))
+	}
+}
+
+func TestLearnHandler_RejectedStatus(t *testing.T) {
+	cfg := &config.Config{ValidUserIDs: []string{"dad", "mom", "teen", "child"}}
+	mockClient := &mockLearningClient{
+		submitFunc: func(ctx context.Context, req *clients.LearningRequest) (*clients.LearningResponse, error) {
+			return &clients.LearningResponse{
+				ID:     "corr_42",
+				Status: "rejected",
+				Errors: []string{"content too long", "unsafe content"},
+			}, nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewLearnHandler(mockClient, cfg, logger)
+	q := &recordingQueue{}
+	handler.UseQueue(q)
+
+	body := `{"user_id":"teen","content":"likes tea","source":"user_correction"}`
+	req := httptest.NewRequest("POST", "/learn", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Code   string   `json:"code"`
+		Detail string   `json:"detail"`
+		ID     string   `json:"id"`
+		Status string   `json:"status"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != "learning_rejected" || resp.ID != "corr_42" || resp.Status != "rejected" {
+		t.Errorf("unexpected rejection body %+v", resp)
+	}
+	if resp.Detail != "content too long; unsafe content" || len(resp.Errors) != 2 {
+		t.Errorf("expected the validation errors surfaced, got detail %q and errors %v", resp.Detail, resp.Errors)
+	}
+	if len(q.records) != 0 {
+		t.Errorf("expected nothing queued, got %d records", len(q.records))
+	}
+}
+
+func TestLearnHandler_UnavailableRetryAfter(t *testing.T) {
+	cfg := &config.Config{
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+		Sidecars:     config.SidecarConfig{RetryAfter: config.RetryAfterConfig{Learning: 60}},
+	}
+	mockClient := &mockLearningClient{
+		submitFunc: func(ctx context.Context, req *clients.LearningRequest) (*clients.LearningResponse, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewLearnHandler(mockClient, cfg, logger)
+
+	body := `{"user_id":"mom","content":"likes tea","source":"user_correction"}`
+	req := httptest.NewRequest("POST", "/learn", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+
+	var errResp struct {
+		Error             string `json:"error"`
+		RetryAfterSeconds int    `json:"retry_after_seconds"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if errResp.Error != "learning sidecar unavailable" || errResp.RetryAfterSeconds != 60 {
+		t.Errorf("unexpected error response: %+v", errResp)
+	}
+}
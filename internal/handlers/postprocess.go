@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/internal/filter"
+)
+
+// responseTransforms applies each user's configured transforms to LLM
+// replies before they are returned
+type responseTransforms struct {
+	config map[string]config.ResponseTransformConfig
+}
+
+func newResponseTransforms(cfg *config.Config) *responseTransforms {
+	return &responseTransforms{config: cfg.ResponseTransforms}
+}
+
+// apply returns the reply to send userID
+func (t *responseTransforms) apply(userID, text string) string {
+	for _, transform := range t.pipeline(userID) {
+		text = transform(text)
+	}
+	return text
+}
+
+// pipeline lists the transforms configured for userID, in the order they run
+func (t *responseTransforms) pipeline(userID string) []func(string) string {
+	cfg := t.config[userID]
+
+	var steps []func(string) string
+	if cfg.StripMarkdown {
+		steps = append(steps, filter.StripMarkdown)
+	}
+	if cfg.AppendSuffix != "" {
+		steps = append(steps, func(text string) string { return text + cfg.AppendSuffix })
+	}
+	return steps
+}
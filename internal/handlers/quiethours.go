@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// quietHours refuses chat and voice to users inside one of their configured
+// quiet windows
+type quietHours struct {
+	windows map[string][]config.QuietWindow
+	now     func() time.Time
+}
+
+func newQuietHours(cfg *config.Config) *quietHours {
+	return &quietHours{
+		windows: cfg.QuietHours,
+		now:     time.Now,
+	}
+}
+
+// active returns the quiet window userID is currently in, if any
+func (q *quietHours) active(userID string) (config.QuietWindow, bool) {
+	now := q.now()
+	for _, window := range q.windows[userID] {
+		if window.Contains(now) {
+			return window, true
+		}
+	}
+	return config.QuietWindow{}, false
+}
+
+// writeQuietHours writes the polite 403 refusing a request during quiet hours
+func writeQuietHours(w http.ResponseWriter, window config.QuietWindow) {
+	writeErrorCode(w, http.StatusForbidden, "quiet_hours", "It's quiet time right now. Let's talk again later!",
+		fmt.Sprintf("quiet hours run from %s to %s", window.Start, window.End))
+}
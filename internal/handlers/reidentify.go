@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// ReidentifyHandler handles POST /reidentify requests, resubmitting a recording
+// to the Voice sidecar with tuning options and returning its verdict as-is
+type ReidentifyHandler struct {
+	voiceClient clients.VoiceClientInterface
+	config      *config.Config
+	logger      *slog.Logger
+}
+
+// NewReidentifyHandler creates a new re-identification handler
+func NewReidentifyHandler(voiceClient clients.VoiceClientInterface, cfg *config.Config, logger *slog.Logger) *ReidentifyHandler {
+	return &ReidentifyHandler{
+		voiceClient: voiceClient,
+		config:      cfg,
+		logger:      logger,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *ReidentifyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+		return
+	}
+
+	wavData, ok := readWAVUpload(w, r, h.logger, nil)
+	if !ok {
+		return
+	}
+
+	opts, err := parseProcessVoiceOptions(r)
+	if err != nil {
+		writeErrorCode(w, http.StatusBadRequest, "invalid_option", "invalid identification option", err.Error())
+		return
+	}
+
+	h.logger.Info("re-identifying recording",
+		"size_bytes", len(wavData),
+		"min_confidence", opts.MinConfidence,
+		"enroll", opts.Enroll)
+
+	voiceResp, err := h.voiceClient.ProcessVoice(r.Context(), wavData, opts)
+	if err != nil {
+		writeVoiceClientError(w, h.logger, err, h.config.Sidecars.RetryAfter.Voice)
+		return
+	}
+	voiceResp.Status = h.config.Voice.CanonicalStatus(voiceResp.Status)
+	roundConfidences(voiceResp, h.config.Voice.GetConfidenceDecimals())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(voiceResp)
+}
+
+// parseProcessVoiceOptions reads the optional min_confidence and enroll form fields
+func parseProcessVoiceOptions(r *http.Request) (clients.ProcessVoiceOptions, error) {
+	var opts clients.ProcessVoiceOptions
+
+	if value := r.FormValue("min_confidence"); value != "" {
+		confidence, err := strconv.ParseFloat(value, 64)
+		if err != nil || confidence < 0 || confidence > 1 {
+			return opts, fmt.Errorf("field %q must be a number between 0 and 1", "min_confidence")
+		}
+		opts.MinConfidence = confidence
+	}
+
+	if value := r.FormValue("enroll"); value != "" {
+		enroll, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("field %q must be true or false", "enroll")
+		}
+		opts.Enroll = enroll
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+)
+
+// createAudioFormRequest builds a multipart upload with a WAV file and extra form fields
+func createAudioFormRequest(t *testing.T, path string, wavData []byte, fields map[string]string) *http.Request {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	part, err := writer.CreateFormFile("file", "test.wav")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := part.Write(wavData); err != nil {
+		t.Fatalf("failed to write wav data: %v", err)
+	}
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			t.Fatalf("failed to write field %s: %v", key, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", path, &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestReidentifyHandler_ForwardsOptions(t *testing.T) {
+	// Create mock client
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{
+				Status:     "identified",
+				UserID:     "teen",
+				Confidence: 0.81,
+				Transcript: "test transcript",
+			}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewReidentifyHandler(mockVoice, &config.Config{}, logger)
+
+	// Create request
+	req := createAudioFormRequest(t, "/reidentify", []byte("fake wav data"), map[string]string{
+		"min_confidence": "0.6",
+		"enroll":         "true",
+	})
+	w := httptest.NewRecorder()
+
+	// Execute handler
+	handler.ServeHTTP(w, req)
+
+	// Verify response
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	want := clients.ProcessVoiceOptions{MinConfidence: 0.6, Enroll: true}
+	if mockVoice.lastOpts != want {
+		t.Errorf("expected options %+v, got %+v", want, mockVoice.lastOpts)
+	}
+
+	var resp clients.VoiceResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.UserID != "teen" {
+		t.Errorf("expected user_id 'teen', got %s", resp.UserID)
+	}
+}
+
+func TestReidentifyHandler_DefaultOptions(t *testing.T) {
+	// Create mock client
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "rejected", Confidence: 0.3}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewReidentifyHandler(mockVoice, &config.Config{}, logger)
+
+	req := createAudioFormRequest(t, "/reidentify", []byte("fake wav data"), nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if mockVoice.lastOpts != (clients.ProcessVoiceOptions{}) {
+		t.Errorf("expected zero options, got %+v", mockVoice.lastOpts)
+	}
+}
+
+func TestReidentifyHandler_InvalidOptions(t *testing.T) {
+	tests := map[string]map[string]string{
+		"confidence not a number": {"min_confidence": "high"},
+		"confidence out of range": {"min_confidence": "1.5"},
+		"enroll not a boolean":    {"enroll": "maybe"},
+	}
+
+	for name, fields := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Create mock client that must not be called
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					t.Error("voice sidecar should not be called")
+					return nil, nil
+				},
+			}
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewReidentifyHandler(mockVoice, &config.Config{}, logger)
+
+			req := createAudioFormRequest(t, "/reidentify", []byte("fake wav data"), fields)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
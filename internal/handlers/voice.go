@@ -1,40 +1,83 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/assistant/orchestrator/internal/audio"
 	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
 )
 
+// guestUserID is the user rejected speakers are answered as when
+// voice.treat_rejected_as_fallback is set
+const guestUserID = "guest"
+
 // VoiceHandler handles POST /voice requests
 type VoiceHandler struct {
 	voiceClient clients.VoiceClientInterface
 	llmClient   clients.LLMClientInterface
+	config      *config.Config
 	logger      *slog.Logger
+	content     *contentPolicy
+	transform   *responseTransforms
+	quiet       *quietHours
+	eval        *evalSampling // nil when the eval log is off
 }
 
 // NewVoiceHandler creates a new voice handler
-func NewVoiceHandler(voiceClient clients.VoiceClientInterface, llmClient clients.LLMClientInterface, logger *slog.Logger) *VoiceHandler {
+func NewVoiceHandler(voiceClient clients.VoiceClientInterface, llmClient clients.LLMClientInterface, cfg *config.Config, logger *slog.Logger) *VoiceHandler {
 	return &VoiceHandler{
 		voiceClient: voiceClient,
 		llmClient:   llmClient,
+		config:      cfg,
 		logger:      logger,
+		content:     newContentPolicy(cfg),
+		transform:   newResponseTransforms(cfg),
+		quiet:       newQuietHours(cfg),
 	}
 }
 
+// UseEvalLog makes the handler offer each LLM reply to the eval log sampler
+func (h *VoiceHandler) UseEvalLog(recorder evalRecorder) {
+	h.eval = newEvalSampling(recorder, h.config)
+}
+
 // voiceSuccessResponse represents a successful voice processing response
 type voiceSuccessResponse struct {
-	Status     string   `json:"status"`
-	UserID     string   `json:"user_id"`
-	Confidence float64  `json:"confidence"`
-	Transcript string   `json:"transcript"`
-	Response   string   `json:"response"`
-	ModelUsed  string   `json:"model_used"`
-	Fallback   bool     `json:"fallback"`
-	MemoriesUsed []string `json:"memories_used,omitempty"`
+	Status        string                     `json:"status"`
+	UserID        string                     `json:"user_id"`
+	Confidence    float64                    `json:"confidence"`
+	Transcript    string                     `json:"transcript"`
+	Response      string                     `json:"response"`
+	ModelUsed     string                     `json:"model_used"`
+	Fallback      bool                       `json:"fallback"`
+	Candidates    []clients.SpeakerCandidate `json:"candidates,omitempty"` // Other speakers it might have been, best first
+	MemoriesUsed  []string                   `json:"memories_used,omitempty"`
+	Suggestions   []string                   `json:"suggestions,omitempty"` // Follow-up quick replies proposed by the LLM
+	Usage         *clients.TokenUsage        `json:"usage,omitempty"`
+	MessageID     string                     `json:"message_id"`
+	RequestID     string                     `json:"request_id,omitempty"`
+	Timings       *voiceTimings              `json:"timings,omitempty"`        // Only when voice.include_timings is set
+	EmptyResponse bool                       `json:"empty_response,omitempty"` // The LLM's blank reply was replaced by the fallback
+	Debug         *debugInfo                 `json:"_debug,omitempty"`         // Raw sidecar responses, for ?debug=raw
+}
+
+// voiceTimings breaks down where a voice request spent its time
+type voiceTimings struct {
+	VoiceMs int64 `json:"voice_ms"` // Voice sidecar: identification and transcription
+	LLMMs   int64 `json:"llm_ms"`   // LLM sidecar: reply generation
 }
 
 // ServeHTTP implements http.Handler
@@ -45,39 +88,73 @@ func (h *VoiceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32 MB max
-		h.logger.Warn("failed to parse multipart form", "error", err)
-		writeError(w, http.StatusBadRequest, "invalid multipart form", err.Error())
+	wavData, ok := readWAVUpload(w, r, h.logger, h.config.Voice.GetAllowedMIMETypes())
+	if !ok {
 		return
 	}
 
-	// Get file from form
-	file, _, err := r.FormFile("file")
-	if err != nil {
-		h.logger.Warn("no file in request", "error", err)
-		writeError(w, http.StatusBadRequest, "file is required", err.Error())
+	// Turn away PDFs, images and the like before they cost a sidecar call
+	if detected, ok := sniffAudio(wavData); !ok {
+		h.logger.Warn("upload is not audio", "detected_type", detected)
+		writeErrorCode(w, http.StatusBadRequest, "invalid_audio", "file is not audio",
+			fmt.Sprintf("content looks like %s", detected))
 		return
 	}
-	defer file.Close()
 
-	// Read WAV data
-	wavData, err := io.ReadAll(file)
-	if err != nil {
-		h.logger.Error("failed to read wav file", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to read audio file", err.Error())
+	if len(r.FormValue("request_id")) > maxRequestIDLength {
+		writeError(w, http.StatusBadRequest, "request_id too long", fmt.Sprintf("request_id must be at most %d characters", maxRequestIDLength))
 		return
 	}
 
 	h.logger.Info("processing voice request", "size_bytes", len(wavData))
 
+	// Stream progress to clients that ask for it and whose connection can flush
+	if flusher, ok := w.(http.Flusher); ok && acceptsEventStream(r) {
+		h.streamVoice(w, flusher, r, wavData)
+		return
+	}
+
+	h.process(w, r, wavData, func(string, interface{}) {})
+}
+
+// process runs a voice upload through the sidecars and writes the final
+// JSON response, reporting intermediate steps to progress
+func (h *VoiceHandler) process(w http.ResponseWriter, r *http.Request, wavData []byte, progress func(event string, data interface{})) {
+	w = withRequestID(w, r.FormValue("request_id"))
+
+	// Answer too-short or silent recordings without a sidecar round trip
+	if reason := h.precheckAudio(wavData); reason != "" {
+		h.logger.Info("no speech detected before sidecar call", "reason", reason)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "no_speech",
+		})
+		return
+	}
+
 	// Call Voice sidecar
-	voiceResp, err := h.voiceClient.ProcessVoice(r.Context(), wavData)
+	voiceStart := time.Now()
+	voiceResp, err := h.voiceClient.ProcessVoice(r.Context(), wavData, clients.ProcessVoiceOptions{})
+	voiceElapsed := time.Since(voiceStart)
+	var timeoutErr *clients.TimeoutError
+	if errors.As(err, &timeoutErr) && timeoutErr.PartialTranscript != "" {
+		h.writePartialTranscript(w, timeoutErr)
+		return
+	}
 	if err != nil {
-		h.logger.Error("Voice sidecar request failed", "error", err)
-		writeError(w, http.StatusServiceUnavailable, "voice sidecar unavailable", err.Error())
+		writeVoiceClientError(w, h.logger, err, h.config.Sidecars.RetryAfter.Voice)
 		return
 	}
+	voiceResp.Status = h.config.Voice.CanonicalStatus(voiceResp.Status)
+	roundConfidences(voiceResp, h.config.Voice.GetConfidenceDecimals())
+
+	// Optionally answer unrecognized speakers as an anonymous guest
+	if voiceResp.Status == "rejected" && h.config.Voice.TreatRejectedAsFallback {
+		h.logger.Info("treating rejected speaker as guest", "confidence", voiceResp.Confidence)
+		voiceResp.Status = "fallback"
+		voiceResp.UserID = guestUserID
+	}
 
 	// Handle different voice processing statuses
 	switch voiceResp.Status {
@@ -94,30 +171,67 @@ func (h *VoiceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.logger.Info("speaker rejected", "confidence", voiceResp.Confidence)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{
+		rejected := map[string]interface{}{
 			"status":     "rejected",
 			"confidence": voiceResp.Confidence,
-		})
+		}
+		if len(voiceResp.Candidates) > 0 {
+			rejected["candidates"] = voiceResp.Candidates
+		}
+		json.NewEncoder(w).Encode(rejected)
 		return
 
 	case "identified", "fallback":
+		// Give unattributed fallback audio the configured default user
+		if voiceResp.Status == "fallback" && voiceResp.UserID == "" && h.config.DefaultUserID != "" {
+			h.logger.Info("using default user for fallback", "user_id", h.config.DefaultUserID)
+			voiceResp.UserID = h.config.DefaultUserID
+		}
+		// An ambiguous identification is answered as its top candidate
+		if voiceResp.UserID == "" && len(voiceResp.Candidates) > 0 {
+			voiceResp.UserID = voiceResp.Candidates[0].UserID
+			voiceResp.Confidence = voiceResp.Candidates[0].Confidence
+		}
+
 		// Continue to LLM processing
-		h.logger.Info("speaker processed", 
-			"status", voiceResp.Status, 
+		h.logger.Info("speaker processed",
+			"status", voiceResp.Status,
 			"user_id", voiceResp.UserID,
 			"confidence", voiceResp.Confidence)
 
+		// The speaker is only known now, so quiet hours are checked here
+		if window, quiet := h.quiet.active(voiceResp.UserID); quiet {
+			h.logger.Info("voice refused during quiet hours", "user_id", voiceResp.UserID)
+			writeQuietHours(w, window)
+			return
+		}
+
+		// Apply the speaker's content filter policy to the transcript
+		transcript, allowed := h.content.apply(voiceResp.UserID, voiceResp.Transcript)
+		if !allowed {
+			h.logger.Warn("transcript blocked by content filter", "user_id", voiceResp.UserID)
+			writeErrorCode(w, http.StatusBadRequest, "content_blocked", "transcript blocked by content filter", "")
+			return
+		}
+		voiceResp.Transcript = transcript
+
+		progress("transcribed", voiceResp)
+
 		// Call LLM sidecar with transcript
 		llmReq := &clients.ChatRequest{
 			UserID:              voiceResp.UserID,
 			Message:             voiceResp.Transcript,
 			ConversationHistory: []clients.ConversationTurn{}, // Empty history for voice requests
+			AssistantName:       h.config.GetAssistantName(),
+			Model:               h.config.GetModelForUser(voiceResp.UserID),
+			Language:            h.config.GetLanguageForUser(voiceResp.UserID, voiceResp.Language),
 		}
 
+		llmStart := time.Now()
 		llmResp, err := h.llmClient.Chat(r.Context(), llmReq)
+		llmElapsed := time.Since(llmStart)
 		if err != nil {
-			h.logger.Error("LLM sidecar request failed", "error", err)
-			writeError(w, http.StatusServiceUnavailable, "llm sidecar unavailable", err.Error())
+			writeLLMClientError(w, h.logger, err, h.config.Sidecars.RetryAfter.LLM)
 			return
 		}
 
@@ -130,7 +244,26 @@ func (h *VoiceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			Response:     llmResp.Response,
 			ModelUsed:    llmResp.ModelUsed,
 			Fallback:     voiceResp.Status == "fallback",
+			Candidates:   voiceResp.Candidates,
 			MemoriesUsed: llmResp.MemoriesUsed,
+			Suggestions:  llmResp.Suggestions,
+			Usage:        llmResp.Usage,
+			MessageID:    newMessageID(),
+			RequestID:    r.FormValue("request_id"),
+		}
+		h.eval.record("voice", voiceResp.UserID, llmResp.ModelUsed, voiceResp.Transcript, llmResp.Response, response.MessageID)
+		response.Response, response.EmptyResponse = fillEmptyReply(h.config, h.logger, voiceResp.UserID, response.Response)
+		response.Response = h.transform.apply(voiceResp.UserID, response.Response)
+		if debug := newRawDebug(r, h.config); debug != nil {
+			debug.add("voice", voiceResp.Raw)
+			debug.add("llm", llmResp.Raw)
+			response.Debug = debug
+		}
+		if h.config.Voice.IncludeTimings {
+			response.Timings = &voiceTimings{
+				VoiceMs: voiceElapsed.Milliseconds(),
+				LLMMs:   llmElapsed.Milliseconds(),
+			}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -144,3 +277,209 @@ func (h *VoiceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// streamVoice answers with Server-Sent Events: "received" once the upload is
+// read, "transcribed" when the speaker and transcript are known, and finally
+// "responded" with the usual JSON body, or "error" with the error body
+func (h *VoiceHandler) streamVoice(w http.ResponseWriter, flusher http.Flusher, r *http.Request, wavData []byte) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	emit := func(event string, data interface{}) {
+		writeSSE(w, event, data)
+		flusher.Flush()
+	}
+
+	emit("received", map[string]int{"size_bytes": len(wavData)})
+
+	// The final response is rendered as usual, then relayed as one event
+	result := newBufferedResponse()
+	h.process(result, r, wavData, emit)
+
+	if result.status == http.StatusOK {
+		emit("responded", json.RawMessage(bytes.TrimSpace(result.body.Bytes())))
+	} else {
+		emit("error", json.RawMessage(bytes.TrimSpace(result.body.Bytes())))
+	}
+}
+
+// readWAVUpload reads the "file" part of a multipart upload, writing an error
+// response and returning false when it is missing or unreadable
+func readWAVUpload(w http.ResponseWriter, r *http.Request, logger *slog.Logger, allowedTypes []string) ([]byte, bool) {
+	// Parse multipart form
+	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32 MB in memory, the rest spills to disk
+		logger.Warn("failed to parse multipart form", "error", err)
+		var sizeErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &sizeErr):
+			writeErrorCode(w, http.StatusRequestEntityTooLarge, "upload_too_large", "upload too large",
+				fmt.Sprintf("body exceeds %d bytes", sizeErr.Limit))
+		case errors.Is(err, multipart.ErrMessageTooLarge):
+			writeErrorCode(w, http.StatusRequestEntityTooLarge, "upload_too_large", "upload too large", err.Error())
+		default:
+			writeErrorCode(w, http.StatusBadRequest, "malformed_multipart", "invalid multipart form", err.Error())
+		}
+		return nil, false
+	}
+
+	// Get file from form
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		logger.Warn("no file in request", "error", err)
+		writeError(w, http.StatusBadRequest, "file is required", err.Error())
+		return nil, false
+	}
+	defer file.Close()
+
+	if contentType := header.Header.Get("Content-Type"); !uploadTypeAllowed(contentType, allowedTypes) {
+		logger.Warn("unsupported upload type", "content_type", contentType)
+		writeErrorCode(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "unsupported audio type",
+			fmt.Sprintf("%s is not one of: %s", contentType, strings.Join(allowedTypes, ", ")))
+		return nil, false
+	}
+
+	// Read WAV data
+	wavData, err := io.ReadAll(file)
+	if err != nil {
+		logger.Error("failed to read wav file", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to read audio file", err.Error())
+		return nil, false
+	}
+
+	return wavData, true
+}
+
+// roundConfidences rounds the confidence values of a voice sidecar response
+// to decimals places, so 0.8900000001 is reported as 0.89. A negative
+// decimals leaves them untouched.
+func roundConfidences(resp *clients.VoiceResponse, decimals int) {
+	if decimals < 0 {
+		return
+	}
+	scale := math.Pow(10, float64(decimals))
+	round := func(v float64) float64 { return math.Round(v*scale) / scale }
+
+	resp.Confidence = round(resp.Confidence)
+	if len(resp.Candidates) > 0 {
+		// Copied so the client's slice is left alone
+		candidates := make([]clients.SpeakerCandidate, len(resp.Candidates))
+		for i, c := range resp.Candidates {
+			candidates[i] = clients.SpeakerCandidate{UserID: c.UserID, Confidence: round(c.Confidence)}
+		}
+		resp.Candidates = candidates
+	}
+}
+
+// uploadTypeAllowed reports whether a file part's declared Content-Type is in
+// allowed; a nil allowed list accepts anything. Parts that declare no type
+// (or the generic application/octet-stream) are left to the WAV checks.
+func uploadTypeAllowed(contentType string, allowed []string) bool {
+	if allowed == nil || contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaType == "application/octet-stream" {
+		return true
+	}
+	for _, t := range allowed {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffAudio reports whether an upload's content could be WAV audio, along
+// with the type http.DetectContentType sees. Content it recognizes as
+// something else (an image, a PDF, an archive, or audio in another format
+// such as WebM, Ogg or MP3) is not, nor is a RIFF container other than WAVE,
+// such as AVI or WebP. Bytes it cannot place come back as text or
+// octet-stream and are left to the WAV header check.
+func sniffAudio(data []byte) (string, bool) {
+	detected := http.DetectContentType(data)
+	if len(data) >= 4 && string(data[:4]) == "RIFF" && (len(data) < 12 || string(data[8:12]) != "WAVE") {
+		return detected, false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(detected)
+	if err != nil {
+		return detected, false
+	}
+	switch mediaType {
+	case "audio/wave", "audio/wav", "audio/x-wav",
+		"application/octet-stream",
+		"text/plain":
+		return detected, true
+	default:
+		return detected, false
+	}
+}
+
+// writePartialTranscript answers a voice sidecar timeout with what it heard
+// before giving up, flagged as degraded; the LLM is not asked to reply to
+// half a sentence
+func (h *VoiceHandler) writePartialTranscript(w http.ResponseWriter, timeoutErr *clients.TimeoutError) {
+	h.logger.Warn("voice sidecar timed out, returning partial transcript", "user_id", timeoutErr.UserID)
+
+	transcript, allowed := h.content.apply(timeoutErr.UserID, timeoutErr.PartialTranscript)
+	if !allowed {
+		h.logger.Warn("transcript blocked by content filter", "user_id", timeoutErr.UserID)
+		writeErrorCode(w, http.StatusBadRequest, "content_blocked", "transcript blocked by content filter", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":             "timeout",
+		"degraded":           true,
+		"user_id":            timeoutErr.UserID,
+		"partial_transcript": transcript,
+	})
+}
+
+// writeVoiceClientError maps a Voice client error to an HTTP response;
+// retryAfter is the hint, in seconds, sent when the sidecar is unreachable
+func writeVoiceClientError(w http.ResponseWriter, logger *slog.Logger, err error, retryAfter int) {
+	if errors.Is(err, clients.ErrInvalidWAV) {
+		logger.Warn("invalid wav upload", "error", err)
+		writeErrorCode(w, http.StatusBadRequest, "invalid_wav", "audio is not a valid WAV file", err.Error())
+		return
+	}
+	var busyErr *clients.BusyError
+	if errors.As(err, &busyErr) {
+		logger.Warn("voice sidecar busy", "retry_after", busyErr.RetryAfter)
+		seconds := int(math.Ceil(busyErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeErrorCode(w, http.StatusServiceUnavailable, "voice_busy", "voice sidecar busy", err.Error())
+		return
+	}
+	logger.Error("Voice sidecar request failed", "error", err)
+	writeUnavailable(w, "voice sidecar unavailable", err.Error(), retryAfter)
+}
+
+// precheckAudio returns a non-empty reason when the WAV is too short or silent.
+// Payloads whose header cannot be parsed are left for the sidecar to judge.
+func (h *VoiceHandler) precheckAudio(wavData []byte) string {
+	info, err := audio.ParseWAVHeader(wavData)
+	if err != nil {
+		return ""
+	}
+
+	if minDuration := h.config.Voice.GetMinDuration(); minDuration > 0 && info.Duration() < minDuration {
+		return "too_short"
+	}
+
+	if threshold := h.config.Voice.SilenceThreshold; threshold > 0 {
+		if peak, ok := audio.PeakLevel(wavData, info); ok && peak < threshold {
+			return "silence"
+		}
+	}
+
+	return ""
+}
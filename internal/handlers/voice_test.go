@@ -3,31 +3,47 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
 )
 
 // mockVoiceClient implements a mock Voice client for testing
 type mockVoiceClient struct {
 	processFunc func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error)
+	enrollFunc  func(ctx context.Context, userID string, wavData []byte) (*clients.EnrollResponse, error)
 	healthFunc  func(ctx context.Context) (time.Duration, error)
+	lastOpts    clients.ProcessVoiceOptions
 }
 
-func (m *mockVoiceClient) ProcessVoice(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+func (m *mockVoiceClient) ProcessVoice(ctx context.Context, wavData []byte, opts clients.ProcessVoiceOptions) (*clients.VoiceResponse, error) {
+	m.lastOpts = opts
 	if m.processFunc != nil {
 		return m.processFunc(ctx, wavData)
 	}
 	return nil, nil
 }
 
+func (m *mockVoiceClient) Enroll(ctx context.Context, userID string, wavData []byte) (*clients.EnrollResponse, error) {
+	if m.enrollFunc != nil {
+		return m.enrollFunc(ctx, userID, wavData)
+	}
+	return nil, nil
+}
+
 func (m *mockVoiceClient) Health(ctx context.Context) (time.Duration, error) {
 	if m.healthFunc != nil {
 		return m.healthFunc(ctx)
@@ -38,23 +54,23 @@ func (m *mockVoiceClient) Health(ctx context.Context) (time.Duration, error) {
 func createMultipartRequest(t *testing.T, wavData []byte) *http.Request {
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
-	
+
 	part, err := writer.CreateFormFile("file", "test.wav")
 	if err != nil {
 		t.Fatalf("failed to create form file: %v", err)
 	}
-	
+
 	if _, err := part.Write(wavData); err != nil {
 		t.Fatalf("failed to write wav data: %v", err)
 	}
-	
+
 	if err := writer.Close(); err != nil {
 		t.Fatalf("failed to close writer: %v", err)
 	}
-	
+
 	req := httptest.NewRequest("POST", "/voice", &buf)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	
+
 	return req
 }
 
@@ -83,7 +99,7 @@ func TestVoiceHandler_Identified(t *testing.T) {
 
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewVoiceHandler(mockVoice, mockLLM, logger)
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
 
 	// Create request
 	req := createMultipartRequest(t, []byte("fake wav data"))
@@ -144,7 +160,7 @@ func TestVoiceHandler_Fallback(t *testing.T) {
 
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewVoiceHandler(mockVoice, mockLLM, logger)
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
 
 	// Create request
 	req := createMultipartRequest(t, []byte("fake wav data"))
@@ -183,7 +199,7 @@ func TestVoiceHandler_NoSpeech(t *testing.T) {
 
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewVoiceHandler(mockVoice, nil, logger)
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
 
 	// Create request
 	req := createMultipartRequest(t, []byte("fake wav data"))
@@ -220,7 +236,7 @@ func TestVoiceHandler_Rejected(t *testing.T) {
 
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewVoiceHandler(mockVoice, nil, logger)
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
 
 	// Create request
 	req := createMultipartRequest(t, []byte("fake wav data"))
@@ -250,7 +266,7 @@ func TestVoiceHandler_Rejected(t *testing.T) {
 func TestVoiceHandler_MethodNotAllowed(t *testing.T) {
 	// Create handler
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	handler := NewVoiceHandler(nil, nil, logger)
+	handler := NewVoiceHandler(nil, nil, &config.Config{}, logger)
 
 	// Create GET request (should be POST)
 	req := httptest.NewRequest("GET", "/voice", nil)
@@ -264,3 +280,1551 @@ func TestVoiceHandler_MethodNotAllowed(t *testing.T) {
 		t.Errorf("expected status 405, got %d", w.Code)
 	}
 }
+
+func TestVoiceHandler_InvalidWAV(t *testing.T) {
+	// Create mock client that rejects the payload
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return nil, fmt.Errorf("%w: missing header", clients.ErrInvalidWAV)
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+	// Create request
+	req := createMultipartRequest(t, []byte("not a wav"))
+	w := httptest.NewRecorder()
+
+	// Execute handler
+	handler.ServeHTTP(w, req)
+
+	// Verify response
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+
+	var errResp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+
+	if errResp["code"] != "invalid_wav" {
+		t.Errorf("expected code 'invalid_wav', got %s", errResp["code"])
+	}
+	if errResp["error"] != "audio is not a valid WAV file" {
+		t.Errorf("expected a readable error message, got %s", errResp["error"])
+	}
+}
+
+func TestVoiceHandler_SidecarBusy(t *testing.T) {
+	// Create mock client that reports backpressure
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return nil, &clients.BusyError{RetryAfter: 2500 * time.Millisecond}
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+	// Create request
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+
+	// Execute handler
+	handler.ServeHTTP(w, req)
+
+	// Verify response
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "3" {
+		t.Errorf("expected Retry-After 3, got %q", got)
+	}
+
+	var errResp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+
+	if errResp["code"] != "voice_busy" {
+		t.Errorf("expected code 'voice_busy', got %s", errResp["code"])
+	}
+}
+
+func TestVoiceHandler_UnavailableRetryAfter(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return nil, fmt.Errorf("connection refused")
+		},
+	}
+
+	cfg := &config.Config{Sidecars: config.SidecarConfig{RetryAfter: config.RetryAfterConfig{Voice: 20}}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, cfg, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "20" {
+		t.Errorf("expected Retry-After 20, got %q", got)
+	}
+
+	var errResp struct {
+		Error             string `json:"error"`
+		RetryAfterSeconds int    `json:"retry_after_seconds"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if errResp.Error != "voice sidecar unavailable" || errResp.RetryAfterSeconds != 20 {
+		t.Errorf("unexpected error response: %+v", errResp)
+	}
+}
+
+func TestVoiceHandler_TimeoutReturnsPartialTranscript(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return nil, &clients.TimeoutError{PartialTranscript: "turn on the", UserID: "mom"}
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			t.Error("LLM should not be called for a partial transcript")
+			return nil, nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Status            string `json:"status"`
+		Degraded          bool   `json:"degraded"`
+		UserID            string `json:"user_id"`
+		PartialTranscript string `json:"partial_transcript"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "timeout" || !resp.Degraded {
+		t.Errorf("expected a degraded timeout response, got %+v", resp)
+	}
+	if resp.PartialTranscript != "turn on the" || resp.UserID != "mom" {
+		t.Errorf("expected the partial transcript, got %+v", resp)
+	}
+}
+
+func TestVoiceHandler_TimeoutWithoutPartialIsUnavailable(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return nil, &clients.TimeoutError{}
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
+
+// createTypedMultipartRequest builds a /voice upload whose file part declares contentType
+func createTypedMultipartRequest(t *testing.T, wavData []byte, contentType string) *http.Request {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="recording"`)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatalf("failed to create form part: %v", err)
+	}
+	if _, err := part.Write(wavData); err != nil {
+		t.Fatalf("failed to write wav data: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/voice", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestVoiceHandler_AllowedMIMEType(t *testing.T) {
+	called := false
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			called = true
+			return &clients.VoiceResponse{Status: "no_speech"}, nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+	req := createTypedMultipartRequest(t, []byte("fake wav data"), "audio/x-wav")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !called {
+		t.Error("expected the upload to reach the voice sidecar")
+	}
+}
+
+func TestVoiceHandler_DisallowedMIMEType(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		contentType string
+	}{
+		{"configured list", []string{"audio/wav"}, "audio/ogg"},
+		{"default is WAV only", nil, "audio/webm;codecs=opus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					t.Error("voice sidecar should not be called for a disallowed type")
+					return nil, nil
+				},
+			}
+
+			cfg := &config.Config{Voice: config.VoiceConfig{AllowedMIMETypes: tt.allowed}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, nil, cfg, logger)
+
+			req := createTypedMultipartRequest(t, []byte("fake wav data"), tt.contentType)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnsupportedMediaType {
+				t.Fatalf("expected status 415, got %d", w.Code)
+			}
+
+			var errResp map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if errResp["code"] != "unsupported_media_type" {
+				t.Errorf("expected code 'unsupported_media_type', got %s", errResp["code"])
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_ModelByResolvedUser(t *testing.T) {
+	// Fallback audio without a speaker resolves to the default user
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "fallback", Transcript: "tell me a story"}, nil
+		},
+	}
+
+	var gotModel string
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			gotModel = req.Model
+			return &clients.ChatResponse{Response: "once upon a time", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		DefaultUserID: "child",
+		ModelByUser:   map[string]string{"child": "small-safe", "dad": "big"},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotModel != "small-safe" {
+		t.Errorf("expected the default user's model, got %q", gotModel)
+	}
+}
+
+func TestVoiceHandler_Language(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		detected string
+		want     string
+	}{
+		{"default", "dad", "", "fr"},
+		{"per-user override", "child", "", "en"},
+		{"detected language wins", "child", "es", "es"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					return &clients.VoiceResponse{Status: "identified", UserID: tt.userID, Confidence: 0.9, Transcript: "hola", Language: tt.detected}, nil
+				},
+			}
+			var got string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					got = req.Language
+					return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{DefaultLanguage: "fr", LanguageByUser: map[string]string{"child": "en"}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if got != tt.want {
+				t.Errorf("expected language %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_TreatRejectedAsFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		wantStatus string
+		wantLLM    bool
+	}{
+		{"default stops at rejected", false, "rejected", false},
+		{"enabled answers as guest", true, "fallback", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					return &clients.VoiceResponse{Status: "rejected", Confidence: 0.3, Transcript: "who are you?"}, nil
+				},
+			}
+
+			var llmUser string
+			llmCalled := false
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					llmCalled = true
+					llmUser = req.UserID
+					return &clients.ChatResponse{Response: "I'm Jarvis", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				DefaultUserID: "child",
+				Voice:         config.VoiceConfig{TreatRejectedAsFallback: tt.enabled},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+			req := createMultipartRequest(t, []byte("fake wav data"))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp["status"] != tt.wantStatus {
+				t.Errorf("expected status %q, got %v", tt.wantStatus, resp["status"])
+			}
+			if llmCalled != tt.wantLLM {
+				t.Errorf("expected LLM called=%v, got %v", tt.wantLLM, llmCalled)
+			}
+			if tt.wantLLM && (llmUser != "guest" || resp["user_id"] != "guest") {
+				t.Errorf("expected the reply to be for guest, got LLM user %q and response user %v", llmUser, resp["user_id"])
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_StatusAliases(t *testing.T) {
+	tests := []struct {
+		sidecarStatus string
+		wantCode      int
+		wantStatus    string
+	}{
+		{"known", http.StatusOK, "identified"},
+		{"unknown", http.StatusOK, "rejected"},
+		{"silence", http.StatusOK, "no_speech"},
+		{"identified", http.StatusOK, "identified"}, // Canonical statuses still work
+		{"mystery", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sidecarStatus, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					return &clients.VoiceResponse{Status: tt.sidecarStatus, UserID: "dad", Confidence: 0.9, Transcript: "hello"}, nil
+				},
+			}
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					return &clients.ChatResponse{Response: "Hi!", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				Voice: config.VoiceConfig{StatusAliases: map[string]string{
+					"known":   "identified",
+					"unknown": "rejected",
+					"silence": "no_speech",
+				}},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+			req := createMultipartRequest(t, []byte("fake wav data"))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+
+			var resp map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp["status"] != tt.wantStatus {
+				t.Errorf("expected status %q, got %v", tt.wantStatus, resp["status"])
+			}
+		})
+	}
+}
+
+// buildTestWAV creates a mono 16-bit PCM WAV file with a constant amplitude
+func buildTestWAV(sampleRate uint32, numSamples int, amplitude int16) []byte {
+	dataSize := numSamples * 2
+	buf := make([]byte, 44+dataSize)
+
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(36+dataSize))
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], 1)
+	binary.LittleEndian.PutUint16(buf[22:24], 1)
+	binary.LittleEndian.PutUint32(buf[24:28], sampleRate)
+	binary.LittleEndian.PutUint32(buf[28:32], sampleRate*2)
+	binary.LittleEndian.PutUint16(buf[32:34], 2)
+	binary.LittleEndian.PutUint16(buf[34:36], 16)
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(dataSize))
+
+	for i := 0; i < numSamples; i++ {
+		binary.LittleEndian.PutUint16(buf[44+i*2:], uint16(amplitude))
+	}
+
+	return buf
+}
+
+func TestVoiceHandler_ShortRecordingSkipsSidecar(t *testing.T) {
+	sidecarCalled := false
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			sidecarCalled = true
+			return &clients.VoiceResponse{Status: "identified"}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		Voice: config.VoiceConfig{MinDurationMs: 300},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, cfg, logger)
+
+	// 100ms recording
+	req := createMultipartRequest(t, buildTestWAV(16000, 1600, 8000))
+	w := httptest.NewRecorder()
+
+	// Execute handler
+	handler.ServeHTTP(w, req)
+
+	// Verify response
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["status"] != "no_speech" {
+		t.Errorf("expected status 'no_speech', got %s", resp["status"])
+	}
+	if sidecarCalled {
+		t.Error("expected voice sidecar not to be called")
+	}
+}
+
+func TestVoiceHandler_SilentRecordingSkipsSidecar(t *testing.T) {
+	sidecarCalled := false
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			sidecarCalled = true
+			return &clients.VoiceResponse{Status: "identified"}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		Voice: config.VoiceConfig{MinDurationMs: 300, SilenceThreshold: 0.01},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, cfg, logger)
+
+	// One second of near-silence
+	req := createMultipartRequest(t, buildTestWAV(16000, 16000, 10))
+	w := httptest.NewRecorder()
+
+	// Execute handler
+	handler.ServeHTTP(w, req)
+
+	var resp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["status"] != "no_speech" {
+		t.Errorf("expected status 'no_speech', got %s", resp["status"])
+	}
+	if sidecarCalled {
+		t.Error("expected voice sidecar not to be called")
+	}
+}
+
+func TestVoiceHandler_LongRecordingForwarded(t *testing.T) {
+	sidecarCalled := false
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			sidecarCalled = true
+			return &clients.VoiceResponse{Status: "no_speech"}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		Voice: config.VoiceConfig{MinDurationMs: 300, SilenceThreshold: 0.01},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, cfg, logger)
+
+	// One second of audible audio
+	req := createMultipartRequest(t, buildTestWAV(16000, 16000, 8000))
+	w := httptest.NewRecorder()
+
+	// Execute handler
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if !sidecarCalled {
+		t.Error("expected voice sidecar to be called")
+	}
+}
+
+func TestVoiceHandler_FallbackUsesDefaultUser(t *testing.T) {
+	// Create mock clients
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{
+				Status:     "fallback",
+				Transcript: "who am I",
+			}, nil
+		},
+	}
+
+	var llmUserID string
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			llmUserID = req.UserID
+			return &clients.ChatResponse{Response: "hello", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ValidUserIDs:  []string{"dad", "mom", "teen", "child"},
+		DefaultUserID: "child",
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+	// Execute handler
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+	var resp voiceSuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if llmUserID != "child" {
+		t.Errorf("expected LLM user 'child', got %q", llmUserID)
+	}
+	if resp.UserID != "child" {
+		t.Errorf("expected user_id 'child', got %q", resp.UserID)
+	}
+	if !resp.Fallback {
+		t.Error("expected fallback true")
+	}
+}
+
+func TestVoiceHandler_FallbackWithoutDefaultUser(t *testing.T) {
+	// Create mock clients
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{
+				Status:     "fallback",
+				Transcript: "who am I",
+			}, nil
+		},
+	}
+
+	llmUserID := "unset"
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			llmUserID = req.UserID
+			return &clients.ChatResponse{Response: "hello", UserID: req.UserID}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+	// Execute handler
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+	var resp voiceSuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if llmUserID != "" {
+		t.Errorf("expected empty LLM user, got %q", llmUserID)
+	}
+	if !resp.Fallback {
+		t.Error("expected fallback true")
+	}
+}
+
+func TestVoiceHandler_MultipartErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    func(t *testing.T) *http.Request
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name: "upload too large",
+			request: func(t *testing.T) *http.Request {
+				req := createMultipartRequest(t, make([]byte, 4096))
+				req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 1024)
+				return req
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "upload_too_large",
+		},
+		{
+			name: "malformed boundary",
+			request: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest("POST", "/voice", bytes.NewReader([]byte("--wrong\r\nnot a part")))
+				req.Header.Set("Content-Type", "multipart/form-data; boundary=expected")
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "malformed_multipart",
+		},
+		{
+			name: "not multipart",
+			request: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest("POST", "/voice", bytes.NewReader([]byte(`{"file": "x"}`)))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "malformed_multipart",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create mock client that must not be called
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					t.Error("voice sidecar should not be called")
+					return nil, nil
+				},
+			}
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.request(t))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+
+			var errResp map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if errResp["code"] != tt.wantCode {
+				t.Errorf("expected code %q, got %q", tt.wantCode, errResp["code"])
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_ForwardsAssistantName(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{
+				Status:     "identified",
+				UserID:     "mom",
+				Confidence: 0.9,
+				Transcript: "what's your name?",
+			}, nil
+		},
+	}
+
+	var got string
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			got = req.AssistantName
+			return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{AssistantName: "Friday"}, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got != "Friday" {
+		t.Errorf("expected assistant_name 'Friday', got %q", got)
+	}
+}
+
+func TestVoiceHandler_PassesThroughUsage(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "dad", Confidence: 0.9, Transcript: "hi"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{
+				Response: "hello",
+				UserID:   req.UserID,
+				Usage:    &clients.TokenUsage{PromptTokens: 64, CompletionTokens: 8},
+			}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var resp voiceSuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 64 || resp.Usage.CompletionTokens != 8 {
+		t.Errorf("expected usage 64/8, got %+v", resp.Usage)
+	}
+}
+
+func TestVoiceHandler_PassesThroughSuggestions(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "dad", Confidence: 0.9, Transcript: "hi"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "hello", UserID: req.UserID, Suggestions: []string{"What's new?"}}, nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+	var resp voiceSuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Suggestions) != 1 || resp.Suggestions[0] != "What's new?" {
+		t.Errorf("expected suggestions passed through, got %v", resp.Suggestions)
+	}
+}
+
+func TestVoiceHandler_Timings(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			time.Sleep(5 * time.Millisecond)
+			return &clients.VoiceResponse{Status: "identified", UserID: "dad", Confidence: 0.9, Transcript: "hi"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			time.Sleep(5 * time.Millisecond)
+			return &clients.ChatResponse{Response: "hello", UserID: req.UserID}, nil
+		},
+	}
+
+	for _, include := range []bool{true, false} {
+		cfg := &config.Config{Voice: config.VoiceConfig{IncludeTimings: include}}
+
+		// Create handler
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+		var resp voiceSuccessResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if !include {
+			if resp.Timings != nil {
+				t.Errorf("expected no timings when disabled, got %+v", resp.Timings)
+			}
+			continue
+		}
+		if resp.Timings == nil {
+			t.Fatal("expected timings when enabled")
+		}
+		if resp.Timings.VoiceMs <= 0 || resp.Timings.LLMMs <= 0 {
+			t.Errorf("expected positive voice_ms and llm_ms, got %+v", resp.Timings)
+		}
+	}
+}
+
+func TestVoiceHandler_ResponseTransforms(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "child", Confidence: 0.9, Transcript: "hi"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "# Hello\n- **there**", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		ResponseTransforms: map[string]config.ResponseTransformConfig{
+			"child": {StripMarkdown: true, AppendSuffix: " Bye."},
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+	var resp voiceSuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if want := "Hello\nthere Bye."; resp.Response != want {
+		t.Errorf("expected %q, got %q", want, resp.Response)
+	}
+}
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	name string
+	data string
+}
+
+// parseSSE splits a Server-Sent Events body into events
+func parseSSE(t *testing.T, body string) []sseEvent {
+	t.Helper()
+
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestVoiceHandler_EventStream(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{
+				Status:     "identified",
+				UserID:     "mom",
+				Confidence: 0.9,
+				Transcript: "what time is it",
+			}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "it is noon", ModelUsed: "llama3.1:8b", UserID: req.UserID}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	events := parseSSE(t, w.Body.String())
+	var names []string
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	if got := strings.Join(names, ","); got != "received,transcribed,responded" {
+		t.Fatalf("expected events received,transcribed,responded, got %s", got)
+	}
+
+	var transcribed clients.VoiceResponse
+	if err := json.Unmarshal([]byte(events[1].data), &transcribed); err != nil {
+		t.Fatalf("failed to decode transcribed event: %v", err)
+	}
+	if transcribed.Transcript != "what time is it" {
+		t.Errorf("expected transcript 'what time is it', got %q", transcribed.Transcript)
+	}
+
+	var responded voiceSuccessResponse
+	if err := json.Unmarshal([]byte(events[2].data), &responded); err != nil {
+		t.Fatalf("failed to decode responded event: %v", err)
+	}
+	if responded.Response != "it is noon" {
+		t.Errorf("expected response 'it is noon', got %q", responded.Response)
+	}
+}
+
+func TestVoiceHandler_EventStreamLLMError(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "dad", Transcript: "hello"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return nil, fmt.Errorf("connection refused")
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	events := parseSSE(t, w.Body.String())
+	if len(events) != 3 || events[2].name != "error" {
+		t.Fatalf("expected received,transcribed,error, got %+v", events)
+	}
+
+	var errResp map[string]string
+	if err := json.Unmarshal([]byte(events[2].data), &errResp); err != nil {
+		t.Fatalf("failed to decode error event: %v", err)
+	}
+	if errResp["error"] != "llm sidecar unavailable" {
+		t.Errorf("expected error 'llm sidecar unavailable', got %q", errResp["error"])
+	}
+}
+
+func TestVoiceHandler_EventStreamNoSpeech(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "no_speech"}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	events := parseSSE(t, w.Body.String())
+	if len(events) != 2 || events[0].name != "received" || events[1].name != "responded" {
+		t.Fatalf("expected received,responded, got %+v", events)
+	}
+	if !strings.Contains(events[1].data, `"no_speech"`) {
+		t.Errorf("expected no_speech result, got %s", events[1].data)
+	}
+}
+
+func TestVoiceHandler_ContentFilter(t *testing.T) {
+	tests := []struct {
+		userID         string
+		wantStatus     int
+		wantTranscript string // Transcript forwarded to the LLM, empty when blocked
+	}{
+		{"child", http.StatusBadRequest, ""},
+		{"teen", http.StatusOK, "play that **** song"},
+		{"mom", http.StatusOK, "play that darn song"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					return &clients.VoiceResponse{Status: "identified", UserID: tt.userID, Confidence: 0.9, Transcript: "play that darn song"}, nil
+				},
+			}
+
+			var forwarded string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					forwarded = req.Message
+					return &clients.ChatResponse{Response: "ok", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				ContentFilter: config.ContentFilterConfig{
+					Words:    []string{"darn"},
+					Policies: map[string]string{"child": config.FilterBlock, "teen": config.FilterMask},
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+			req := createMultipartRequest(t, []byte("fake wav data"))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if forwarded != tt.wantTranscript {
+				t.Errorf("expected LLM to receive %q, got %q", tt.wantTranscript, forwarded)
+			}
+			if tt.wantStatus == http.StatusBadRequest && !strings.Contains(w.Body.String(), "content_blocked") {
+				t.Errorf("expected content_blocked code, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_MessageIDs(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "dad", Confidence: 0.9, Transcript: "hi"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "hello", UserID: req.UserID}, nil
+		},
+	}
+
+	// Create handler
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+	req := createAudioFormRequest(t, "/voice", []byte("fake wav data"), map[string]string{"request_id": "utterance-7"})
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var resp voiceSuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.MessageID == "" {
+		t.Error("expected a non-empty message_id")
+	}
+	if resp.RequestID != "utterance-7" {
+		t.Errorf("expected request_id 'utterance-7', got %q", resp.RequestID)
+	}
+}
+
+func TestVoiceHandler_ErrorEchoesRequestID(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return nil, fmt.Errorf("connection refused")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+	req := createAudioFormRequest(t, "/voice", []byte("fake wav data"), map[string]string{"request_id": "utterance-7"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if errResp["request_id"] != "utterance-7" {
+		t.Errorf("expected request_id 'utterance-7', got %v", errResp["request_id"])
+	}
+}
+
+func TestVoiceHandler_EmptyLLMResponse(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "dad", Confidence: 0.9, Transcript: "hello"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: " ", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{Chat: config.ChatConfig{EmptyResponseFallback: "Say that again?"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+	req := createMultipartRequest(t, []byte("fake wav data"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["response"] != "Say that again?" {
+		t.Errorf("expected the fallback message, got %v", resp["response"])
+	}
+	if resp["empty_response"] != true {
+		t.Errorf("expected empty_response to be set, got %v", resp["empty_response"])
+	}
+}
+
+func TestVoiceHandler_EmptyLLMResponseWithSuffix(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{Status: "identified", UserID: "dad", Confidence: 0.9, Transcript: "hello"}, nil
+		},
+	}
+	mockLLM := &mockLLMClient{
+		chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+			return &clients.ChatResponse{Response: "", UserID: req.UserID}, nil
+		},
+	}
+
+	cfg := &config.Config{
+		Chat:               config.ChatConfig{EmptyResponseFallback: "Say that again?"},
+		ResponseTransforms: map[string]config.ResponseTransformConfig{"dad": {AppendSuffix: " (AI)"}},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+	var resp voiceSuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if want := "Say that again? (AI)"; resp.Response != want {
+		t.Errorf("expected %q, got %q", want, resp.Response)
+	}
+	if !resp.EmptyResponse {
+		t.Error("expected empty_response to be set")
+	}
+}
+
+func TestVoiceHandler_RejectsNonAudioUploads(t *testing.T) {
+	png, err := os.ReadFile("testdata/pixel.png")
+	if err != nil {
+		t.Fatalf("failed to read PNG fixture: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantCode int
+	}{
+		{"real WAV", buildTestWAV(16000, 16000, 8000), http.StatusOK},
+		{"PNG image", png, http.StatusBadRequest},
+		{"PDF document", []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n"), http.StatusBadRequest},
+		{"RIFF but not WAVE", append([]byte("RIFF\x24\x00\x00\x00WEBPVP8 "), make([]byte, 32)...), http.StatusBadRequest},
+		{"WebM recording", append([]byte("\x1a\x45\xdf\xa3"), make([]byte, 32)...), http.StatusBadRequest},
+		{"Ogg recording", append([]byte("OggS\x00"), make([]byte, 32)...), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sidecarCalled := false
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					sidecarCalled = true
+					return &clients.VoiceResponse{Status: "no_speech"}, nil
+				},
+			}
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+			req := createMultipartRequest(t, tt.data)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode == http.StatusOK {
+				if !sidecarCalled {
+					t.Error("expected the WAV to reach the voice sidecar")
+				}
+				return
+			}
+
+			if sidecarCalled {
+				t.Error("expected the voice sidecar not to be called")
+			}
+			var resp map[string]interface{}
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp["code"] != "invalid_audio" {
+				t.Errorf("expected code invalid_audio, got %v", resp["code"])
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_QuietHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		hour       int
+		wantStatus int
+	}{
+		{"inside window", 22, http.StatusForbidden},
+		{"outside window", 16, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					return &clients.VoiceResponse{Status: "identified", UserID: "teen", Confidence: 0.9, Transcript: "hello"}, nil
+				},
+			}
+			llmCalled := false
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					llmCalled = true
+					return &clients.ChatResponse{Response: "hi", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{
+				QuietHours: map[string][]config.QuietWindow{"teen": {{Start: "21:00", End: "07:00"}}},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+			handler.quiet.now = func() time.Time {
+				return time.Date(2024, 3, 1, tt.hour, 0, 0, 0, time.Local)
+			}
+
+			req := createMultipartRequest(t, []byte("fake wav data"))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if llmCalled != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("expected LLM called=%v, got %v", tt.wantStatus == http.StatusOK, llmCalled)
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				var resp map[string]interface{}
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp["code"] != "quiet_hours" {
+					t.Errorf("expected code quiet_hours, got %v", resp["code"])
+				}
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_RawDebug(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		mockVoice := &mockVoiceClient{
+			processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+				return &clients.VoiceResponse{
+					Status: "identified", UserID: "dad", Confidence: 0.9, Transcript: "hello",
+					Raw: json.RawMessage(`{"status":"identified","scores":{"dad":0.9}}`),
+				}, nil
+			},
+		}
+		mockLLM := &mockLLMClient{
+			chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+				return &clients.ChatResponse{Response: "hi", UserID: req.UserID, Raw: json.RawMessage(`{"response":"hi"}`)}, nil
+			},
+		}
+
+		cfg := &config.Config{Debug: config.DebugConfig{RawResponses: enabled}}
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+		req := createMultipartRequest(t, []byte("fake wav data"))
+		req.URL.RawQuery = "debug=raw"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		debug, hasDebug := resp["_debug"].(map[string]interface{})
+		if !enabled {
+			if hasDebug {
+				t.Errorf("expected no _debug field when disabled, got %v", resp["_debug"])
+			}
+			continue
+		}
+		raw, _ := debug["raw"].(map[string]interface{})
+		if _, ok := raw["voice"].(map[string]interface{})["scores"]; !ok {
+			t.Errorf("expected the raw voice response, got %v", raw)
+		}
+		if raw["llm"].(map[string]interface{})["response"] != "hi" {
+			t.Errorf("expected the raw LLM response, got %v", raw)
+		}
+	}
+}
+
+func TestVoiceHandler_Candidates(t *testing.T) {
+	candidates := []clients.SpeakerCandidate{{UserID: "mom", Confidence: 0.61}, {UserID: "teen", Confidence: 0.58}}
+	tests := []struct {
+		name     string
+		voice    clients.VoiceResponse
+		wantUser string
+	}{
+		{
+			name:     "identified",
+			voice:    clients.VoiceResponse{Status: "identified", UserID: "mom", Confidence: 0.61, Transcript: "hi", Candidates: candidates},
+			wantUser: "mom",
+		},
+		{
+			name:     "no user falls back to the top candidate",
+			voice:    clients.VoiceResponse{Status: "identified", Transcript: "hi", Candidates: candidates},
+			wantUser: "mom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					resp := tt.voice
+					return &resp, nil
+				},
+			}
+			var llmUserID string
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					llmUserID = req.UserID
+					return &clients.ChatResponse{Response: "hello", UserID: req.UserID}, nil
+				},
+			}
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, mockLLM, &config.Config{}, logger)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp voiceSuccessResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if llmUserID != tt.wantUser || resp.UserID != tt.wantUser {
+				t.Errorf("expected %q answered, got LLM user %q and response user %q", tt.wantUser, llmUserID, resp.UserID)
+			}
+			if len(resp.Candidates) != 2 || resp.Candidates[1] != candidates[1] {
+				t.Errorf("expected the candidates passed through, got %+v", resp.Candidates)
+			}
+		})
+	}
+}
+
+func TestVoiceHandler_RejectedCandidates(t *testing.T) {
+	mockVoice := &mockVoiceClient{
+		processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+			return &clients.VoiceResponse{
+				Status:     "rejected",
+				Confidence: 0.4,
+				Candidates: []clients.SpeakerCandidate{{UserID: "mom", Confidence: 0.4}, {UserID: "teen", Confidence: 0.38}},
+			}, nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewVoiceHandler(mockVoice, nil, &config.Config{}, logger)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+	var resp struct {
+		Status     string                     `json:"status"`
+		Candidates []clients.SpeakerCandidate `json:"candidates"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "rejected" || len(resp.Candidates) != 2 || resp.Candidates[0].UserID != "mom" {
+		t.Errorf("expected the rejection to list the candidates, got %+v", resp)
+	}
+}
+
+func TestVoiceHandler_RoundsConfidence(t *testing.T) {
+	tests := []struct {
+		name     string
+		decimals int
+		want     string
+	}{
+		{name: "default", decimals: 0, want: `"confidence":0.89`},
+		{name: "three decimals", decimals: 3, want: `"confidence":0.887`},
+		{name: "full precision", decimals: -1, want: `"confidence":0.8867000001`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockVoice := &mockVoiceClient{
+				processFunc: func(ctx context.Context, wavData []byte) (*clients.VoiceResponse, error) {
+					return &clients.VoiceResponse{
+						Status:     "identified",
+						UserID:     "mom",
+						Confidence: 0.8867000001,
+						Transcript: "hi",
+						Candidates: []clients.SpeakerCandidate{{UserID: "mom", Confidence: 0.8867000001}},
+					}, nil
+				},
+			}
+			mockLLM := &mockLLMClient{
+				chatFunc: func(ctx context.Context, req *clients.ChatRequest) (*clients.ChatResponse, error) {
+					return &clients.ChatResponse{Response: "hello", UserID: req.UserID}, nil
+				},
+			}
+
+			cfg := &config.Config{Voice: config.VoiceConfig{ConfidenceDecimals: tt.decimals}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := NewVoiceHandler(mockVoice, mockLLM, cfg, logger)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, createMultipartRequest(t, []byte("fake wav data")))
+
+			// Compare the encoded JSON, where the extra digits would show
+			body := w.Body.String()
+			if strings.Count(body, tt.want) != 2 {
+				t.Errorf("expected the response and its candidate to carry %s, got %s", tt.want, body)
+			}
+		})
+	}
+}
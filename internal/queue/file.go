@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileQueue is a durable FIFO of JSON records, stored one per line in a file
+// so pending work survives restarts
+type FileQueue struct {
+	path    string
+	mu      sync.Mutex
+	drainMu sync.Mutex // Held for a whole Drain, so no record is handed out twice
+}
+
+// NewFileQueue opens the queue stored at path, creating its directory if needed
+func NewFileQueue(path string) (*FileQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	return &FileQueue{path: path}, nil
+}
+
+// Push appends a record and syncs it to disk before returning
+func (q *FileQueue) Push(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	f, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open queue: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return f.Sync()
+}
+
+// Len returns the number of pending records
+func (q *FileQueue) Len() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	records, err := q.read()
+	return len(records), err
+}
+
+// Drain hands pending records to fn in order, stopping at the first error.
+// Records fn accepted are removed; the rest stay queued. Drains run one at a
+// time, but the file lock is not held while fn runs, so Push is never blocked
+// by a slow consumer.
+func (q *FileQueue) Drain(fn func(record json.RawMessage) error) (int, error) {
+	q.drainMu.Lock()
+	defer q.drainMu.Unlock()
+
+	q.mu.Lock()
+	records, err := q.read()
+	q.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	done := 0
+	var fnErr error
+	for _, record := range records {
+		if fnErr = fn(record); fnErr != nil {
+			break
+		}
+		done++
+	}
+
+	if done > 0 {
+		if err := q.removeFirst(done); err != nil {
+			return 0, err
+		}
+	}
+	return done, fnErr
+}
+
+// read loads every record in the file. The caller must hold q.mu.
+func (q *FileQueue) read() ([]json.RawMessage, error) {
+	data, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+
+	var records []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			records = append(records, json.RawMessage(append([]byte(nil), line...)))
+		}
+	}
+	return records, scanner.Err()
+}
+
+// removeFirst drops the n oldest records. Records pushed meanwhile were
+// appended, so they are kept.
+func (q *FileQueue) removeFirst(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	records, err := q.read()
+	if err != nil {
+		return err
+	}
+	if n > len(records) {
+		n = len(records)
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records[n:] {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+
+	// Replace the file atomically so a crash never leaves it half written
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("failed to replace queue: %w", err)
+	}
+	return nil
+}
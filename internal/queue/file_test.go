@@ -0,0 +1,116 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileQueue_PushAndDrain(t *testing.T) {
+	q, err := NewFileQueue(filepath.Join(t.TempDir(), "nested", "queue.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileQueue failed: %v", err)
+	}
+
+	for _, n := range []int{1, 2, 3} {
+		if err := q.Push(map[string]int{"n": n}); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+	}
+	if n, _ := q.Len(); n != 3 {
+		t.Fatalf("expected 3 records, got %d", n)
+	}
+
+	var seen []int
+	sent, err := q.Drain(func(record json.RawMessage) error {
+		var r struct{ N int }
+		json.Unmarshal(record, &r)
+		seen = append(seen, r.N)
+		return nil
+	})
+	if err != nil || sent != 3 {
+		t.Fatalf("expected 3 drained without error, got %d, %v", sent, err)
+	}
+	if len(seen) != 3 || seen[0] != 1 || seen[2] != 3 {
+		t.Errorf("expected records in push order, got %v", seen)
+	}
+	if n, _ := q.Len(); n != 0 {
+		t.Errorf("expected empty queue, got %d", n)
+	}
+}
+
+func TestFileQueue_DrainStopsAtFirstError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	q, _ := NewFileQueue(path)
+	q.Push("a")
+	q.Push("b")
+	q.Push("c")
+
+	down := errors.New("sidecar down")
+	sent, err := q.Drain(func(record json.RawMessage) error {
+		if string(record) == `"b"` {
+			return down
+		}
+		return nil
+	})
+	if !errors.Is(err, down) || sent != 1 {
+		t.Fatalf("expected 1 drained and the callback error, got %d, %v", sent, err)
+	}
+
+	// The queue is durable: a fresh handle sees the records left behind
+	reopened, _ := NewFileQueue(path)
+	if n, _ := reopened.Len(); n != 2 {
+		t.Errorf("expected 2 records left, got %d", n)
+	}
+}
+
+func TestFileQueue_ConcurrentDrains(t *testing.T) {
+	q, _ := NewFileQueue(filepath.Join(t.TempDir(), "queue.jsonl"))
+	q.Push("a")
+	q.Push("b")
+	q.Push("c")
+
+	var mu sync.Mutex
+	delivered := 0
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		q.Drain(func(record json.RawMessage) error {
+			mu.Lock()
+			delivered++
+			if delivered == 1 {
+				close(started)
+			}
+			mu.Unlock()
+			<-release // Hold the first drain open while the second starts
+			return nil
+		})
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		q.Drain(func(record json.RawMessage) error {
+			mu.Lock()
+			delivered++
+			mu.Unlock()
+			return nil
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if delivered != 3 {
+		t.Errorf("expected each record delivered once, got %d deliveries", delivered)
+	}
+	if n, _ := q.Len(); n != 0 {
+		t.Errorf("expected empty queue, got %d", n)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIPResolver finds the address of the client behind a request. The
+// X-Forwarded-For and X-Real-IP headers are only believed when the request
+// comes from a trusted proxy; anyone else could forge them.
+type clientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// newClientIPResolver parses the trusted proxies, given as IPs or CIDR ranges
+func newClientIPResolver(proxies []string) (*clientIPResolver, error) {
+	c := &clientIPResolver{}
+	for _, proxy := range proxies {
+		network, err := parseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		c.trusted = append(c.trusted, network)
+	}
+	return c, nil
+}
+
+// parseProxy reads an IP or CIDR range; a single IP becomes a one-address network
+func parseProxy(proxy string) (*net.IPNet, error) {
+	if strings.Contains(proxy, "/") {
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		return network, nil
+	}
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// isTrusted reports whether ip belongs to a trusted proxy
+func (c *clientIPResolver) isTrusted(ip net.IP) bool {
+	if c == nil || ip == nil {
+		return false
+	}
+	for _, network := range c.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the client's IP. Behind trusted proxies it walks
+// X-Forwarded-For from the right, skipping the proxies' own hops, and
+// falls back to X-Real-IP; otherwise it is the connection's peer.
+func (c *clientIPResolver) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if !c.isTrusted(peer) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		var leftmost net.IP
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				// A garbled hop ends the chain we can vouch for
+				break
+			}
+			if !c.isTrusted(ip) {
+				return ip
+			}
+			leftmost = ip
+		}
+		if leftmost != nil {
+			return leftmost
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip
+	}
+	return peer
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	ips, err := newClientIPResolver([]string{"10.0.0.1", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatalf("newClientIPResolver failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:4242", "198.51.100.7", "198.51.100.8", "203.0.113.9"},
+		{"trusted proxy forwards client", "10.0.0.1:4242", "198.51.100.7", "", "198.51.100.7"},
+		{"skips trusted hops from the right", "10.0.0.1:4242", "198.51.100.7, 203.0.113.5, 192.168.1.20", "", "203.0.113.5"},
+		{"spoofed leftmost entry is not believed", "10.0.0.1:4242", "127.0.0.1, 198.51.100.7", "", "198.51.100.7"},
+		{"only trusted hops uses the leftmost", "10.0.0.1:4242", "192.168.1.20, 192.168.1.21", "", "192.168.1.20"},
+		{"trusted proxy with X-Real-IP", "192.168.3.4:4242", "", "198.51.100.8", "198.51.100.8"},
+		{"trusted proxy without headers", "10.0.0.1:4242", "", "", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := ips.clientIP(req).String(); got != tt.want {
+				t.Errorf("expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewClientIPResolver_InvalidProxy(t *testing.T) {
+	if _, err := newClientIPResolver([]string{"not-an-ip"}); err == nil {
+		t.Error("expected error for an invalid trusted proxy")
+	}
+}
+
+func TestLocalhostOnly_BehindTrustedProxy(t *testing.T) {
+	ips, _ := newClientIPResolver([]string{"127.0.0.1"})
+	handler := localhostOnly(ips, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// A local reverse proxy relaying a remote client must not unlock pprof
+	req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+	req.RemoteAddr = "127.0.0.1:4242"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a forwarded remote client, got %d", w.Code)
+	}
+
+	// Without a trusted proxy the header is ignored
+	req = httptest.NewRequest("GET", "/debug/pprof/", nil)
+	req.RemoteAddr = "127.0.0.1:4242"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	w = httptest.NewRecorder()
+	localhostOnly(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a loopback peer with an untrusted header, got %d", w.Code)
+	}
+}
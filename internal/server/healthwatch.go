@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/assistant/orchestrator/internal/handlers"
+)
+
+// healthWatcher periodically checks every sidecar, logging only when a
+// sidecar changes between ok and unreachable, and caches the latest state
+// for the /health handler
+type healthWatcher struct {
+	logger            *slog.Logger
+	sidecars          map[string]healthChecker
+	interval          time.Duration
+	failureThreshold  int
+	recoveryThreshold int
+	webhook           *healthWebhook // Told about transitions, nil when not configured
+
+	mu     sync.RWMutex
+	states map[string]*watchedSidecar // Empty until the first round completes
+}
+
+// watchedSidecar is the debounced health of one sidecar
+type watchedSidecar struct {
+	healthy bool
+	latency time.Duration
+	streak  int // Consecutive results contradicting healthy
+}
+
+func newHealthWatcher(logger *slog.Logger, sidecars map[string]healthChecker, interval time.Duration, failureThreshold, recoveryThreshold int) *healthWatcher {
+	return &healthWatcher{
+		logger:            logger,
+		sidecars:          sidecars,
+		interval:          interval,
+		failureThreshold:  failureThreshold,
+		recoveryThreshold: recoveryThreshold,
+		states:            make(map[string]*watchedSidecar),
+	}
+}
+
+// run checks the sidecars every interval until ctx is cancelled
+func (hw *healthWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(hw.interval)
+	defer ticker.Stop()
+
+	for {
+		hw.checkAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAll runs one round of health checks in parallel and records the results
+func (hw *healthWatcher) checkAll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, hw.interval)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for name, sidecar := range hw.sidecars {
+		wg.Add(1)
+		go func(name string, sidecar healthChecker) {
+			defer wg.Done()
+			latency, err := sidecar.Health(ctx)
+			hw.record(name, latency, err)
+		}(name, sidecar)
+	}
+	wg.Wait()
+}
+
+// record applies one check result, flipping the sidecar's state once enough
+// consecutive results disagree with it
+func (hw *healthWatcher) record(name string, latency time.Duration, err error) {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+
+	healthy := err == nil
+	state, seen := hw.states[name]
+	if !seen {
+		// The first result is taken as-is; only an unreachable start is worth a log
+		hw.states[name] = &watchedSidecar{healthy: healthy, latency: latency}
+		if !healthy {
+			hw.logger.Warn("sidecar unreachable", "sidecar", name, "error", err)
+			hw.webhook.notify(unreachableEvent(name, err))
+		}
+		return
+	}
+
+	if healthy {
+		state.latency = latency
+	}
+
+	if healthy == state.healthy {
+		state.streak = 0
+		return
+	}
+
+	state.streak++
+	threshold := hw.failureThreshold
+	if healthy {
+		threshold = hw.recoveryThreshold
+	}
+	if state.streak < threshold {
+		return
+	}
+
+	state.healthy = healthy
+	state.streak = 0
+	if healthy {
+		hw.logger.Info("sidecar recovered", "sidecar", name, "from", "unreachable", "to", "ok", "latency_ms", latency.Milliseconds())
+		hw.webhook.notify(healthEvent{Event: eventSidecarRecovered, Sidecar: name, Status: "ok", LatencyMs: latency.Milliseconds(), Time: time.Now()})
+	} else {
+		hw.logger.Warn("sidecar became unreachable", "sidecar", name, "from", "ok", "to", "unreachable", "error", err)
+		hw.webhook.notify(unreachableEvent(name, err))
+	}
+}
+
+// unreachableEvent describes a sidecar found unreachable for the webhook
+func unreachableEvent(name string, err error) healthEvent {
+	return healthEvent{Event: eventSidecarUnreachable, Sidecar: name, Status: "unreachable", Error: err.Error(), Time: time.Now()}
+}
+
+// SidecarStatuses implements handlers.HealthSource
+func (hw *healthWatcher) SidecarStatuses() (map[string]handlers.SidecarStatus, bool) {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+
+	if len(hw.states) < len(hw.sidecars) {
+		return nil, false
+	}
+
+	statuses := make(map[string]handlers.SidecarStatus, len(hw.states))
+	for name, state := range hw.states {
+		statuses[name] = handlers.SidecarStatus{Healthy: state.healthy, Latency: state.latency}
+	}
+	return statuses, true
+}
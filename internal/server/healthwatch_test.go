@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedSidecar answers health checks from a fixed sequence of results
+type scriptedSidecar struct {
+	mu      sync.Mutex
+	healthy []bool
+	calls   int
+}
+
+func (s *scriptedSidecar) Health(ctx context.Context) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok := s.healthy[s.calls%len(s.healthy)]
+	s.calls++
+	if !ok {
+		return 0, errors.New("connection refused")
+	}
+	return 5 * time.Millisecond, nil
+}
+
+// recordingHandler captures log messages for assertions
+type recordingHandler struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (h *recordingHandler) Enabled(ctx context.Context, level slog.Level) bool { return true }
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler           { return h }
+func (h *recordingHandler) WithGroup(name string) slog.Handler                 { return h }
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, r.Message)
+	return nil
+}
+
+func (h *recordingHandler) count(message string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, m := range h.messages {
+		if m == message {
+			n++
+		}
+	}
+	return n
+}
+
+func TestHealthWatcher_LogsEachTransitionOnce(t *testing.T) {
+	// ok, then down for two rounds, up for two, down, up
+	flapping := &scriptedSidecar{healthy: []bool{true, false, false, true, true, false, true}}
+	steady := &scriptedSidecar{healthy: []bool{true}}
+
+	logs := &recordingHandler{}
+	watcher := newHealthWatcher(slog.New(logs), map[string]healthChecker{
+		"llm":   flapping,
+		"voice": steady,
+	}, time.Second, 1, 1)
+
+	for i := 0; i < len(flapping.healthy); i++ {
+		watcher.checkAll(context.Background())
+	}
+
+	if got := logs.count("sidecar became unreachable"); got != 2 {
+		t.Errorf("expected 2 ok->unreachable transitions, got %d", got)
+	}
+	if got := logs.count("sidecar recovered"); got != 2 {
+		t.Errorf("expected 2 unreachable->ok transitions, got %d", got)
+	}
+
+	statuses, ok := watcher.SidecarStatuses()
+	if !ok {
+		t.Fatal("expected statuses to be ready")
+	}
+	if !statuses["llm"].Healthy || !statuses["voice"].Healthy {
+		t.Errorf("expected both sidecars healthy after the last round, got %+v", statuses)
+	}
+}
+
+func TestHealthWatcher_FailureThresholdDebouncesBlips(t *testing.T) {
+	// A single failed check is not enough to flip with a threshold of 2
+	blip := &scriptedSidecar{healthy: []bool{true, false, true, false, false, true}}
+
+	logs := &recordingHandler{}
+	watcher := newHealthWatcher(slog.New(logs), map[string]healthChecker{"llm": blip}, time.Second, 2, 1)
+
+	for i := 0; i < 3; i++ {
+		watcher.checkAll(context.Background())
+	}
+	if got := logs.count("sidecar became unreachable"); got != 0 {
+		t.Fatalf("expected a single failure to be ignored, got %d transitions", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		watcher.checkAll(context.Background())
+	}
+	if got := logs.count("sidecar became unreachable"); got != 1 {
+		t.Errorf("expected one transition after two failures, got %d", got)
+	}
+	statuses, _ := watcher.SidecarStatuses()
+	if statuses["llm"].Healthy {
+		t.Error("expected llm to be reported unreachable")
+	}
+
+	watcher.checkAll(context.Background())
+	if got := logs.count("sidecar recovered"); got != 1 {
+		t.Errorf("expected one recovery, got %d", got)
+	}
+}
+
+func TestHealthWatcher_NotReadyBeforeFirstRound(t *testing.T) {
+	watcher := newHealthWatcher(slog.New(&recordingHandler{}), map[string]healthChecker{
+		"llm": &scriptedSidecar{healthy: []bool{true}},
+	}, time.Second, 1, 1)
+
+	if _, ok := watcher.SidecarStatuses(); ok {
+		t.Error("expected statuses to be unavailable before the first round")
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/queue"
+)
+
+// drainLearningQueue resubmits queued learning requests every interval until
+// ctx is cancelled
+func drainLearningQueue(ctx context.Context, logger *slog.Logger, q *queue.FileQueue, client clients.LearningClientInterface, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		flushLearningQueue(ctx, logger, q, client)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// flushLearningQueueOnShutdown makes a final attempt to send queued learning
+// requests within the shutdown deadline
+func (s *Server) flushLearningQueueOnShutdown(ctx context.Context) {
+	flushLearningQueue(ctx, s.logger, s.learningQueue, s.learningClient)
+	if n, err := s.learningQueue.Len(); err == nil && n > 0 {
+		s.logger.Warn("learn requests left queued at shutdown", "count", n, "path", s.config.LearningQueue.GetPath())
+	}
+}
+
+// flushLearningQueue resubmits queued learning requests in order, stopping at
+// the first one the sidecar still cannot take. Submissions the sidecar
+// refuses outright are dropped so they cannot block the queue.
+func flushLearningQueue(ctx context.Context, logger *slog.Logger, q *queue.FileQueue, client clients.LearningClientInterface) {
+	sent, err := q.Drain(func(record json.RawMessage) error {
+		var req clients.LearningRequest
+		if err := json.Unmarshal(record, &req); err != nil {
+			logger.Warn("dropping unreadable queued learn request", "error", err)
+			return nil
+		}
+
+		_, err := client.Submit(ctx, &req)
+		var rejected *clients.RejectedError
+		if errors.As(err, &rejected) {
+			logger.Warn("dropping queued learn request rejected by sidecar", "user_id", req.UserID, "error", err)
+			return nil
+		}
+		return err
+	})
+
+	if sent > 0 {
+		logger.Info("flushed queued learn requests", "count", sent)
+	}
+	if err != nil {
+		logger.Debug("learning queue not drained", "error", err)
+	}
+}
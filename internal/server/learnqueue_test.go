@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/internal/queue"
+)
+
+// switchableLearningClient fails submissions while down and records the rest
+type switchableLearningClient struct {
+	down      bool
+	submitted []string
+}
+
+func (c *switchableLearningClient) Submit(ctx context.Context, req *clients.LearningRequest) (*clients.LearningResponse, error) {
+	if c.down {
+		return nil, errors.New("connection refused")
+	}
+	c.submitted = append(c.submitted, req.Content)
+	return &clients.LearningResponse{ID: "id", Status: "processing"}, nil
+}
+
+func (c *switchableLearningClient) Health(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
+func TestFlushLearningQueue_SendsOnceSidecarIsBack(t *testing.T) {
+	q, err := queue.NewFileQueue(filepath.Join(t.TempDir(), "learning.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileQueue failed: %v", err)
+	}
+	q.Push(&clients.LearningRequest{UserID: "dad", Content: "first", Source: "user_correction"})
+	q.Push(&clients.LearningRequest{UserID: "dad", Content: "second", Source: "user_correction"})
+
+	client := &switchableLearningClient{down: true}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	flushLearningQueue(context.Background(), logger, q, client)
+	if n, _ := q.Len(); n != 2 {
+		t.Fatalf("expected submissions to stay queued while down, got %d", n)
+	}
+
+	client.down = false
+	flushLearningQueue(context.Background(), logger, q, client)
+	if n, _ := q.Len(); n != 0 {
+		t.Errorf("expected queue to be flushed, got %d left", n)
+	}
+	if len(client.submitted) != 2 || client.submitted[0] != "first" || client.submitted[1] != "second" {
+		t.Errorf("expected submissions in order, got %v", client.submitted)
+	}
+}
+
+func TestFlushLearningQueue_DropsRejected(t *testing.T) {
+	q, _ := queue.NewFileQueue(filepath.Join(t.TempDir(), "learning.jsonl"))
+	q.Push(&clients.LearningRequest{UserID: "dad", Content: "bad"})
+
+	client := &mockRejectingLearningClient{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	flushLearningQueue(context.Background(), logger, q, client)
+
+	if n, _ := q.Len(); n != 0 {
+		t.Errorf("expected rejected submission to be dropped, got %d left", n)
+	}
+}
+
+// mockRejectingLearningClient refuses every submission with a 4xx
+type mockRejectingLearningClient struct{}
+
+func (mockRejectingLearningClient) Submit(ctx context.Context, req *clients.LearningRequest) (*clients.LearningResponse, error) {
+	return nil, &clients.RejectedError{StatusCode: 400, Body: "invalid"}
+}
+
+func (mockRejectingLearningClient) Health(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
+func TestServer_ShutdownFlushesLearningQueue(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.LearningQueue = config.LearningQueueConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "learning.jsonl")}
+	srv := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	client := &switchableLearningClient{}
+	srv.learningClient = client
+	srv.learningQueue.Push(&clients.LearningRequest{UserID: "dad", Content: "pending", Source: "user_correction"})
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if len(client.submitted) != 1 || client.submitted[0] != "pending" {
+		t.Errorf("expected shutdown to flush the queue, got %v", client.submitted)
+	}
+	if n, _ := srv.learningQueue.Len(); n != 0 {
+		t.Errorf("expected an empty queue after shutdown, got %d", n)
+	}
+}
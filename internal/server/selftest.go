@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// selfTestOrder lists the sidecars in the order the self-test reports them
+var selfTestOrder = []string{"voice", "llm", "learning"}
+
+// selfTestResult is the outcome of pinging one sidecar
+type selfTestResult struct {
+	latency time.Duration
+	err     error
+}
+
+// SelfTest pings every sidecar once, writes a reachability table to w and
+// reports whether all required sidecars answered. The learning sidecar is
+// optional while submissions can be queued for it.
+func (s *Server) SelfTest(ctx context.Context, w io.Writer) bool {
+	optional := map[string]bool{"learning": s.learningQueue != nil}
+	return selfTest(ctx, w, s.sidecars, optional, s.config.Sidecars.GetHealthCheckTimeout())
+}
+
+// selfTest pings the sidecars concurrently, each under its own timeout, and
+// prints one row per sidecar
+func selfTest(ctx context.Context, w io.Writer, sidecars map[string]healthChecker, optional map[string]bool, timeout time.Duration) bool {
+	results := make(map[string]selfTestResult, len(sidecars))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, sidecar := range sidecars {
+		wg.Add(1)
+		go func(name string, sidecar healthChecker) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			latency, err := sidecar.Health(checkCtx)
+
+			mu.Lock()
+			results[name] = selfTestResult{latency: latency, err: err}
+			mu.Unlock()
+		}(name, sidecar)
+	}
+	wg.Wait()
+
+	passed := true
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIDECAR\tREQUIRED\tSTATUS\tLATENCY\tERROR")
+	for _, name := range selfTestOrder {
+		result, ok := results[name]
+		if !ok {
+			continue
+		}
+
+		required := "yes"
+		if optional[name] {
+			required = "no"
+		}
+
+		if result.err != nil {
+			if !optional[name] {
+				passed = false
+			}
+			fmt.Fprintf(tw, "%s\t%s\tdown\t-\t%v\n", name, required, result.err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\tok\t%dms\t\n", name, required, result.latency.Milliseconds())
+	}
+	tw.Flush()
+
+	return passed
+}
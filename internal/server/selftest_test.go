@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSidecar answers health checks with a fixed latency and error
+type stubSidecar struct {
+	latency time.Duration
+	err     error
+}
+
+func (s stubSidecar) Health(ctx context.Context) (time.Duration, error) {
+	return s.latency, s.err
+}
+
+func TestSelfTest_AllReachable(t *testing.T) {
+	var out bytes.Buffer
+	passed := selfTest(context.Background(), &out, map[string]healthChecker{
+		"voice":    stubSidecar{latency: 12 * time.Millisecond},
+		"llm":      stubSidecar{latency: 8 * time.Millisecond},
+		"learning": stubSidecar{latency: 5 * time.Millisecond},
+	}, nil, time.Second)
+
+	if !passed {
+		t.Errorf("expected the self-test to pass, got:\n%s", out.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and 3 rows, got:\n%s", out.String())
+	}
+	for i, name := range []string{"voice", "llm", "learning"} {
+		if fields := strings.Fields(lines[i+1]); fields[0] != name || fields[2] != "ok" {
+			t.Errorf("expected row %d to report %s ok, got %q", i+1, name, lines[i+1])
+		}
+	}
+	if !strings.Contains(lines[1], "12ms") {
+		t.Errorf("expected the voice latency in its row, got %q", lines[1])
+	}
+}
+
+func TestSelfTest_RequiredSidecarDown(t *testing.T) {
+	var out bytes.Buffer
+	passed := selfTest(context.Background(), &out, map[string]healthChecker{
+		"voice":    stubSidecar{},
+		"llm":      stubSidecar{err: errors.New("connection refused")},
+		"learning": stubSidecar{},
+	}, nil, time.Second)
+
+	if passed {
+		t.Error("expected the self-test to fail when the LLM sidecar is down")
+	}
+	if !strings.Contains(out.String(), "connection refused") {
+		t.Errorf("expected the error in the summary, got:\n%s", out.String())
+	}
+}
+
+func TestSelfTest_OptionalSidecarDown(t *testing.T) {
+	var out bytes.Buffer
+	passed := selfTest(context.Background(), &out, map[string]healthChecker{
+		"voice":    stubSidecar{},
+		"llm":      stubSidecar{},
+		"learning": stubSidecar{err: errors.New("connection refused")},
+	}, map[string]bool{"learning": true}, time.Second)
+
+	if !passed {
+		t.Errorf("expected an optional sidecar being down not to fail the self-test, got:\n%s", out.String())
+	}
+}
+
+func TestSelfTest_TimesOutHangingSidecar(t *testing.T) {
+	var out bytes.Buffer
+	hanging := healthCheckerFunc(func(ctx context.Context) (time.Duration, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	start := time.Now()
+	passed := selfTest(context.Background(), &out, map[string]healthChecker{"voice": hanging}, nil, 20*time.Millisecond)
+
+	if passed {
+		t.Error("expected a hanging sidecar to fail the self-test")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the check to give up after its timeout, took %v", elapsed)
+	}
+}
+
+// healthCheckerFunc adapts a function to healthChecker
+type healthCheckerFunc func(ctx context.Context) (time.Duration, error)
+
+func (f healthCheckerFunc) Health(ctx context.Context) (time.Duration, error) {
+	return f(ctx)
+}
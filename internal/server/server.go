@@ -2,81 +2,336 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/assistant/orchestrator/internal/clients"
 	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/internal/evallog"
 	"github.com/assistant/orchestrator/internal/handlers"
+	"github.com/assistant/orchestrator/internal/queue"
+	"github.com/assistant/orchestrator/pkg/apierror"
 )
 
+// writeTimeoutGrace is the extra time given to the connection write deadline
+// beyond the longest route timeout
+const writeTimeoutGrace = 5 * time.Second
+
 // Server represents the HTTP server
 type Server struct {
 	httpServer *http.Server
 	logger     *slog.Logger
+	config     *config.Config
+	sidecars   map[string]healthChecker
+	watcher    *healthWatcher // nil when the health watch is disabled
+
+	learningClient clients.LearningClientInterface
+	learningQueue  *queue.FileQueue // nil when learning submissions are not queued
+
+	state atomic.Value // Lifecycle state reported by /health, see OrchestratorState
+
+	stopBackground context.CancelFunc
+	shutdownHooks  []func(ctx context.Context)
 }
 
 // New creates a new HTTP server with configured routes and middleware
 func New(cfg *config.Config, logger *slog.Logger) *Server {
 	// Create sidecar clients
-	voiceClient := clients.NewVoiceClient(
-		cfg.Sidecars.VoiceURL,
-		cfg.Sidecars.GetSidecarTimeout(),
+	var (
+		voiceClient    clients.VoiceClientInterface
+		llmClient      clients.LLMClientInterface
+		learningClient clients.LearningClientInterface
 	)
 
-	llmClient := clients.NewLLMClient(
-		cfg.Sidecars.LLMURL,
-		cfg.Sidecars.GetSidecarTimeout(),
-	)
+	if cfg.IsDryRun() {
+		logger.Warn("dry-run mode enabled, using stub sidecar clients")
+		voiceClient = clients.NewStubVoiceClient()
+		llmClient = clients.NewStubLLMClient()
+		learningClient = clients.NewStubLearningClient()
+	} else {
+		// All three clients share one proxied transport when a proxy is set
+		var opts []clients.ClientOption
+		if proxyURL, err := cfg.Sidecars.GetHTTPProxy(); err != nil {
+			logger.Error("ignoring http_proxy", "error", err)
+		} else if proxyURL != nil {
+			logger.Info("reaching sidecars through a proxy", "proxy", proxyURL.Redacted())
+			opts = append(opts, clients.WithTransport(clients.NewProxyTransport(proxyURL)))
+		}
 
-	learningClient := clients.NewLearningClient(
-		cfg.Sidecars.LearningURL,
-		cfg.Sidecars.GetSidecarTimeout(),
-	)
+		voice := clients.NewVoiceClient(
+			cfg.Sidecars.VoiceURL,
+			cfg.Sidecars.GetSidecarTimeout(),
+			opts...,
+		)
+
+		llm := clients.NewBalancedLLMClient(
+			cfg.Sidecars.LLMURL,
+			cfg.Sidecars.GetSidecarTimeout(),
+			opts...,
+		)
+
+		learning := clients.NewLearningClient(
+			cfg.Sidecars.LearningURL,
+			cfg.Sidecars.GetSidecarTimeout(),
+			opts...,
+		)
+
+		if ua := cfg.Sidecars.UserAgent; ua != "" {
+			voice.SetUserAgent(ua)
+			llm.SetUserAgent(ua)
+			learning.SetUserAgent(ua)
+		}
+		voiceClient, llmClient, learningClient = voice, llm, learning
+	}
+
+	if limit := cfg.Sidecars.LLMConcurrency; limit.MaxInFlight > 0 {
+		llmClient = clients.NewLimitedLLMClient(llmClient, limit.MaxInFlight, limit.MaxQueued, limit.GetQueueTimeout())
+	}
 
 	// Create handlers
 	chatHandler := handlers.NewChatHandler(llmClient, cfg, logger)
-	voiceHandler := handlers.NewVoiceHandler(voiceClient, llmClient, logger)
+	voiceHandler := handlers.NewVoiceHandler(voiceClient, llmClient, cfg, logger)
+	reidentifyHandler := handlers.NewReidentifyHandler(voiceClient, cfg, logger)
+	enrollHandler := handlers.NewEnrollHandler(voiceClient, cfg, logger)
 	learnHandler := handlers.NewLearnHandler(learningClient, cfg, logger)
-	healthHandler := handlers.NewHealthHandler(voiceClient, llmClient, learningClient, logger)
+	learnStreamHandler := handlers.NewLearnStreamHandler(learningClient, cfg, logger)
+	healthHandler := handlers.NewHealthHandler(voiceClient, llmClient, learningClient, cfg, logger)
+	capabilitiesHandler := handlers.NewCapabilitiesHandler(voiceClient, llmClient, learningClient, cfg, logger)
+
+	var learningQueue *queue.FileQueue
+	if cfg.LearningQueue.Enabled {
+		q, err := queue.NewFileQueue(cfg.LearningQueue.GetPath())
+		if err != nil {
+			logger.Error("learning queue disabled", "path", cfg.LearningQueue.GetPath(), "error", err)
+		} else {
+			learningQueue = q
+			learnHandler.UseQueue(q)
+		}
+	}
+
+	var evalSampler *evallog.Sampler
+	if cfg.EvalLog.Enabled {
+		sampler, err := evallog.New(cfg.EvalLog.GetPath(), evallog.Options{
+			SampleRate:   cfg.EvalLog.SampleRate,
+			MaxFileBytes: cfg.EvalLog.GetMaxFileBytes(),
+			MaxFiles:     cfg.EvalLog.GetMaxFiles(),
+			BufferSize:   cfg.EvalLog.GetBufferSize(),
+		}, logger)
+		if err != nil {
+			logger.Error("eval log disabled", "path", cfg.EvalLog.GetPath(), "error", err)
+		} else {
+			evalSampler = sampler
+			chatHandler.UseEvalLog(sampler)
+			voiceHandler.UseEvalLog(sampler)
+		}
+	}
+
+	sidecars := map[string]healthChecker{
+		"voice":    voiceClient,
+		"llm":      llmClient,
+		"learning": learningClient,
+	}
+
+	var watcher *healthWatcher
+	if cfg.HealthWatch.Enabled {
+		watcher = newHealthWatcher(logger, sidecars,
+			cfg.HealthWatch.GetInterval(),
+			cfg.HealthWatch.GetFailureThreshold(),
+			cfg.HealthWatch.GetRecoveryThreshold())
+		if webhook := cfg.HealthWatch.Webhook; webhook.URL != "" {
+			watcher.webhook = newHealthWebhook(logger, webhook.URL, webhook.GetTimeout(), webhook.GetDebounce())
+		}
+		healthHandler.UseSource(watcher)
+	}
+
+	srv := &Server{
+		logger:   logger,
+		config:   cfg,
+		sidecars: sidecars,
+		watcher:  watcher,
+
+		learningClient: learningClient,
+		learningQueue:  learningQueue,
+	}
+	srv.state.Store(handlers.OrchestratorStarting)
+	healthHandler.UseReadiness(srv)
+
+	// Conversations wait for warm-up when gated; everything else, /health
+	// included, answers from the start
+	gated := func(handler http.Handler) http.Handler {
+		if !cfg.Warmup.Enabled || !cfg.Warmup.Gate {
+			return handler
+		}
+		return readyGateMiddleware(srv, cfg.Warmup.GetInterval(), handler)
+	}
+
+	ips, err := newClientIPResolver(cfg.Server.TrustedProxies)
+	if err != nil {
+		logger.Error("ignoring trusted proxies", "error", err)
+		ips = &clientIPResolver{}
+	}
 
 	// Setup routes
 	mux := http.NewServeMux()
-	mux.Handle("/chat", loggingMiddleware(logger, chatHandler))
-	mux.Handle("/voice", loggingMiddleware(logger, voiceHandler))
-	mux.Handle("/learn", loggingMiddleware(logger, learnHandler))
-	mux.Handle("/health", loggingMiddleware(logger, healthHandler))
+	route := func(path string, handler http.Handler) {
+		if !cfg.Server.IsRouteEnabled(path) {
+			logger.Info("route disabled", "path", path)
+			return
+		}
+		handler = requestTimeoutMiddleware(logger, cfg.Server.GetRouteTimeout(path), cfg.Server.GetMaxRequestTimeout(path), handler)
+		handler = bodyLimitMiddleware(cfg.Server.GetRouteMaxBodyBytes(path), handler)
+		handler = headerLimitMiddleware(cfg.Server.GetMaxHeaderCount(), handler)
+		mux.Handle(path, loggingMiddleware(logger, ips, handler))
+	}
+	route("/chat", gated(chatHandler))
+	route("/voice", gated(voiceHandler))
+	route("/reidentify", reidentifyHandler)
+	route("/enroll", enrollHandler)
+	route("/learn", learnHandler)
+	route("/learn/stream", learnStreamHandler)
+	route("/health", healthHandler)
+	for _, name := range []string{"voice", "llm", "learning"} {
+		route("/health/"+name, healthHandler.SidecarHandler(name))
+	}
+	route("/capabilities", capabilitiesHandler)
+	// Admin users are named by an unauthenticated header, so admin routes,
+	// like pprof, only answer local clients
+	route("/admin/cache/clear", localhostOnly(ips, handlers.RequireAdmin(cfg, logger, handlers.NewClearCacheHandler(chatHandler, logger))))
+
+	// Profiling endpoints are opt-in and never run under the route timeout,
+	// since CPU profiles and traces stream for as long as requested
+	if cfg.Debug.PprofEnabled {
+		logger.Warn("pprof endpoints enabled on /debug/pprof/ (localhost only)")
+		mux.Handle("/debug/pprof/", loggingMiddleware(logger, ips, localhostOnly(ips, pprofHandler())))
+	}
 
-	// Create HTTP server
-	httpServer := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      mux,
-		ReadTimeout:  cfg.Server.GetReadTimeout(),
-		WriteTimeout: cfg.Server.GetWriteTimeout(),
+	// Create HTTP server. The connection write deadline must outlast the
+	// slowest route so its timeout response can still be delivered.
+	srv.httpServer = &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           mux,
+		ReadTimeout:       cfg.Server.GetReadTimeout(),
+		ReadHeaderTimeout: cfg.Server.GetReadHeaderTimeout(),
+		WriteTimeout:      writeTimeout(&cfg.Server),
+		IdleTimeout:       cfg.Server.GetIdleTimeout(),
+		MaxHeaderBytes:    cfg.Server.GetMaxHeaderBytes(),
 	}
 
-	return &Server{
-		httpServer: httpServer,
-		logger:     logger,
+	// Give queued learning submissions a last chance before exiting; whatever
+	// the sidecar cannot take stays on disk for the next start
+	if learningQueue != nil {
+		srv.RegisterOnShutdown(srv.flushLearningQueueOnShutdown)
+	}
+	// Write out the sampled interactions still buffered
+	if evalSampler != nil {
+		srv.RegisterOnShutdown(func(ctx context.Context) { evalSampler.Close() })
 	}
+	return srv
 }
 
-// Start starts the HTTP server
+// RegisterOnShutdown adds a cleanup hook run by Shutdown once in-flight
+// requests have finished. Hooks run in registration order and should return
+// when ctx is done.
+func (s *Server) RegisterOnShutdown(hook func(ctx context.Context)) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// OrchestratorState returns starting, ready or shutting_down
+func (s *Server) OrchestratorState() string {
+	return s.state.Load().(string)
+}
+
+// markReady moves the server from starting to ready, unless it is already
+// shutting down
+func (s *Server) markReady() {
+	s.state.CompareAndSwap(handlers.OrchestratorStarting, handlers.OrchestratorReady)
+}
+
+// markReadyAfter marks the server ready once timeout passes, whether or not
+// the sidecars came up
+func (s *Server) markReadyAfter(ctx context.Context, timeout time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(timeout):
+		if s.OrchestratorState() == handlers.OrchestratorStarting {
+			s.logger.Warn("sidecars not ready before the warm-up timeout, serving anyway", "timeout", timeout.String())
+		}
+		s.markReady()
+	}
+}
+
+// Start starts the HTTP(S) server, warming up and watching the sidecars in the background
 func (s *Server) Start() error {
-	s.logger.Info("starting server", "addr", s.httpServer.Addr)
-	return s.httpServer.ListenAndServe()
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopBackground = cancel
+
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	// The server is ready once enough sidecars are warmed up, or warm-up
+	// gave up on them
+	if s.config.Warmup.Enabled {
+		onReady := readyAfter(s.config.Warmup.GetRequiredSidecars(len(s.sidecars)), s.markReady)
+		go func() {
+			warmUp(ctx, s.logger, s.sidecars, s.config.Warmup.GetInterval(), s.config.Warmup.GetMaxAttempts(), onReady)
+			s.markReady()
+		}()
+		if timeout := s.config.Warmup.GetReadyTimeout(); timeout > 0 {
+			go s.markReadyAfter(ctx, timeout)
+		}
+	} else {
+		s.markReady()
+	}
+	if s.watcher != nil {
+		go s.watcher.run(ctx)
+	}
+	if s.learningQueue != nil {
+		go drainLearningQueue(ctx, s.logger, s.learningQueue, s.learningClient, s.config.LearningQueue.GetRetryInterval())
+	}
+
+	return s.serve(ln)
+}
+
+// serve accepts connections on ln, over HTTPS when a certificate is configured
+func (s *Server) serve(ln net.Listener) error {
+	if tls := s.config.Server.TLS; tls.Enabled() {
+		s.logger.Info("starting server", "addr", ln.Addr().String(), "tls", true)
+		return s.httpServer.ServeTLS(ln, tls.CertFile, tls.KeyFile)
+	}
+	s.logger.Info("starting server", "addr", ln.Addr().String())
+	return s.httpServer.Serve(ln)
 }
 
-// Shutdown gracefully shuts down the server
+// Shutdown gracefully shuts down the server, then runs the shutdown hooks
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down server")
-	return s.httpServer.Shutdown(ctx)
+	s.state.Store(handlers.OrchestratorShuttingDown)
+	if s.stopBackground != nil {
+		s.stopBackground()
+	}
+	err := s.httpServer.Shutdown(ctx)
+
+	// Hooks get whatever is left of ctx, even if in-flight requests used it up
+	for _, hook := range s.shutdownHooks {
+		hook(ctx)
+	}
+	return err
 }
 
-// loggingMiddleware logs incoming HTTP requests
-func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+// loggingMiddleware logs incoming HTTP requests with the client IP found by ips
+func loggingMiddleware(logger *slog.Logger, ips *clientIPResolver, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -97,10 +352,160 @@ func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 			"status", rw.statusCode,
 			"duration_ms", duration.Milliseconds(),
 			"remote_addr", r.RemoteAddr,
+			"client_ip", ips.clientIP(r).String(),
 		)
 	})
 }
 
+// timeoutMiddleware aborts handlers that run longer than timeout with a 503 JSON error
+func timeoutMiddleware(timeout time.Duration, next http.Handler) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+
+	body, _ := json.Marshal(apierror.New(http.StatusServiceUnavailable, "timeout", "request timed out",
+		fmt.Sprintf("handler exceeded %s", timeout)))
+	timeoutHandler := http.TimeoutHandler(next, timeout, string(body))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// http.TimeoutHandler buffers the whole response, which would hold
+		// back every event of a stream; streams get a context deadline instead
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		// Only seen on timeout; a completed handler's own headers replace it
+		w.Header().Set("Content-Type", "application/json")
+		timeoutHandler.ServeHTTP(w, r)
+	})
+}
+
+// timeoutHeader lets a client pick its own handler timeout, in seconds
+const timeoutHeader = "X-Timeout-Seconds"
+
+// requestTimeoutMiddleware applies the route timeout, or the one a request
+// asks for in X-Timeout-Seconds, clamped to max
+func requestTimeoutMiddleware(logger *slog.Logger, routeTimeout, max time.Duration, next http.Handler) http.Handler {
+	byRoute := timeoutMiddleware(routeTimeout, next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(timeoutHeader)
+		if value == "" {
+			byRoute.ServeHTTP(w, r)
+			return
+		}
+
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || !(seconds > 0) || math.IsInf(seconds, 1) { // Also rejects NaN
+			apierror.Write(w, http.StatusBadRequest, apierror.New(http.StatusBadRequest, "invalid_timeout", "invalid timeout",
+				timeoutHeader+" must be a positive number of seconds"))
+			return
+		}
+
+		timeout := max
+		if seconds < max.Seconds() {
+			timeout = time.Duration(seconds * float64(time.Second))
+		} else if seconds > max.Seconds() {
+			logger.Info("clamping requested timeout", "path", r.URL.Path, "requested", value, "max", max.String())
+		}
+		timeoutMiddleware(timeout, next).ServeHTTP(w, r)
+	})
+}
+
+// bodyLimitMiddleware caps request bodies at limit bytes. Requests that declare
+// a larger Content-Length are rejected with 413 up front; chunked bodies are
+// cut off by http.MaxBytesReader and the handler reports the 413. Multipart
+// uploads get the code their handlers use for a chunked overflow, so clients
+// see upload_too_large either way.
+func bodyLimitMiddleware(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			code, msg := "body_too_large", "request body too large"
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+				code, msg = "upload_too_large", "upload too large"
+			}
+			apierror.Write(w, http.StatusRequestEntityTooLarge, apierror.New(http.StatusRequestEntityTooLarge, code,
+				msg, fmt.Sprintf("body exceeds %d bytes", limit)))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// headerLimitMiddleware rejects requests carrying more than max header lines
+// with 431. Their total size is already capped by the server's MaxHeaderBytes;
+// this stops floods of tiny headers that fit under it.
+func headerLimitMiddleware(max int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := 0
+		for _, values := range r.Header {
+			count += len(values)
+		}
+		if count > max {
+			apierror.Write(w, http.StatusRequestHeaderFieldsTooLarge, apierror.New(http.StatusRequestHeaderFieldsTooLarge, "too_many_headers",
+				"too many request headers", fmt.Sprintf("request has %d headers, at most %d allowed", count, max)))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readyGateMiddleware answers 503 not_ready while the orchestrator is still
+// starting, telling clients to retry after the warm-up interval
+func readyGateMiddleware(readiness handlers.ReadinessSource, retryAfter time.Duration, next http.Handler) http.Handler {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if readiness.OrchestratorState() != handlers.OrchestratorStarting {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		resp := apierror.New(http.StatusServiceUnavailable, "not_ready", "orchestrator is starting up",
+			"waiting for sidecars to pass their health checks")
+		resp.RetryAfterSeconds = seconds
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		apierror.Write(w, http.StatusServiceUnavailable, resp)
+	})
+}
+
+// pprofHandler serves the net/http/pprof endpoints
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// writeTimeout returns the connection write deadline: the longest route
+// timeout plus writeTimeoutGrace. A write_timeout_seconds of 0 leaves routes
+// without their own timeout unbounded, so the connection gets none either.
+func writeTimeout(s *config.ServerConfig) time.Duration {
+	if s.GetWriteTimeout() <= 0 {
+		return 0
+	}
+	return s.GetMaxRouteTimeout() + writeTimeoutGrace
+}
+
+// localhostOnly hides the wrapped handler from non-loopback clients. Behind
+// a trusted proxy on the same host, the forwarded client IP is what counts.
+func localhostOnly(ips *clientIPResolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := ips.clientIP(r); ip == nil || !ip.IsLoopback() {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // responseWriter wraps http.ResponseWriter to capture the status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -112,3 +517,10 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Flush lets streaming handlers push data through the wrapper
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
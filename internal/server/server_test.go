@@ -0,0 +1,797 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/assistant/orchestrator/internal/clients"
+	"github.com/assistant/orchestrator/internal/config"
+	"github.com/assistant/orchestrator/internal/handlers"
+)
+
+// newTestConfig returns a minimal valid configuration
+func newTestConfig() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{
+			Port:                10080,
+			ReadTimeoutSeconds:  5,
+			WriteTimeoutSeconds: 5,
+		},
+		Sidecars: config.SidecarConfig{
+			VoiceURL:       "http://127.0.0.1:1",
+			LLMURL:         config.URLList{"http://127.0.0.1:1"},
+			LearningURL:    "http://127.0.0.1:1",
+			TimeoutSeconds: 1,
+		},
+		ValidUserIDs: []string{"dad", "mom", "teen", "child"},
+	}
+}
+
+func TestServer_DryRunChat(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id": "dad",
+		"message": "hello",
+	})
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp clients.ChatResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Response != "echo: hello" {
+		t.Errorf("expected response 'echo: hello', got %s", resp.Response)
+	}
+	if resp.ModelUsed != "dry-run" {
+		t.Errorf("expected model 'dry-run', got %s", resp.ModelUsed)
+	}
+}
+
+func TestTimeoutMiddleware_SlowHandler(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := timeoutMiddleware(20*time.Millisecond, slow)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %s", ct)
+	}
+
+	var errResp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if errResp["error"] != "request timed out" {
+		t.Errorf("expected error 'request timed out', got %s", errResp["error"])
+	}
+}
+
+func TestTimeoutMiddleware_FastHandler(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	handler := timeoutMiddleware(time.Second, fast)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("expected handler content type, got %s", ct)
+	}
+}
+
+func TestRequestTimeoutMiddleware_ShortOverrideFailsFast(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := requestTimeoutMiddleware(logger, time.Minute, time.Minute, slow)
+
+	req := httptest.NewRequest("GET", "/chat", nil)
+	req.Header.Set("X-Timeout-Seconds", "0.05")
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the short override to fail fast, took %s", elapsed)
+	}
+}
+
+func TestRequestTimeoutMiddleware_ClampsLargeValue(t *testing.T) {
+	var remaining time.Duration
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		remaining = time.Until(deadline)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mw := requestTimeoutMiddleware(logger, 5*time.Second, 10*time.Second, handler)
+
+	req := httptest.NewRequest("GET", "/chat", nil)
+	req.Header.Set("X-Timeout-Seconds", "3600")
+	w := httptest.NewRecorder()
+	mw.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if remaining <= 5*time.Second || remaining > 10*time.Second {
+		t.Errorf("expected the deadline clamped to 10s, got %s left", remaining)
+	}
+}
+
+func TestRequestTimeoutMiddleware_InvalidValue(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not run for an invalid timeout")
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mw := requestTimeoutMiddleware(logger, time.Minute, time.Minute, handler)
+
+	for _, value := range []string{"soon", "-1", "0", "NaN"} {
+		req := httptest.NewRequest("GET", "/chat", nil)
+		req.Header.Set("X-Timeout-Seconds", value)
+		w := httptest.NewRecorder()
+		mw.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", value, w.Code)
+		}
+	}
+}
+
+func TestServer_RouteTimeouts(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Server.WriteTimeoutSeconds = 30
+	cfg.Server.RouteTimeouts = map[string]int{"/voice": 120}
+
+	if got := cfg.Server.GetRouteTimeout("/voice"); got != 120*time.Second {
+		t.Errorf("expected /voice timeout 120s, got %v", got)
+	}
+	if got := cfg.Server.GetRouteTimeout("/health"); got != 30*time.Second {
+		t.Errorf("expected /health to use default 30s, got %v", got)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	if srv.httpServer.WriteTimeout != 120*time.Second+writeTimeoutGrace {
+		t.Errorf("expected write timeout to cover slowest route, got %v", srv.httpServer.WriteTimeout)
+	}
+}
+
+func TestServer_PprofDisabled(t *testing.T) {
+	cfg := newTestConfig()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+	req.RemoteAddr = "127.0.0.1:50000"
+	w := httptest.NewRecorder()
+
+	srv.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestServer_PprofEnabled(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Debug.PprofEnabled = true
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "127.0.0.1:50000"
+		w := httptest.NewRecorder()
+
+		srv.httpServer.Handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestServer_PprofRejectsRemoteClients(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Debug.PprofEnabled = true
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+	req.RemoteAddr = "192.168.1.20:50000"
+	w := httptest.NewRecorder()
+
+	srv.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestServer_AdminRoutesLocalOnly(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.AdminUserIDs = []string{"dad"}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"local admin", "127.0.0.1:50000", http.StatusOK},
+		{"remote admin", "192.168.1.20:50000", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/cache/clear", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-User-ID", "dad")
+			w := httptest.NewRecorder()
+
+			srv.httpServer.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_OversizedChatBody(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Server.RouteMaxBodyBytes = map[string]int64{"/chat": 64}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id": "dad",
+		"message": strings.Repeat("a", 128),
+	})
+
+	tests := []struct {
+		name          string
+		contentLength int64
+	}{
+		{"declared length", int64(len(body))},
+		{"chunked", -1}, // Unknown length, only caught while decoding
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/chat", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.ContentLength = tt.contentLength
+			w := httptest.NewRecorder()
+
+			srv.httpServer.Handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("expected status 413, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp["code"] != "body_too_large" {
+				t.Errorf("expected code 'body_too_large', got %q", resp["code"])
+			}
+		})
+	}
+}
+
+func TestServer_OversizedVoiceUpload(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Server.RouteMaxBodyBytes = map[string]int64{"/voice": 1024}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "audio.wav")
+	part.Write(make([]byte, 4096))
+	mw.Close()
+
+	tests := []struct {
+		name          string
+		contentLength int64
+	}{
+		{"declared length", int64(body.Len())},
+		{"chunked", -1}, // Unknown length, only caught while parsing the form
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/voice", bytes.NewReader(body.Bytes()))
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			req.ContentLength = tt.contentLength
+			w := httptest.NewRecorder()
+
+			srv.httpServer.Handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("expected status 413, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp["code"] != "upload_too_large" || resp["detail"] != "body exceeds 1024 bytes" {
+				t.Errorf("unexpected error response %v", resp)
+			}
+		})
+	}
+}
+
+func TestServer_ChatBodyWithinLimit(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Server.MaxBodyBytes = 1024
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id": "dad",
+		"message": "hello",
+	})
+	req := httptest.NewRequest("POST", "/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutMiddleware_EventStreamNotBuffered(t *testing.T) {
+	var flushable bool
+	var deadline time.Time
+	streaming := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, flushable = w.(http.Flusher)
+		deadline, _ = r.Context().Deadline()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := loggingMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, timeoutMiddleware(time.Minute, streaming))
+
+	req := httptest.NewRequest("POST", "/voice", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !flushable {
+		t.Error("expected event streams to get a flushable writer")
+	}
+	if deadline.IsZero() {
+		t.Error("expected event streams to keep a context deadline")
+	}
+}
+
+func TestServer_ServesTLS(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Server.TLS = config.TLSConfig{CertFile: "testdata/cert.pem", KeyFile: "testdata/key.pem"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected fixture config to validate: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.serve(ln)
+	defer srv.httpServer.Close()
+
+	// Trust the self-signed fixture
+	pem, _ := os.ReadFile("testdata/cert.pem")
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pem)
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+	}
+
+	resp, err := client.Get("https://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.TLS == nil {
+		t.Error("expected the response to arrive over TLS")
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_OrchestratorStateLifecycle(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Server.Port = 0 // Any free port
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	if got := srv.OrchestratorState(); got != handlers.OrchestratorStarting {
+		t.Fatalf("expected %q before Start, got %q", handlers.OrchestratorStarting, got)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Start() }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.OrchestratorState() != handlers.OrchestratorReady {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q after Start, got %q", handlers.OrchestratorReady, srv.OrchestratorState())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if got := srv.OrchestratorState(); got != handlers.OrchestratorShuttingDown {
+		t.Errorf("expected %q after Shutdown, got %q", handlers.OrchestratorShuttingDown, got)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("expected Start to return ErrServerClosed, got %v", err)
+	}
+}
+
+func TestServer_LateWarmUpKeepsShuttingDown(t *testing.T) {
+	srv := &Server{}
+	srv.state.Store(handlers.OrchestratorShuttingDown)
+	srv.markReady()
+	if got := srv.OrchestratorState(); got != handlers.OrchestratorShuttingDown {
+		t.Errorf("expected a late warm-up not to undo shutting_down, got %q", got)
+	}
+}
+
+func TestServer_ShutdownRunsHooks(t *testing.T) {
+	srv := New(newTestConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var calls []string
+	srv.RegisterOnShutdown(func(ctx context.Context) { calls = append(calls, "first") })
+	srv.RegisterOnShutdown(func(ctx context.Context) { calls = append(calls, "second") })
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("expected hooks to run in order, got %v", calls)
+	}
+}
+
+func TestServer_ConnectionTimeouts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	srv := New(newTestConfig(), logger)
+	if srv.httpServer.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("expected default read header timeout 10s, got %v", srv.httpServer.ReadHeaderTimeout)
+	}
+	if srv.httpServer.IdleTimeout != 120*time.Second {
+		t.Errorf("expected default idle timeout 120s, got %v", srv.httpServer.IdleTimeout)
+	}
+
+	cfg := newTestConfig()
+	cfg.Server.ReadHeaderTimeoutSeconds = 3
+	cfg.Server.IdleTimeoutSeconds = 45
+	srv = New(cfg, logger)
+	if srv.httpServer.ReadHeaderTimeout != 3*time.Second {
+		t.Errorf("expected configured read header timeout 3s, got %v", srv.httpServer.ReadHeaderTimeout)
+	}
+	if srv.httpServer.IdleTimeout != 45*time.Second {
+		t.Errorf("expected configured idle timeout 45s, got %v", srv.httpServer.IdleTimeout)
+	}
+}
+
+func TestServer_WriteTimeout(t *testing.T) {
+	tests := []struct {
+		name          string
+		writeTimeout  int
+		routeTimeouts map[string]int
+		want          time.Duration
+	}{
+		{"unset means unlimited", 0, nil, 0},
+		{"unset with route timeouts stays unlimited", 0, map[string]int{"/voice": 30}, 0},
+		{"route default plus grace", 60, nil, 65 * time.Second},
+		{"longest route plus grace", 60, map[string]int{"/voice": 120}, 125 * time.Second},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Server.WriteTimeoutSeconds = tt.writeTimeout
+			cfg.Server.RouteTimeouts = tt.routeTimeouts
+
+			srv := New(cfg, logger)
+			if srv.httpServer.WriteTimeout != tt.want {
+				t.Errorf("expected write timeout %v, got %v", tt.want, srv.httpServer.WriteTimeout)
+			}
+		})
+	}
+}
+
+func TestServer_OversizedHeaders(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Server.MaxHeaderBytes = 1024
+	cfg.Server.MaxHeaderCount = 20
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+	if srv.httpServer.MaxHeaderBytes != 1024 {
+		t.Errorf("expected MaxHeaderBytes 1024, got %d", srv.httpServer.MaxHeaderBytes)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go srv.serve(ln)
+	defer srv.httpServer.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	url := "http://" + ln.Addr().String() + "/health"
+
+	tests := []struct {
+		name   string
+		header func(h http.Header)
+		want   int
+	}{
+		{"within limits", func(h http.Header) { h.Set("X-Note", "hello") }, http.StatusOK},
+		// net/http allows a few KiB of slack over MaxHeaderBytes
+		{"oversized header", func(h http.Header) { h.Set("X-Note", strings.Repeat("a", 16<<10)) }, http.StatusRequestHeaderFieldsTooLarge},
+		{"too many headers", func(h http.Header) {
+			for i := 0; i < 30; i++ {
+				h.Add("X-Note", "a")
+			}
+		}, http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", url, nil)
+			tt.header(req.Header)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServer_ReadyGate(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Warmup = config.WarmupConfig{Enabled: true, Gate: true, IntervalSeconds: 3}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	chat := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id":"dad","message":"hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Still starting: conversations are refused, /health answers
+	w := chat()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 while starting, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp struct {
+		Code              string `json:"code"`
+		RetryAfterSeconds int    `json:"retry_after_seconds"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if errResp.Code != "not_ready" || errResp.RetryAfterSeconds != 3 {
+		t.Errorf("expected not_ready with retry_after_seconds 3, got %+v", errResp)
+	}
+	if got := w.Header().Get("Retry-After"); got != "3" {
+		t.Errorf("expected Retry-After 3, got %q", got)
+	}
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	hw := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(hw, req)
+	if hw.Code != http.StatusOK || !strings.Contains(hw.Body.String(), handlers.OrchestratorStarting) {
+		t.Errorf("expected /health to answer while starting, got %d: %s", hw.Code, hw.Body.String())
+	}
+
+	// Ready: the gate opens
+	srv.markReady()
+	if w := chat(); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 once ready, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServer_ReadyGateDisabled(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Warmup = config.WarmupConfig{Enabled: true}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_id":"dad","message":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected ungated chat to be served while starting, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServer_MarkReadyAfterTimeout(t *testing.T) {
+	srv := &Server{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	srv.state.Store(handlers.OrchestratorStarting)
+
+	srv.markReadyAfter(context.Background(), 10*time.Millisecond)
+
+	if got := srv.OrchestratorState(); got != handlers.OrchestratorReady {
+		t.Errorf("expected %q after the ready timeout, got %q", handlers.OrchestratorReady, got)
+	}
+}
+
+func TestServer_DisabledRoutes(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Mode = config.ModeDryRun
+	cfg.Server.DisabledRoutes = []string{"/learn"}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"disabled route", "/learn", `{"user_id":"dad","content":"the sky is blue","source":"conversation"}`, http.StatusNotFound},
+		{"enabled route", "/chat", `{"user_id":"dad","message":"hello"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			srv.httpServer.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_SidecarsThroughProxy(t *testing.T) {
+	// A forward proxy sees absolute-form requests naming the sidecar
+	hosts := make(chan string, 4)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts <- r.URL.Host
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	cfg := newTestConfig()
+	cfg.Sidecars.LLMURL = config.URLList{"http://llm.sidecar.invalid"}
+	cfg.Sidecars.HTTPProxy = proxy.URL
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(cfg, logger)
+
+	req := httptest.NewRequest("GET", "/health/llm", nil)
+	w := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the LLM sidecar to answer through the proxy, got %d: %s", w.Code, w.Body.String())
+	}
+	select {
+	case host := <-hosts:
+		if host != "llm.sidecar.invalid" {
+			t.Errorf("expected the proxy to be asked for llm.sidecar.invalid, got %q", host)
+		}
+	default:
+		t.Error("expected the health check to go through the proxy")
+	}
+}
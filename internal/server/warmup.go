@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthChecker is the part of every sidecar client used for warm-up
+type healthChecker interface {
+	Health(ctx context.Context) (time.Duration, error)
+}
+
+// warmUp pings each sidecar until it answers healthy or attempts run out,
+// logging when each becomes ready and calling onReady, if set. It blocks
+// until every sidecar is done; callers run it in the background.
+func warmUp(ctx context.Context, logger *slog.Logger, sidecars map[string]healthChecker, interval time.Duration, maxAttempts int, onReady func(name string)) {
+	var wg sync.WaitGroup
+
+	for name, sidecar := range sidecars {
+		wg.Add(1)
+		go func(name string, sidecar healthChecker) {
+			defer wg.Done()
+
+			start := time.Now()
+			for attempt := 1; attempt <= maxAttempts; attempt++ {
+				latency, err := sidecar.Health(ctx)
+				if err == nil {
+					logger.Info("sidecar ready",
+						"sidecar", name,
+						"attempts", attempt,
+						"latency_ms", latency.Milliseconds(),
+						"waited_ms", time.Since(start).Milliseconds())
+					if onReady != nil {
+						onReady(name)
+					}
+					return
+				}
+
+				logger.Debug("sidecar not ready yet", "sidecar", name, "attempt", attempt, "error", err)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(interval):
+				}
+			}
+
+			logger.Warn("sidecar did not become ready during warm-up",
+				"sidecar", name,
+				"attempts", maxAttempts)
+		}(name, sidecar)
+	}
+
+	wg.Wait()
+}
+
+// readyAfter returns a warm-up callback that calls ready once required
+// sidecars have answered healthy
+func readyAfter(required int, ready func()) func(name string) {
+	var healthy int32
+	return func(name string) {
+		if int(atomic.AddInt32(&healthy, 1)) == required {
+			ready()
+		}
+	}
+}
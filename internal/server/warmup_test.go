@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSidecar fails its first failures health checks, then succeeds
+type countingSidecar struct {
+	calls    int32
+	failures int32
+}
+
+func (c *countingSidecar) Health(ctx context.Context) (time.Duration, error) {
+	n := atomic.AddInt32(&c.calls, 1)
+	if n <= c.failures {
+		return 0, errors.New("cold start")
+	}
+	return time.Millisecond, nil
+}
+
+func TestWarmUp_PingsEverySidecar(t *testing.T) {
+	voice := &countingSidecar{}
+	llm := &countingSidecar{failures: 2}
+	learning := &countingSidecar{}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	warmUp(context.Background(), logger, map[string]healthChecker{
+		"voice":    voice,
+		"llm":      llm,
+		"learning": learning,
+	}, time.Millisecond, 5, nil)
+
+	if voice.calls != 1 || learning.calls != 1 {
+		t.Errorf("expected one ping for ready sidecars, got voice=%d learning=%d", voice.calls, learning.calls)
+	}
+	if llm.calls != 3 {
+		t.Errorf("expected llm to be pinged until ready (3 calls), got %d", llm.calls)
+	}
+}
+
+func TestWarmUp_GivesUpAfterMaxAttempts(t *testing.T) {
+	down := &countingSidecar{failures: 100}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	warmUp(context.Background(), logger, map[string]healthChecker{"llm": down}, time.Millisecond, 4, nil)
+
+	if down.calls != 4 {
+		t.Errorf("expected 4 attempts, got %d", down.calls)
+	}
+}
+
+func TestWarmUp_StopsOnCancel(t *testing.T) {
+	down := &countingSidecar{failures: 100}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	warmUp(ctx, logger, map[string]healthChecker{"llm": down}, time.Hour, 10, nil)
+
+	if down.calls != 1 {
+		t.Errorf("expected warm-up to stop after cancellation, got %d calls", down.calls)
+	}
+}
+
+func TestWarmUp_ReadyOnceRequiredSidecarsAnswer(t *testing.T) {
+	var readyCalls int32
+	onReady := readyAfter(2, func() { atomic.AddInt32(&readyCalls, 1) })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	warmUp(context.Background(), logger, map[string]healthChecker{
+		"voice":    &countingSidecar{},
+		"llm":      &countingSidecar{},
+		"learning": &countingSidecar{failures: 100},
+	}, time.Millisecond, 3, onReady)
+
+	if readyCalls != 1 {
+		t.Errorf("expected ready once two of three sidecars answered, got %d calls", readyCalls)
+	}
+}
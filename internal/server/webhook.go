@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health webhook events
+const (
+	eventSidecarUnreachable = "sidecar_unreachable"
+	eventSidecarRecovered   = "sidecar_recovered"
+)
+
+// healthEvent is the JSON body POSTed to the health webhook
+type healthEvent struct {
+	Event     string    `json:"event"`   // eventSidecarUnreachable or eventSidecarRecovered
+	Sidecar   string    `json:"sidecar"` // "voice", "llm" or "learning"
+	Status    string    `json:"status"`  // "unreachable" or "ok"
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latency_ms,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// healthWebhook POSTs sidecar health transitions to a URL. Posts run in the
+// background, so a slow receiver never holds up health checks. A transition
+// arriving within debounce of the last notification about the same sidecar
+// is held back until the window ends and dropped if the sidecar has flipped
+// back by then, so a flapping sidecar costs at most one post per window.
+type healthWebhook struct {
+	url      string
+	client   *http.Client
+	debounce time.Duration
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	sidecars map[string]*webhookState
+}
+
+// webhookState tracks the notifications about one sidecar
+type webhookState struct {
+	lastSent   time.Time
+	lastStatus string       // Status of the last event sent
+	pending    *healthEvent // Latest held-back event
+	timer      *time.Timer  // Fires at the end of the debounce window while one is pending
+}
+
+func newHealthWebhook(logger *slog.Logger, url string, timeout, debounce time.Duration) *healthWebhook {
+	return &healthWebhook{
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		debounce: debounce,
+		logger:   logger,
+		sidecars: make(map[string]*webhookState),
+	}
+}
+
+// notify sends event now, or holds it back if the sidecar was notified about
+// within the debounce window. It never blocks on the receiver; a nil webhook
+// ignores events.
+func (wh *healthWebhook) notify(event healthEvent) {
+	if wh == nil {
+		return
+	}
+
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	state, ok := wh.sidecars[event.Sidecar]
+	if !ok {
+		state = &webhookState{}
+		wh.sidecars[event.Sidecar] = state
+	}
+
+	if state.timer != nil {
+		state.pending = &event
+		return
+	}
+	if elapsed := time.Since(state.lastSent); !state.lastSent.IsZero() && elapsed < wh.debounce {
+		state.pending = &event
+		state.timer = time.AfterFunc(wh.debounce-elapsed, func() { wh.flush(event.Sidecar) })
+		return
+	}
+	wh.send(state, event)
+}
+
+// flush sends the event held back for a sidecar when its debounce window
+// ends, unless it would repeat the status last sent
+func (wh *healthWebhook) flush(sidecar string) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	state := wh.sidecars[sidecar]
+	event := state.pending
+	state.pending, state.timer = nil, nil
+	if event == nil || event.Status == state.lastStatus {
+		return
+	}
+	wh.send(state, *event)
+}
+
+// send records event as sent and posts it in the background; callers hold mu
+func (wh *healthWebhook) send(state *webhookState, event healthEvent) {
+	state.lastSent = time.Now()
+	state.lastStatus = event.Status
+	go wh.post(event)
+}
+
+// post delivers one event, logging rather than retrying failures
+func (wh *healthWebhook) post(event healthEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		wh.logger.Error("failed to encode health webhook event", "error", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, wh.url, bytes.NewReader(body))
+	if err != nil {
+		wh.logger.Error("failed to build health webhook request", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		wh.logger.Warn("health webhook failed", "event", event.Event, "sidecar", event.Sidecar, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		wh.logger.Warn("health webhook rejected event", "event", event.Event, "sidecar", event.Sidecar, "status", resp.StatusCode)
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is a mock webhook collecting the events posted to it
+func webhookReceiver(t *testing.T) (*httptest.Server, <-chan healthEvent) {
+	t.Helper()
+	events := make(chan healthEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected a JSON POST, got %s %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var event healthEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode webhook event: %v", err)
+		}
+		events <- event
+	}))
+	t.Cleanup(srv.Close)
+	return srv, events
+}
+
+// nextEvent waits for the next posted event
+func nextEvent(t *testing.T, events <-chan healthEvent) healthEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a webhook event")
+		return healthEvent{}
+	}
+}
+
+// expectNoEvent fails if an event is posted within wait
+func expectNoEvent(t *testing.T, events <-chan healthEvent, wait time.Duration) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("expected no webhook event, got %+v", event)
+	case <-time.After(wait):
+	}
+}
+
+func TestHealthWatcher_WebhookFiresOnTransitions(t *testing.T) {
+	receiver, events := webhookReceiver(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// ok, down for two rounds, then up again
+	llm := &scriptedSidecar{healthy: []bool{true, false, false, true}}
+	watcher := newHealthWatcher(logger, map[string]healthChecker{"llm": llm}, time.Second, 1, 1)
+	watcher.webhook = newHealthWebhook(logger, receiver.URL, time.Second, 0)
+
+	watcher.checkAll(context.Background())
+	expectNoEvent(t, events, 50*time.Millisecond)
+
+	watcher.checkAll(context.Background())
+	down := nextEvent(t, events)
+	if down.Event != eventSidecarUnreachable || down.Sidecar != "llm" || down.Status != "unreachable" || down.Error != "connection refused" {
+		t.Errorf("unexpected unreachable event %+v", down)
+	}
+
+	// Staying down is not a transition
+	watcher.checkAll(context.Background())
+	expectNoEvent(t, events, 50*time.Millisecond)
+
+	watcher.checkAll(context.Background())
+	up := nextEvent(t, events)
+	if up.Event != eventSidecarRecovered || up.Sidecar != "llm" || up.Status != "ok" || up.LatencyMs != 5 {
+		t.Errorf("unexpected recovery event %+v", up)
+	}
+}
+
+func TestHealthWatcher_WebhookReportsUnreachableStart(t *testing.T) {
+	receiver, events := webhookReceiver(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	watcher := newHealthWatcher(logger, map[string]healthChecker{"voice": &scriptedSidecar{healthy: []bool{false}}}, time.Second, 2, 1)
+	watcher.webhook = newHealthWebhook(logger, receiver.URL, time.Second, 0)
+
+	watcher.checkAll(context.Background())
+	if event := nextEvent(t, events); event.Event != eventSidecarUnreachable || event.Sidecar != "voice" {
+		t.Errorf("expected the unreachable start reported, got %+v", event)
+	}
+}
+
+func TestHealthWebhook_Debounce(t *testing.T) {
+	receiver, events := webhookReceiver(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	webhook := newHealthWebhook(logger, receiver.URL, time.Second, 200*time.Millisecond)
+
+	down := unreachableEvent("llm", errors.New("connection refused"))
+	up := healthEvent{Event: eventSidecarRecovered, Sidecar: "llm", Status: "ok", Time: time.Now()}
+
+	webhook.notify(down)
+	nextEvent(t, events)
+
+	// A flap within the window ends where it started, so nothing more is sent
+	webhook.notify(up)
+	webhook.notify(down)
+	expectNoEvent(t, events, 400*time.Millisecond)
+
+	// A transition that still stands when the window ends is sent late
+	webhook.notify(up)
+	start := time.Now()
+	if event := nextEvent(t, events); event.Event != eventSidecarRecovered {
+		t.Errorf("expected the held-back recovery, got %+v", event)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		// The last post was over 400ms ago, outside the window
+		t.Errorf("expected the recovery sent right away, took %v", elapsed)
+	}
+
+	webhook.notify(down)
+	start = time.Now()
+	if event := nextEvent(t, events); event.Event != eventSidecarUnreachable {
+		t.Errorf("expected the held-back unreachable event, got %+v", event)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the event held back until the window ended, sent after %v", elapsed)
+	}
+}
+
+func TestHealthWebhook_SlowReceiverDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer receiver.Close()
+	defer close(release)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	watcher := newHealthWatcher(logger, map[string]healthChecker{"llm": &scriptedSidecar{healthy: []bool{false}}}, time.Second, 1, 1)
+	watcher.webhook = newHealthWebhook(logger, receiver.URL, 50*time.Millisecond, 0)
+
+	start := time.Now()
+	watcher.checkAll(context.Background())
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("expected the health check not to wait for the webhook, took %v", elapsed)
+	}
+}
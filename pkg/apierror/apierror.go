@@ -0,0 +1,45 @@
+// Package apierror defines the error body returned by every tier, the
+// orchestrator and the Windows client alike, so clients handle errors the
+// same way whichever one answered.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Response is the JSON body of an error response
+type Response struct {
+	Code              string `json:"code"`                          // Machine-readable, e.g. "content_blocked"
+	Message           string `json:"message"`                       // Human-readable summary
+	Error             string `json:"error"`                         // Same as Message, for clients reading the original field
+	Detail            string `json:"detail,omitempty"`              // Extra context, such as the underlying error
+	RequestID         string `json:"request_id,omitempty"`          // Echo of the client's request_id, when known
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"` // Set with 503s when a retry is worth it
+}
+
+// New builds an error body. An empty code is derived from the status.
+func New(status int, code, message, detail string) Response {
+	if code == "" {
+		code = CodeForStatus(status)
+	}
+	return Response{Code: code, Message: message, Error: message, Detail: detail}
+}
+
+// CodeForStatus returns the generic code for an HTTP status, e.g.
+// "method_not_allowed" for 405
+func CodeForStatus(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// Write sends resp as a JSON error response with the given status
+func Write(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
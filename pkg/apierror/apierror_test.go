@@ -0,0 +1,49 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          "bad_request",
+		http.StatusMethodNotAllowed:    "method_not_allowed",
+		http.StatusInternalServerError: "internal_server_error",
+		http.StatusServiceUnavailable:  "service_unavailable",
+		599:                            "error",
+	}
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, http.StatusBadRequest, New(http.StatusBadRequest, "", "invalid user_id", ""))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %s", ct)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	want := map[string]interface{}{"code": "bad_request", "message": "invalid user_id", "error": "invalid user_id"}
+	if len(body) != len(want) {
+		t.Errorf("expected exactly %v, got %v", want, body)
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("expected %s %v, got %v", k, v, body[k])
+		}
+	}
+}